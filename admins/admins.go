@@ -0,0 +1,330 @@
+package admins
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/helpers"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+const (
+	// KeyPrefix marks admin api keys so they are easy to spot in configs and logs
+	KeyPrefix = "ppa_"
+
+	// hashPrefix marks a key prefix taken from the key's hash, for keys that weren't generated
+	hashPrefix = "sha:"
+	// prefixLength is how many characters of a generated key or a key's hash its prefix shows
+	prefixLength = 8
+
+	// lastUsedInterval limits how often we write last used timestamps back to the store
+	lastUsedInterval = time.Minute
+)
+
+var (
+	_dataStore store.Store
+
+	_lastUsedLock sync.Mutex
+
+	ErrInvalidKey = errors.New("invalid api key")
+	ErrLastAdmin  = errors.New("can't remove the last admin or their last api key")
+)
+
+func Setup(dataStore store.Store) {
+	_dataStore = dataStore
+}
+
+// Bootstrap creates the first admin account if none exist yet.  The bootstrap key is used as its api key,
+// or one is generated and logged if it is empty.  Once an admin exists the bootstrap key is ignored.
+func Bootstrap(bootstrapKey string) error {
+	admins, err := _dataStore.GetAdmins()
+	if err != nil {
+		return err
+	}
+
+	if len(admins) > 0 {
+		if bootstrapKey != "" {
+			log.Println("Admin accounts already exist, ignoring adminKey")
+		}
+
+		return hideKeyPrefixes()
+	}
+
+	admin := models.Admin{
+		Name: "admin",
+		Role: models.RoleAdmin,
+	}
+
+	if err := _dataStore.CreateAdmin(&admin); err != nil {
+		return err
+	}
+
+	created, err := createAPIKey(admin, models.AdminAPIKeyCreatePayload{Name: "bootstrap"}, bootstrapKey)
+	if err != nil {
+		return err
+	}
+
+	if bootstrapKey == "" {
+		log.Println("Admin Authorization Key Generated:", created.Key)
+	}
+
+	return nil
+}
+
+// Authenticate finds the admin and api key matching key.  Every stored key is compared in constant time
+func Authenticate(key string) (models.Admin, models.AdminAPIKey, error) {
+	keys, err := _dataStore.GetAdminAPIKeys()
+	if err != nil {
+		return models.Admin{}, models.AdminAPIKey{}, err
+	}
+
+	hash := []byte(helpers.HashToken(key))
+
+	var match *models.AdminAPIKey
+	for i := range keys {
+		if subtle.ConstantTimeCompare(hash, []byte(keys[i].Hash)) == 1 {
+			match = &keys[i]
+		}
+	}
+
+	if match == nil {
+		return models.Admin{}, models.AdminAPIKey{}, ErrInvalidKey
+	}
+
+	now := time.Now()
+
+	if match.ExpiresAt != nil && now.After(*match.ExpiresAt) {
+		return models.Admin{}, models.AdminAPIKey{}, ErrInvalidKey
+	}
+
+	admin, err := _dataStore.GetAdminByID(match.AdminID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return models.Admin{}, models.AdminAPIKey{}, ErrInvalidKey
+		}
+
+		return models.Admin{}, models.AdminAPIKey{}, err
+	}
+
+	touchAPIKey(*match, now)
+
+	// Accounts created before roles existed are full admins
+	if admin.Role == "" {
+		admin.Role = models.RoleAdmin
+	}
+
+	match.Hash = ""
+
+	return admin, *match, nil
+}
+
+func touchAPIKey(key models.AdminAPIKey, now time.Time) {
+	_lastUsedLock.Lock()
+	defer _lastUsedLock.Unlock()
+
+	if key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < lastUsedInterval {
+		return
+	}
+
+	key.LastUsedAt = &now
+
+	if err := _dataStore.UpdateAdminAPIKey(&key); err != nil {
+		log.Println("Error:", err)
+	}
+}
+
+func GetAdmins() ([]models.Admin, error) {
+	return _dataStore.GetAdmins()
+}
+
+// CreateAdmin creates an admin along with their first api key
+func CreateAdmin(adminPayload models.AdminCreatePayload) (*models.AdminCreated, error) {
+	if adminPayload.Role == "" {
+		adminPayload.Role = models.RoleAdmin
+	}
+
+	admin := models.Admin{
+		Name: adminPayload.Name,
+		Role: adminPayload.Role,
+	}
+
+	if err := _dataStore.CreateAdmin(&admin); err != nil {
+		return nil, err
+	}
+
+	key, err := createAPIKey(admin, models.AdminAPIKeyCreatePayload{Name: "default"}, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AdminCreated{
+		Admin:  admin,
+		APIKey: *key,
+	}, nil
+}
+
+func UpdateAdmin(admin models.Admin, adminPayload models.AdminUpdatePayload) (*models.Admin, error) {
+	if adminPayload.Name != "" {
+		admin.Name = adminPayload.Name
+	}
+
+	if adminPayload.Role != "" && adminPayload.Role != admin.Role {
+		if isAdmin(admin) {
+			if err := ensureAdminCredential(admin.ID, 0); err != nil {
+				return nil, err
+			}
+		}
+
+		admin.Role = adminPayload.Role
+	}
+
+	if err := _dataStore.UpdateAdmin(&admin); err != nil {
+		return nil, err
+	}
+
+	return &admin, nil
+}
+
+func GetAdmin(id int) (models.Admin, error) {
+	return _dataStore.GetAdminByID(id)
+}
+
+func DeleteAdmin(id int) error {
+	if _, err := _dataStore.GetAdminByID(id); err != nil {
+		return err
+	}
+
+	if err := ensureAdminCredential(id, 0); err != nil {
+		return err
+	}
+
+	return _dataStore.DeleteAdmin(id)
+}
+
+// ensureAdminCredential makes sure that without the admin adminID and the api key keyID, some admin can
+// still log in with an unexpired api key so we can't lock ourselves out
+func ensureAdminCredential(adminID int, keyID int) error {
+	admins, err := _dataStore.GetAdmins()
+	if err != nil {
+		return err
+	}
+
+	keys, err := _dataStore.GetAdminAPIKeys()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	for _, admin := range admins {
+		if admin.ID == adminID || !isAdmin(admin) {
+			continue
+		}
+
+		for _, key := range keys {
+			if key.AdminID == admin.ID && key.ID != keyID && (key.ExpiresAt == nil || now.Before(*key.ExpiresAt)) {
+				return nil
+			}
+		}
+	}
+
+	return ErrLastAdmin
+}
+
+// isAdmin reports whether the account has the admin role.  Accounts created before roles existed are admins
+func isAdmin(admin models.Admin) bool {
+	return admin.Role == models.RoleAdmin || admin.Role == ""
+}
+
+func GetAPIKeys() ([]models.AdminAPIKey, error) {
+	keys, err := _dataStore.GetAdminAPIKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range keys {
+		keys[i].Hash = ""
+	}
+
+	return keys, nil
+}
+
+func CreateAPIKey(admin models.Admin, keyPayload models.AdminAPIKeyCreatePayload) (*models.AdminAPIKeyCreated, error) {
+	return createAPIKey(admin, keyPayload, "")
+}
+
+func DeleteAPIKey(id int) error {
+	if _, err := _dataStore.GetAdminAPIKeyByID(id); err != nil {
+		return err
+	}
+
+	if err := ensureAdminCredential(0, id); err != nil {
+		return err
+	}
+
+	return _dataStore.DeleteAdminAPIKey(id)
+}
+
+// createAPIKey stores a new api key for the admin.  If key is empty a random one is generated
+func createAPIKey(admin models.Admin, keyPayload models.AdminAPIKeyCreatePayload, key string) (*models.AdminAPIKeyCreated, error) {
+	hash := helpers.HashToken(key)
+	prefix := hashPrefix + hash[:prefixLength]
+
+	if key == "" {
+		token, err := helpers.NewToken()
+		if err != nil {
+			return nil, err
+		}
+
+		key = KeyPrefix + token
+		hash = helpers.HashToken(key)
+		prefix = key[:len(KeyPrefix)+prefixLength]
+	}
+
+	apiKey := models.AdminAPIKey{
+		AdminID:   admin.ID,
+		Name:      keyPayload.Name,
+		Prefix:    prefix,
+		Hash:      hash,
+		ExpiresAt: keyPayload.ExpiresAt,
+	}
+
+	if err := _dataStore.CreateAdminAPIKey(&apiKey); err != nil {
+		return nil, err
+	}
+
+	apiKey.Hash = ""
+
+	return &models.AdminAPIKeyCreated{
+		AdminAPIKey: apiKey,
+		Key:         key,
+	}, nil
+}
+
+// hideKeyPrefixes replaces prefixes that show part of a key that wasn't generated, like the bootstrap key.
+// Only generated keys are random enough for their start to give nothing away
+func hideKeyPrefixes() error {
+	keys, err := _dataStore.GetAdminAPIKeys()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if strings.HasPrefix(key.Prefix, KeyPrefix) || strings.HasPrefix(key.Prefix, hashPrefix) {
+			continue
+		}
+
+		key.Prefix = hashPrefix + key.Hash[:prefixLength]
+
+		if err := _dataStore.UpdateAdminAPIKey(&key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,54 +1,260 @@
 package main
 
 import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/auth"
+	"github.com/geekgonecrazy/prismplus/bruteforce"
 	"github.com/geekgonecrazy/prismplus/controllers"
-	"github.com/geekgonecrazy/prismplus/sessions"
+	"github.com/geekgonecrazy/prismplus/models"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func apiServer() {
-	sessions.InitializeSessionStore()
+var (
+	// Roles allowed on each group of routes
+	adminOnly      = []string{models.RoleAdmin}
+	sessionManager = []string{models.RoleAdmin, models.RoleOperator}
+	readOnly       = []string{models.RoleAdmin, models.RoleOperator, models.RoleViewer}
+	streamerOnly   = []string{models.RoleStreamer}
+	public         []string
+)
+
+// noScope means api tokens can't call the route
+const noScope = ""
+
+type route struct {
+	method  string
+	path    string
+	handler echo.HandlerFunc
+	roles   []string
+	scope   string
+}
+
+// routes is every api route along with the roles allowed to call it and the scope an api token needs
+// to call it.  Routes with no roles are public
+var routes = []route{
+	{http.MethodGet, "/api/v1/streamers", controllers.GetStreamersHandler, readOnly, models.ScopeStreamersRead},
+	{http.MethodPost, "/api/v1/streamers", controllers.CreateStreamerHandler, adminOnly, models.ScopeStreamersWrite},
+	{http.MethodGet, "/api/v1/streamers/:streamer", controllers.GetStreamerHandler, readOnly, models.ScopeStreamersRead},
+	{http.MethodPut, "/api/v1/streamers/:streamer", controllers.UpdateStreamerHandler, adminOnly, models.ScopeStreamersWrite},
+	{http.MethodDelete, "/api/v1/streamers/:streamer", controllers.DeleteStreamerHandler, adminOnly, models.ScopeStreamersWrite},
+	{http.MethodPut, "/api/v1/streamers/:streamer/password", controllers.SetStreamerPasswordHandler, adminOnly, noScope},
+	{http.MethodPost, "/api/v1/streamers/:streamer/magiclink", controllers.CreateStreamerMagicLinkHandler, adminOnly, noScope},
+	{http.MethodPost, "/api/v1/streamers/:streamer/key", controllers.RotateStreamerKeyHandler, adminOnly, models.ScopeStreamersWrite},
+	{http.MethodPut, "/api/v1/streamers/:streamer/disable", controllers.DisableStreamerHandler, adminOnly, models.ScopeStreamersWrite},
+	{http.MethodDelete, "/api/v1/streamers/:streamer/disable", controllers.EnableStreamerHandler, adminOnly, models.ScopeStreamersWrite},
+	{http.MethodPost, "/api/v1/streamers/:streamer/destinations", controllers.CreateStreamerDestinationHandler, adminOnly, models.ScopeDestinationsWrite},
+	{http.MethodDelete, "/api/v1/streamers/:streamer/destinations/:destination", controllers.RemoveStreamerDestinationHandler, adminOnly, models.ScopeDestinationsWrite},
+	{http.MethodPut, "/api/v1/streamers/:streamer/profile", controllers.SetStreamerActiveProfileHandler, adminOnly, models.ScopeDestinationsWrite},
+	{http.MethodGet, "/api/v1/streamers/:streamer/keys", controllers.GetStreamerStreamKeysHandler, adminOnly, models.ScopeStreamersRead},
+	{http.MethodPost, "/api/v1/streamers/:streamer/keys", controllers.CreateStreamerStreamKeyHandler, adminOnly, models.ScopeStreamersWrite},
+	{http.MethodDelete, "/api/v1/streamers/:streamer/keys/:key", controllers.RevokeStreamerStreamKeyHandler, adminOnly, models.ScopeStreamersWrite},
+
+	{http.MethodPost, "/api/v1/streamer/login", controllers.StreamerLoginHandler, public, noScope},
+	{http.MethodPost, "/api/v1/streamer/login/magic", controllers.StreamerMagicLinkLoginHandler, public, noScope},
+	{http.MethodPost, "/api/v1/streamer/logout", controllers.StreamerLogoutHandler, streamerOnly, noScope},
+	{http.MethodPut, "/api/v1/streamer/password", controllers.SetMyStreamerPasswordHandler, streamerOnly, noScope},
+	{http.MethodGet, "/api/v1/streamer", controllers.GetMyStreamerHandler, streamerOnly, models.ScopeStreamersRead},
+	{http.MethodGet, "/api/v1/streamer/history", controllers.GetMyStreamerHistoryHandler, streamerOnly, models.ScopeSessionsRead},
+	{http.MethodGet, "/api/v1/streamer/events", controllers.GetMyStreamerEventsHandler, streamerOnly, models.ScopeSessionsRead},
+	{http.MethodGet, "/api/v1/streamer/events/ws", controllers.GetMyStreamerEventsWebSocketHandler, streamerOnly, models.ScopeSessionsRead},
+	{http.MethodGet, "/api/v1/streamer/destinations", controllers.GetMyStreamerDestinationsHandler, streamerOnly, models.ScopeDestinationsRead},
+	{http.MethodPost, "/api/v1/streamer/destinations", controllers.CreateMyStreamerDestinationHandler, streamerOnly, models.ScopeDestinationsWrite},
+	{http.MethodDelete, "/api/v1/streamer/destinations/:destination", controllers.RemoveMyStreamerDestinationHandler, streamerOnly, models.ScopeDestinationsWrite},
+	{http.MethodGet, "/api/v1/streamer/profiles", controllers.GetMyStreamerProfilesHandler, streamerOnly, models.ScopeDestinationsRead},
+	{http.MethodPut, "/api/v1/streamer/profiles/:profile", controllers.SetMyStreamerProfileHandler, streamerOnly, models.ScopeDestinationsWrite},
+	{http.MethodDelete, "/api/v1/streamer/profiles/:profile", controllers.DeleteMyStreamerProfileHandler, streamerOnly, models.ScopeDestinationsWrite},
+	{http.MethodPut, "/api/v1/streamer/profile", controllers.SetMyStreamerActiveProfileHandler, streamerOnly, models.ScopeDestinationsWrite},
+	{http.MethodGet, "/api/v1/streamer/keys", controllers.GetMyStreamerStreamKeysHandler, streamerOnly, models.ScopeStreamersRead},
+	{http.MethodPost, "/api/v1/streamer/keys", controllers.CreateMyStreamerStreamKeyHandler, streamerOnly, noScope},
+	{http.MethodDelete, "/api/v1/streamer/keys/:key", controllers.RevokeMyStreamerStreamKeyHandler, streamerOnly, noScope},
+	{http.MethodGet, "/api/v1/streamer/schedules", controllers.GetMyStreamerSchedulesHandler, streamerOnly, models.ScopeDestinationsRead},
+	{http.MethodPost, "/api/v1/streamer/schedules", controllers.CreateMyStreamerScheduleHandler, streamerOnly, models.ScopeDestinationsWrite},
+	{http.MethodPut, "/api/v1/streamer/schedules/:schedule", controllers.UpdateMyStreamerScheduleHandler, streamerOnly, models.ScopeDestinationsWrite},
+	{http.MethodDelete, "/api/v1/streamer/schedules/:schedule", controllers.DeleteMyStreamerScheduleHandler, streamerOnly, models.ScopeDestinationsWrite},
+
+	{http.MethodGet, "/api/v1/sessions", controllers.GetSessionsHandler, readOnly, models.ScopeSessionsRead},
+	{http.MethodPost, "/api/v1/sessions", controllers.CreateSessionHandler, sessionManager, models.ScopeSessionsWrite},
+	{http.MethodGet, "/api/v1/sessions/:session", controllers.GetSessionHandler, readOnly, models.ScopeSessionsRead},
+	{http.MethodPost, "/api/v1/sessions/:session/destinations", controllers.AddDestinationHandler, sessionManager, models.ScopeDestinationsWrite},
+	{http.MethodGet, "/api/v1/sessions/:session/destinations", controllers.GetDestinationsHandler, readOnly, models.ScopeDestinationsRead},
+	{http.MethodDelete, "/api/v1/sessions/:session/destinations/:destination", controllers.RemoveDestinationHandler, sessionManager, models.ScopeDestinationsWrite},
+	{http.MethodDelete, "/api/v1/sessions/:session", controllers.DeleteSessionHandler, sessionManager, models.ScopeSessionsWrite},
+	{http.MethodGet, "/api/v1/history", controllers.GetSessionHistoryHandler, readOnly, models.ScopeSessionsRead},
+	{http.MethodGet, "/api/v1/events", controllers.GetEventsHandler, readOnly, models.ScopeSessionsRead},
+	{http.MethodGet, "/api/v1/events/ws", controllers.GetEventsWebSocketHandler, readOnly, models.ScopeSessionsRead},
+
+	{http.MethodGet, "/api/v1/admin/bans", controllers.GetBansHandler, adminOnly, noScope},
+	{http.MethodDelete, "/api/v1/admin/bans", controllers.DeleteBansHandler, adminOnly, noScope},
+	{http.MethodDelete, "/api/v1/admin/bans/:ip", controllers.DeleteBanHandler, adminOnly, noScope},
+
+	{http.MethodGet, "/api/v1/admin/accounts", controllers.GetAdminsHandler, adminOnly, noScope},
+	{http.MethodPost, "/api/v1/admin/accounts", controllers.CreateAdminHandler, adminOnly, noScope},
+	{http.MethodPut, "/api/v1/admin/accounts/:admin", controllers.UpdateAdminHandler, adminOnly, noScope},
+	{http.MethodDelete, "/api/v1/admin/accounts/:admin", controllers.DeleteAdminHandler, adminOnly, noScope},
+
+	{http.MethodGet, "/api/v1/admin/keys", controllers.GetAdminAPIKeysHandler, adminOnly, noScope},
+	{http.MethodPost, "/api/v1/admin/keys", controllers.CreateAdminAPIKeyHandler, adminOnly, noScope},
+	{http.MethodDelete, "/api/v1/admin/keys/:key", controllers.DeleteAdminAPIKeyHandler, adminOnly, noScope},
+
+	{http.MethodGet, "/api/v1/admin/tokens", controllers.GetAPITokensHandler, adminOnly, noScope},
+	{http.MethodPost, "/api/v1/admin/tokens", controllers.CreateAPITokenHandler, adminOnly, noScope},
+	{http.MethodDelete, "/api/v1/admin/tokens/:token", controllers.DeleteAPITokenHandler, adminOnly, noScope},
+
+	{http.MethodGet, "/api/v1/admin/webhooks", controllers.GetWebhooksHandler, adminOnly, noScope},
+	{http.MethodPost, "/api/v1/admin/webhooks", controllers.CreateWebhookHandler, adminOnly, noScope},
+	{http.MethodPut, "/api/v1/admin/webhooks/:webhook", controllers.UpdateWebhookHandler, adminOnly, noScope},
+	{http.MethodDelete, "/api/v1/admin/webhooks/:webhook", controllers.DeleteWebhookHandler, adminOnly, noScope},
+	{http.MethodPost, "/api/v1/admin/webhooks/:webhook/ping", controllers.PingWebhookHandler, adminOnly, noScope},
+	{http.MethodGet, "/api/v1/admin/webhooks/:webhook/deliveries", controllers.GetWebhookDeliveriesHandler, adminOnly, noScope},
 
+	{http.MethodGet, "/api/v1/streamer/tokens", controllers.GetMyStreamerAPITokensHandler, streamerOnly, noScope},
+	{http.MethodPost, "/api/v1/streamer/tokens", controllers.CreateMyStreamerAPITokenHandler, streamerOnly, noScope},
+	{http.MethodDelete, "/api/v1/streamer/tokens/:token", controllers.DeleteMyStreamerAPITokenHandler, streamerOnly, noScope},
+
+	{http.MethodGet, "/api/v1/audit", controllers.GetAuditHandler, adminOnly, noScope},
+
+	{http.MethodGet, "/api/v1/admin/export", controllers.GetExportHandler, adminOnly, noScope},
+	{http.MethodPost, "/api/v1/admin/import", controllers.ImportHandler, adminOnly, noScope},
+	{http.MethodGet, "/api/v1/admin/backup", controllers.GetBackupHandler, adminOnly, noScope},
+
+	{http.MethodGet, "/metrics", echo.WrapHandler(promhttp.Handler()), readOnly, noScope},
+
+	{http.MethodGet, "/healthz", controllers.HealthzHandler, public, noScope},
+	{http.MethodGet, "/readyz", controllers.ReadyzHandler, public, noScope},
+	{http.MethodGet, "/api/v1/status", controllers.GetStatusHandler, adminOnly, noScope},
+}
+
+func apiServer() {
 	router := echo.New()
 
-	router.Use(middleware.Logger())
+	if cfg.API.TrustProxyHeaders {
+		router.IPExtractor = echo.ExtractIPFromXFFHeader()
+	} else {
+		router.IPExtractor = echo.ExtractIPDirect()
+	}
+
+	router.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
+		// Health checks run every few seconds and would drown out everything else
+		Skipper: isHealthCheck,
+	}))
 	router.Use(middleware.Recover())
-	router.Use(middleware.CORS())
+
+	if cfg.API.TLS.HSTSMaxAge > 0 {
+		router.Use(hstsMiddleware())
+	}
+
+	router.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins: cfg.API.CORSAllowOrigins,
+	}))
 
 	router.Use(middleware.StaticWithConfig(middleware.StaticConfig{
-		Root:  "web/public",
+		Root:  cfg.API.StaticRoot,
 		Index: "index.html",
 		HTML5: true,
 	}))
 
-	keyAuthConfig := middleware.KeyAuthConfig{KeyLookup: "header:Authorization", Validator: validateAdminKey}
+	router.Use(bruteForceProtection)
+	router.Use(auth.Authenticate)
+	router.Use(requestMetrics)
 
-	router.GET("/api/v1/streamers", controllers.GetStreamersHandler, middleware.KeyAuthWithConfig(keyAuthConfig))
-	router.POST("/api/v1/streamers", controllers.CreateStreamerHandler, middleware.KeyAuthWithConfig(keyAuthConfig))
-	router.GET("/api/v1/streamers/:streamer", controllers.GetStreamerHandler, middleware.KeyAuthWithConfig(keyAuthConfig))
-	router.DELETE("/api/v1/streamers/:streamer", controllers.DeleteStreamerHandler, middleware.KeyAuthWithConfig(keyAuthConfig))
+	for _, r := range routes {
+		// Metrics get their own listener when metricsBind is set
+		if r.path == "/metrics" && cfg.Metrics.Bind != "" {
+			continue
+		}
 
-	router.GET("/api/v1/streamer", controllers.GetMyStreamerHandler)
-	router.GET("/api/v1/streamer/destinations", controllers.GetMyStreamerDestinationsHandler)
-	router.POST("/api/v1/streamer/destinations", controllers.CreateMyStreamerDestinationHandler)
-	router.DELETE("/api/v1/streamer/destinations/:destination", controllers.RemoveMyStreamerDestinationHandler)
+		addRoute(router, r)
+	}
+
+	router.Logger.Fatal(startAPIServer(router))
+}
+
+// addRoute adds the route to router behind the middleware that authorizes it
+func addRoute(router *echo.Echo, r route) {
+	middlewares := []echo.MiddlewareFunc{}
+
+	if r.roles != nil {
+		middlewares = append(middlewares, auth.Require(r.scope, r.roles...))
+	}
+
+	// Disabled streamers can still look around and log out but can't change anything
+	if r.method != http.MethodGet && r.path != "/api/v1/streamer/logout" && strings.HasPrefix(r.path, "/api/v1/streamer/") {
+		middlewares = append(middlewares, refuseDisabledStreamers)
+	}
+
+	router.Add(r.method, r.path, r.handler, middlewares...)
+}
+
+// refuseDisabledStreamers refuses requests from streamers that are disabled
+func refuseDisabledStreamers(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		principal, ok := auth.GetPrincipal(c)
+		if ok && principal.Streamer != nil && principal.Streamer.IsDisabled(time.Now()) {
+			return c.String(http.StatusForbidden, "streamer is disabled")
+		}
+
+		return next(c)
+	}
+}
 
-	router.GET("/api/v1/sessions", controllers.GetSessionsHandler, middleware.KeyAuthWithConfig(keyAuthConfig))
-	router.POST("/api/v1/sessions", controllers.CreateSessionHandler, middleware.KeyAuthWithConfig(keyAuthConfig))
-	router.GET("/api/v1/sessions/:session", controllers.GetSessionHandler)
-	router.POST("/api/v1/sessions/:session/destinations", controllers.AddDestinationHandler)
-	router.GET("/api/v1/sessions/:session/destinations", controllers.GetDestinationsHandler)
-	router.DELETE("/api/v1/sessions/:session/destinations/:destination", controllers.RemoveDestinationHandler)
-	router.DELETE("/api/v1/sessions/:session", controllers.DeleteSessionHandler)
+// isHealthCheck is whether the request is a load balancer or orchestrator probe
+func isHealthCheck(c echo.Context) bool {
+	return c.Path() == "/healthz" || c.Path() == "/readyz"
+}
+
+// bruteForceProtection refuses banned IPs and IPs making too many requests before anything is done to
+// authenticate them.  Failed authentication is counted by auth.Require and the login handlers.  Health
+// checks are let through, a probe from one IP mustn't make the instance look unhealthy
+func bruteForceProtection(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if isHealthCheck(c) {
+			return next(c)
+		}
+
+		ip := c.RealIP()
+
+		if bruteforce.IsBanned(ip) || !bruteforce.Allow(ip) {
+			return c.NoContent(http.StatusTooManyRequests)
+		}
 
-	router.Logger.Fatal(router.Start(":5383"))
+		return next(c)
+	}
 }
 
-func validateAdminKey(key string, c echo.Context) (bool, error) {
-	if key == *adminKey {
-		return true, nil
+// responseStatus is the status the request ends up with, including errors the handler returned that
+// echo hasn't turned into a response yet
+func responseStatus(c echo.Context, err error) int {
+	if he, ok := err.(*echo.HTTPError); ok {
+		return he.Code
+	}
+
+	if err != nil && !c.Response().Committed {
+		return http.StatusInternalServerError
 	}
 
-	return false, nil
+	return c.Response().Status
+}
+
+// requestMetrics times every api request by route
+func requestMetrics(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+
+		err := next(c)
+
+		route := c.Path()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		requestDuration.WithLabelValues(c.Request().Method, route, strconv.Itoa(responseStatus(c, err))).Observe(time.Since(start).Seconds())
+
+		return err
+	}
 }
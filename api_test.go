@@ -0,0 +1,428 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/admins"
+	"github.com/geekgonecrazy/prismplus/auth"
+	"github.com/geekgonecrazy/prismplus/bruteforce"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/sessions"
+	"github.com/geekgonecrazy/prismplus/store/memstore"
+	"github.com/geekgonecrazy/prismplus/streamers"
+	"github.com/geekgonecrazy/prismplus/tokens"
+	"github.com/geekgonecrazy/rtmp-lib/av"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	anyone   = "public"
+	viewer   = models.RoleViewer
+	operator = models.RoleOperator
+	admin    = models.RoleAdmin
+	streamer = models.RoleStreamer
+)
+
+// routeRoles is the least privileged role that can call each route.  Changing who can call a route means
+// changing it here too
+var routeRoles = []struct {
+	method  string
+	path    string
+	minimum string
+}{
+	{http.MethodGet, "/api/v1/streamers", viewer},
+	{http.MethodPost, "/api/v1/streamers", admin},
+	{http.MethodGet, "/api/v1/streamers/:streamer", viewer},
+	{http.MethodPut, "/api/v1/streamers/:streamer", admin},
+	{http.MethodDelete, "/api/v1/streamers/:streamer", admin},
+	{http.MethodPut, "/api/v1/streamers/:streamer/password", admin},
+	{http.MethodPost, "/api/v1/streamers/:streamer/magiclink", admin},
+	{http.MethodPost, "/api/v1/streamers/:streamer/key", admin},
+	{http.MethodPut, "/api/v1/streamers/:streamer/disable", admin},
+	{http.MethodDelete, "/api/v1/streamers/:streamer/disable", admin},
+	{http.MethodPost, "/api/v1/streamers/:streamer/destinations", admin},
+	{http.MethodDelete, "/api/v1/streamers/:streamer/destinations/:destination", admin},
+	{http.MethodPut, "/api/v1/streamers/:streamer/profile", admin},
+	{http.MethodGet, "/api/v1/streamers/:streamer/keys", admin},
+	{http.MethodPost, "/api/v1/streamers/:streamer/keys", admin},
+	{http.MethodDelete, "/api/v1/streamers/:streamer/keys/:key", admin},
+
+	{http.MethodPost, "/api/v1/streamer/login", anyone},
+	{http.MethodPost, "/api/v1/streamer/login/magic", anyone},
+	{http.MethodPost, "/api/v1/streamer/logout", streamer},
+	{http.MethodPut, "/api/v1/streamer/password", streamer},
+	{http.MethodGet, "/api/v1/streamer", streamer},
+	{http.MethodGet, "/api/v1/streamer/history", streamer},
+	{http.MethodGet, "/api/v1/streamer/events", streamer},
+	{http.MethodGet, "/api/v1/streamer/events/ws", streamer},
+	{http.MethodGet, "/api/v1/streamer/destinations", streamer},
+	{http.MethodPost, "/api/v1/streamer/destinations", streamer},
+	{http.MethodDelete, "/api/v1/streamer/destinations/:destination", streamer},
+	{http.MethodGet, "/api/v1/streamer/profiles", streamer},
+	{http.MethodPut, "/api/v1/streamer/profiles/:profile", streamer},
+	{http.MethodDelete, "/api/v1/streamer/profiles/:profile", streamer},
+	{http.MethodPut, "/api/v1/streamer/profile", streamer},
+	{http.MethodGet, "/api/v1/streamer/keys", streamer},
+	{http.MethodPost, "/api/v1/streamer/keys", streamer},
+	{http.MethodDelete, "/api/v1/streamer/keys/:key", streamer},
+	{http.MethodGet, "/api/v1/streamer/schedules", streamer},
+	{http.MethodPost, "/api/v1/streamer/schedules", streamer},
+	{http.MethodPut, "/api/v1/streamer/schedules/:schedule", streamer},
+	{http.MethodDelete, "/api/v1/streamer/schedules/:schedule", streamer},
+	{http.MethodGet, "/api/v1/streamer/tokens", streamer},
+	{http.MethodPost, "/api/v1/streamer/tokens", streamer},
+	{http.MethodDelete, "/api/v1/streamer/tokens/:token", streamer},
+
+	{http.MethodGet, "/api/v1/sessions", viewer},
+	{http.MethodPost, "/api/v1/sessions", operator},
+	{http.MethodGet, "/api/v1/sessions/:session", viewer},
+	{http.MethodPost, "/api/v1/sessions/:session/destinations", operator},
+	{http.MethodGet, "/api/v1/sessions/:session/destinations", viewer},
+	{http.MethodDelete, "/api/v1/sessions/:session/destinations/:destination", operator},
+	{http.MethodDelete, "/api/v1/sessions/:session", operator},
+	{http.MethodGet, "/api/v1/history", viewer},
+	{http.MethodGet, "/api/v1/events", viewer},
+	{http.MethodGet, "/api/v1/events/ws", viewer},
+
+	{http.MethodGet, "/api/v1/admin/bans", admin},
+	{http.MethodDelete, "/api/v1/admin/bans", admin},
+	{http.MethodDelete, "/api/v1/admin/bans/:ip", admin},
+	{http.MethodGet, "/api/v1/admin/accounts", admin},
+	{http.MethodPost, "/api/v1/admin/accounts", admin},
+	{http.MethodPut, "/api/v1/admin/accounts/:admin", admin},
+	{http.MethodDelete, "/api/v1/admin/accounts/:admin", admin},
+	{http.MethodGet, "/api/v1/admin/keys", admin},
+	{http.MethodPost, "/api/v1/admin/keys", admin},
+	{http.MethodDelete, "/api/v1/admin/keys/:key", admin},
+	{http.MethodGet, "/api/v1/admin/tokens", admin},
+	{http.MethodPost, "/api/v1/admin/tokens", admin},
+	{http.MethodDelete, "/api/v1/admin/tokens/:token", admin},
+	{http.MethodGet, "/api/v1/admin/webhooks", admin},
+	{http.MethodPost, "/api/v1/admin/webhooks", admin},
+	{http.MethodPut, "/api/v1/admin/webhooks/:webhook", admin},
+	{http.MethodDelete, "/api/v1/admin/webhooks/:webhook", admin},
+	{http.MethodPost, "/api/v1/admin/webhooks/:webhook/ping", admin},
+	{http.MethodGet, "/api/v1/admin/webhooks/:webhook/deliveries", admin},
+	{http.MethodGet, "/api/v1/audit", admin},
+	{http.MethodGet, "/api/v1/admin/export", admin},
+	{http.MethodPost, "/api/v1/admin/import", admin},
+	{http.MethodGet, "/api/v1/admin/backup", admin},
+	{http.MethodGet, "/api/v1/status", admin},
+
+	{http.MethodGet, "/metrics", viewer},
+	{http.MethodGet, "/healthz", anyone},
+	{http.MethodGet, "/readyz", anyone},
+}
+
+// insufficient is a role just short of each minimum role
+var insufficient = map[string]string{
+	viewer:   streamer,
+	operator: viewer,
+	admin:    operator,
+	streamer: admin,
+}
+
+// setupCredentials logs in as every role against an in-memory store and returns their bearer tokens
+func setupCredentials(t *testing.T) map[string]string {
+	t.Helper()
+
+	dataStore := memstore.New()
+	admins.Setup(dataStore)
+	streamers.Setup(dataStore)
+	tokens.Setup(dataStore)
+
+	credentials := map[string]string{admin: "bootstrap-admin-key"}
+
+	if err := admins.Bootstrap(credentials[admin]); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, role := range []string{operator, viewer} {
+		created, err := admins.CreateAdmin(models.AdminCreatePayload{Name: role, Role: role})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		credentials[role] = created.APIKey.Key
+	}
+
+	created, err := streamers.CreateStreamer(models.StreamerCreatePayload{Name: "streamer", StreamKey: "streamer-stream-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := streamers.SetPassword(*created, "hunter2hunter2"); err != nil {
+		t.Fatal(err)
+	}
+
+	login, err := streamers.Login("streamer", "hunter2hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credentials[streamer] = login.Token
+
+	return credentials
+}
+
+func call(router *echo.Echo, method string, path string, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestRouteRoles(t *testing.T) {
+	credentials := setupCredentials(t)
+
+	expected := map[string]string{}
+	for _, r := range routeRoles {
+		expected[r.method+" "+r.path] = r.minimum
+	}
+
+	// Every route is authorized by its real middleware, in front of a handler that always succeeds
+	router := echo.New()
+	router.Use(auth.Authenticate)
+
+	for _, r := range routes {
+		name := r.method + " " + r.path
+		if _, ok := expected[name]; !ok {
+			t.Errorf("%s has no expected role in routeRoles", name)
+		}
+
+		delete(expected, name)
+
+		r.handler = func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		}
+
+		addRoute(router, r)
+	}
+
+	for name := range expected {
+		t.Errorf("%s is in routeRoles but isn't a route", name)
+	}
+
+	for _, r := range routeRoles {
+		r := r
+
+		t.Run(r.method+" "+r.path, func(t *testing.T) {
+			path := r.path
+			for _, segment := range strings.Split(r.path, "/") {
+				if strings.HasPrefix(segment, ":") {
+					path = strings.Replace(path, segment, "1", 1)
+				}
+			}
+
+			if r.minimum == anyone {
+				if rec := call(router, r.method, path, ""); rec.Code != http.StatusOK {
+					t.Errorf("anonymous got %d, want %d", rec.Code, http.StatusOK)
+				}
+
+				return
+			}
+
+			if rec := call(router, r.method, path, ""); rec.Code != http.StatusUnauthorized {
+				t.Errorf("anonymous got %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+
+			if rec := call(router, r.method, path, credentials[insufficient[r.minimum]]); rec.Code != http.StatusForbidden {
+				t.Errorf("%s got %d, want %d", insufficient[r.minimum], rec.Code, http.StatusForbidden)
+			}
+
+			if rec := call(router, r.method, path, credentials[r.minimum]); rec.Code != http.StatusOK {
+				t.Errorf("%s got %d, want %d", r.minimum, rec.Code, http.StatusOK)
+			}
+
+			// Admins can do everything operators and viewers can
+			if r.minimum == viewer || r.minimum == operator {
+				if rec := call(router, r.method, path, credentials[admin]); rec.Code != http.StatusOK {
+					t.Errorf("admin got %d, want %d", rec.Code, http.StatusOK)
+				}
+			}
+		})
+	}
+}
+
+func TestKeysRedactedBelowAdmin(t *testing.T) {
+	credentials := setupCredentials(t)
+
+	created, err := streamers.CreateStreamer(models.StreamerCreatePayload{Name: "redacted", StreamKey: "secret-stream-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := streamers.AddDestination(*created, models.Destination{Name: "Twitch", Server: "rtmp://live.twitch.tv/app", Key: "secret-destination-key"}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := echo.New()
+	router.Use(auth.Authenticate)
+
+	for _, r := range routes {
+		if r.path == "/api/v1/streamers" || r.path == "/api/v1/streamers/:streamer" {
+			addRoute(router, r)
+		}
+	}
+
+	for _, path := range []string{"/api/v1/streamers", "/api/v1/streamers/2"} {
+		for _, role := range []string{viewer, operator, admin} {
+			rec := call(router, http.MethodGet, path, credentials[role])
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s GET %s got %d, want %d", role, path, rec.Code, http.StatusOK)
+			}
+
+			body := rec.Body.String()
+			leaked := strings.Contains(body, "secret-stream-key") || strings.Contains(body, "secret-destination-key")
+
+			if role == admin && !leaked {
+				t.Errorf("admin GET %s doesn't show keys: %s", path, body)
+			}
+
+			if role != admin && leaked {
+				t.Errorf("%s GET %s shows keys: %s", role, path, body)
+			}
+		}
+	}
+}
+
+func TestOnlyUnauthenticatedCountsTowardsBans(t *testing.T) {
+	credentials := setupCredentials(t)
+
+	bruteforce.Setup(1, time.Minute, time.Minute, 0, 0)
+	t.Cleanup(func() {
+		bruteforce.Setup(0, 0, 0, 0, 0)
+	})
+
+	router := echo.New()
+	router.Use(auth.Authenticate)
+
+	for _, r := range routes {
+		if r.path == "/api/v1/admin/bans" {
+			addRoute(router, r)
+		}
+	}
+
+	ip := httptest.NewRequest(http.MethodGet, "/", nil).RemoteAddr
+	ip = ip[:strings.LastIndex(ip, ":")]
+
+	// A dashboard polling a route its key can't use
+	for i := 0; i < 5; i++ {
+		if rec := call(router, http.MethodGet, "/api/v1/admin/bans", credentials[streamer]); rec.Code != http.StatusForbidden {
+			t.Fatalf("streamer got %d, want %d", rec.Code, http.StatusForbidden)
+		}
+	}
+
+	if bruteforce.IsBanned(ip) {
+		t.Fatal("banned after 403s")
+	}
+
+	if rec := call(router, http.MethodGet, "/api/v1/admin/bans", "wrong-key"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong key got %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	if !bruteforce.IsBanned(ip) {
+		t.Error("not banned after a 401")
+	}
+}
+
+// fakeCodec stands in for an encoder's stream headers
+type fakeCodec struct {
+	codecType av.CodecType
+}
+
+func (c fakeCodec) Type() av.CodecType {
+	return c.codecType
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// TestListSessionsWhilePublishing lists sessions while publishers attach, fail over and detach.  Run it
+// with -race
+func TestListSessionsWhilePublishing(t *testing.T) {
+	credentials := setupCredentials(t)
+
+	if err := sessions.InitializeSessionStore(memstore.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := sessions.CreateAndGetSession(models.SessionPayload{Key: "race-stream-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := echo.New()
+	router.Use(auth.Authenticate)
+
+	for _, r := range routes {
+		if r.method == http.MethodGet && (r.path == "/api/v1/sessions" || r.path == "/api/v1/sessions/:session") {
+			addRoute(router, r)
+		}
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		streams := []av.CodecData{fakeCodec{av.H264}}
+
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			primary, err := session.AttachPublisher(sessions.PublisherPrimary, "127.0.0.1:1000", nil, nopCloser{}, streams)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			backup, err := session.AttachPublisher(sessions.PublisherBackup, "127.0.0.1:2000", nil, nopCloser{}, streams)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			for n := 0; n < 10; n++ {
+				packet := av.Packet{IsKeyFrame: n%5 == 0, Time: time.Duration(n) * 33 * time.Millisecond, Data: []byte{0}}
+
+				session.WritePacket(primary, packet)
+				session.WritePacket(backup, packet)
+			}
+
+			session.DetachPublisher(primary)
+			session.WritePacket(backup, av.Packet{IsKeyFrame: true, Data: []byte{0}})
+			session.DetachPublisher(backup)
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		for _, role := range []string{viewer, admin} {
+			for _, path := range []string{"/api/v1/sessions", "/api/v1/sessions/" + session.ID} {
+				if rec := call(router, http.MethodGet, path, credentials[role]); rec.Code != http.StatusOK {
+					t.Errorf("%s GET %s got %d, want %d", role, path, rec.Code, http.StatusOK)
+				}
+			}
+		}
+	}
+
+	close(stop)
+	<-done
+}
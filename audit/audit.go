@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"log"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/auth"
+	"github.com/geekgonecrazy/prismplus/events"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+var _dataStore store.Store
+
+func Setup(dataStore store.Store) {
+	_dataStore = dataStore
+}
+
+// Record appends an entry for a mutating call to the audit log and publishes it as a config change.
+// before and after are diffed with secrets redacted, either can be nil.  Failing to record is logged but
+// doesn't fail the request
+func Record(c echo.Context, action string, target string, streamerID int, before interface{}, after interface{}) {
+	entry := models.AuditEntry{
+		Time:       time.Now(),
+		Actor:      "anonymous",
+		Action:     action,
+		Target:     target,
+		StreamerID: streamerID,
+		ClientIP:   c.RealIP(),
+	}
+
+	if principal, ok := auth.GetPrincipal(c); ok {
+		entry.Actor = principal.Name
+		entry.Role = principal.Role
+
+		switch {
+		case principal.Admin != nil:
+			entry.ActorID = principal.Admin.ID
+		case principal.Token != nil:
+			entry.ActorID = principal.Token.ID
+		case principal.Streamer != nil:
+			entry.ActorID = principal.Streamer.ID
+		}
+	}
+
+	save(entry, before, after)
+}
+
+// RecordAs is Record for changes made outside the api, like the offline cli
+func RecordAs(actor string, action string, target string, streamerID int, before interface{}, after interface{}) {
+	save(models.AuditEntry{
+		Time:       time.Now(),
+		Actor:      actor,
+		Action:     action,
+		Target:     target,
+		StreamerID: streamerID,
+	}, before, after)
+}
+
+func save(entry models.AuditEntry, before interface{}, after interface{}) {
+	changes, err := diff(before, after)
+	if err != nil {
+		log.Println("Error diffing audit entry:", err)
+	}
+
+	entry.Changes = changes
+
+	if err := _dataStore.CreateAuditEntry(&entry); err != nil {
+		log.Println("Error recording audit entry:", err)
+	}
+
+	events.Publish(events.ConfigChanged, entry.StreamerID, models.ConfigChangedEvent{
+		Action: entry.Action,
+		Target: entry.Target,
+		Actor:  entry.Actor,
+	})
+}
+
+// GetEntries returns a page of the audit log, newest first
+func GetEntries(query models.AuditQuery) (models.AuditPage, error) {
+	if query.Limit <= 0 {
+		query.Limit = defaultLimit
+	}
+
+	if query.Limit > maxLimit {
+		query.Limit = maxLimit
+	}
+
+	return _dataStore.GetAuditEntries(query)
+}
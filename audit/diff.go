@@ -0,0 +1,136 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+
+	"github.com/geekgonecrazy/prismplus/models"
+)
+
+const redacted = "[redacted]"
+
+// secretFields are never written to the audit log, only that they changed
+var secretFields = map[string]bool{
+	"key":          true,
+	"streamKey":    true,
+	"password":     true,
+	"passwordHash": true,
+	"token":        true,
+	"hash":         true,
+	"secret":       true,
+}
+
+// Diff returns what changed between before and after with secrets redacted, the same as the audit
+// log records it
+func Diff(before interface{}, after interface{}) (map[string]models.AuditChange, error) {
+	return diff(before, after)
+}
+
+// diff flattens before and after into dotted paths and returns the paths that differ
+func diff(before interface{}, after interface{}) (map[string]models.AuditChange, error) {
+	beforeFields := map[string]interface{}{}
+	if err := flattenValue(before, beforeFields); err != nil {
+		return nil, err
+	}
+
+	afterFields := map[string]interface{}{}
+	if err := flattenValue(after, afterFields); err != nil {
+		return nil, err
+	}
+
+	changes := map[string]models.AuditChange{}
+
+	for path, b := range beforeFields {
+		a, ok := afterFields[path]
+		if ok && reflect.DeepEqual(a, b) {
+			continue
+		}
+
+		changes[path] = redact(path, models.AuditChange{Before: b, After: a})
+	}
+
+	for path, a := range afterFields {
+		if _, ok := beforeFields[path]; ok {
+			continue
+		}
+
+		changes[path] = redact(path, models.AuditChange{After: a})
+	}
+
+	return changes, nil
+}
+
+func redact(path string, change models.AuditChange) models.AuditChange {
+	if !secretFields[lastSegment(path)] {
+		return change
+	}
+
+	if change.Before != nil {
+		change.Before = redacted
+	}
+
+	if change.After != nil {
+		change.After = redacted
+	}
+
+	return change
+}
+
+func lastSegment(path string) string {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] == '.' {
+			return path[i+1:]
+		}
+	}
+
+	return path
+}
+
+// flattenValue round trips v through json so we diff exactly what the api would show
+func flattenValue(v interface{}, fields map[string]interface{}) error {
+	if v == nil {
+		return nil
+	}
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(buf, &generic); err != nil {
+		return err
+	}
+
+	flatten("", generic, fields)
+
+	return nil
+}
+
+func flatten(prefix string, v interface{}, fields map[string]interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+
+		return prefix + "." + key
+	}
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			flatten(join(key), child, fields)
+		}
+	case []interface{}:
+		for i, child := range value {
+			flatten(join(strconv.Itoa(i)), child, fields)
+		}
+	default:
+		if prefix == "" {
+			prefix = "value"
+		}
+
+		fields[prefix] = value
+	}
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/geekgonecrazy/prismplus/admins"
+	"github.com/geekgonecrazy/prismplus/bruteforce"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+	"github.com/geekgonecrazy/prismplus/streamers"
+	"github.com/geekgonecrazy/prismplus/tokens"
+	"github.com/labstack/echo/v4"
+)
+
+const principalContextKey = "principal"
+
+// Principal is whoever made the request
+type Principal struct {
+	Role string `json:"role"`
+	Name string `json:"name"`
+
+	// Admin is set for admin, operator and viewer roles
+	Admin *models.Admin `json:"admin,omitempty"`
+	// Streamer is set for the streamer role and for tokens bound to a streamer
+	Streamer *models.Streamer `json:"streamer,omitempty"`
+	// Token is set for the token role
+	Token *models.APIToken `json:"token,omitempty"`
+}
+
+// HasRole reports whether the principal has one of roles
+func (p *Principal) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if p.Role == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetPrincipal returns the authenticated caller if there is one
+func GetPrincipal(c echo.Context) (*Principal, bool) {
+	principal, ok := c.Get(principalContextKey).(*Principal)
+
+	return principal, ok
+}
+
+// GetBearerToken returns the token from the Authorization header
+func GetBearerToken(c echo.Context) (string, bool) {
+	authorizationHeader := c.Request().Header.Get("Authorization")
+
+	split := strings.Split(authorizationHeader, " ")
+
+	if len(split) < 2 {
+		return "", false
+	}
+
+	if len(split[1]) == 0 {
+		return "", false
+	}
+
+	return split[1], true
+}
+
+// Authenticate resolves the bearer token to a principal if it can.  It never rejects a request, that is
+// left up to Require so public routes keep working
+func Authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token, ok := GetBearerToken(c)
+		if !ok {
+			return next(c)
+		}
+
+		principal, err := resolve(token)
+		if err != nil {
+			log.Println("Error:", err)
+			return c.NoContent(http.StatusInternalServerError)
+		}
+
+		if principal != nil {
+			c.Set(principalContextKey, principal)
+		}
+
+		return next(c)
+	}
+}
+
+func resolve(token string) (*Principal, error) {
+	if strings.HasPrefix(token, tokens.Prefix) {
+		return resolveAPIToken(token)
+	}
+
+	admin, _, err := admins.Authenticate(token)
+	if err == nil {
+		return &Principal{
+			Role:  admin.Role,
+			Name:  admin.Name,
+			Admin: &admin,
+		}, nil
+	}
+
+	if !errors.Is(err, admins.ErrInvalidKey) {
+		return nil, err
+	}
+
+	streamer, err := streamers.GetStreamerByToken(token)
+	if err == nil {
+		return &Principal{
+			Role:     models.RoleStreamer,
+			Name:     streamer.Name,
+			Streamer: &streamer,
+		}, nil
+	}
+
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func resolveAPIToken(token string) (*Principal, error) {
+	apiToken, err := tokens.Authenticate(token)
+	if err != nil {
+		if errors.Is(err, tokens.ErrInvalidToken) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	principal := &Principal{
+		Role:  models.RoleToken,
+		Name:  apiToken.Name,
+		Token: &apiToken,
+	}
+
+	if apiToken.StreamerID != 0 {
+		streamer, err := streamers.GetStreamer(apiToken.StreamerID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		principal.Streamer = &streamer
+	}
+
+	return principal, nil
+}
+
+// Allowed reports whether the principal may call a route open to roles, or to api tokens with scope.
+// Tokens bound to a streamer can only be used on streamer routes and unbound tokens only on the rest
+func (p *Principal) Allowed(scope string, roles ...string) bool {
+	if p.HasRole(roles...) {
+		return true
+	}
+
+	if p.Token == nil || scope == "" || !p.Token.HasScope(scope) {
+		return false
+	}
+
+	streamerRoute := false
+	for _, role := range roles {
+		if role == models.RoleStreamer {
+			streamerRoute = true
+		}
+	}
+
+	return streamerRoute == (p.Token.StreamerID != 0)
+}
+
+// Require rejects requests that aren't from one of roles or from an api token with scope.  Unauthenticated
+// requests get a 401, which counts as a failure towards banning the caller.  Authenticated ones that aren't
+// allowed get a 403 and don't count, they got their credentials right
+func Require(scope string, roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal, ok := GetPrincipal(c)
+			if !ok {
+				bruteforce.RecordFailure(c.RealIP(), "api: unauthenticated "+c.Request().Method+" "+c.Path())
+				return c.NoContent(http.StatusUnauthorized)
+			}
+
+			if !principal.Allowed(scope, roles...) {
+				return c.NoContent(http.StatusForbidden)
+			}
+
+			return next(c)
+		}
+	}
+}
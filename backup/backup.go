@@ -0,0 +1,902 @@
+// Package backup exports and imports streamers between instances and takes hot copies of the database
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/audit"
+	"github.com/geekgonecrazy/prismplus/events"
+	"github.com/geekgonecrazy/prismplus/helpers"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/quotas"
+	"github.com/geekgonecrazy/prismplus/sessions"
+	"github.com/geekgonecrazy/prismplus/store"
+	"github.com/geekgonecrazy/prismplus/streamers"
+)
+
+var (
+	_dataStore store.Store
+
+	// ErrInvalid is returned for exports that can't be imported and unknown options
+	ErrInvalid = errors.New("invalid")
+	// ErrPassphrase is returned when secrets need a passphrase that is missing or wrong
+	ErrPassphrase = errors.New("passphrase is missing or wrong")
+)
+
+func Setup(dataStore store.Store) {
+	_dataStore = dataStore
+}
+
+// Backup writes a consistent copy of the database to w without stopping the server
+func Backup(w io.Writer) error {
+	return _dataStore.Backup(w)
+}
+
+// Extension is the file extension for backups of the configured store
+func Extension() string {
+	return _dataStore.BackupExtension()
+}
+
+// Export returns every streamer with its destinations, profiles, extra stream keys and limits.  secrets
+// decides whether keys are left out, included or encrypted with passphrase
+func Export(secrets string, passphrase string) (*models.Export, error) {
+	if secrets == "" {
+		secrets = models.ExportSecretsOmit
+	}
+
+	export := &models.Export{
+		Version:    models.ExportVersion,
+		ExportedAt: time.Now(),
+		Secrets:    secrets,
+		Streamers:  []models.ExportStreamer{},
+	}
+
+	var seal func(string) (string, error)
+
+	switch secrets {
+	case models.ExportSecretsOmit:
+		seal = func(string) (string, error) { return "", nil }
+	case models.ExportSecretsPlain:
+		seal = func(value string) (string, error) { return value, nil }
+	case models.ExportSecretsEncrypt:
+		if passphrase == "" {
+			return nil, ErrPassphrase
+		}
+
+		s, encryption, err := newEncryption(passphrase)
+		if err != nil {
+			return nil, err
+		}
+
+		export.Encryption = encryption
+		seal = s.encrypt
+	default:
+		return nil, fmt.Errorf("%w: secrets must be %s, %s or %s", ErrInvalid, models.ExportSecretsOmit, models.ExportSecretsPlain, models.ExportSecretsEncrypt)
+	}
+
+	sealIfSet := func(value string) (string, error) {
+		if value == "" {
+			return "", nil
+		}
+
+		return seal(value)
+	}
+
+	s, err := _dataStore.GetStreamers()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, streamer := range s {
+		exported := models.ExportStreamer{
+			Name:                     streamer.Name,
+			DuplicatePublisherPolicy: streamer.DuplicatePublisherPolicy,
+			Destinations:             []models.ExportDestination{},
+			Profiles:                 []models.ExportProfile{},
+			ActiveProfile:            streamer.ActiveProfile,
+			StreamKeys:               []models.ExportStreamKey{},
+			Limits:                   streamer.Limits,
+			Disabled:                 streamer.Disabled,
+		}
+
+		if exported.StreamKey, err = sealIfSet(streamer.StreamKey); err != nil {
+			return nil, err
+		}
+
+		for _, destination := range streamer.Destinations {
+			key, err := sealIfSet(destination.Key)
+			if err != nil {
+				return nil, err
+			}
+
+			exported.Destinations = append(exported.Destinations, models.ExportDestination{
+				ID:     destination.ID,
+				Name:   destination.Name,
+				Server: destination.Server,
+				Key:    key,
+			})
+		}
+
+		for _, profile := range streamer.Profiles {
+			exported.Profiles = append(exported.Profiles, models.ExportProfile{
+				Name:           profile.Name,
+				DestinationIDs: append([]int{}, profile.DestinationIDs...),
+			})
+		}
+
+		streamKeys, err := _dataStore.GetStreamKeys(streamer.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, streamKey := range streamKeys {
+			key, err := sealIfSet(streamKey.Key)
+			if err != nil {
+				return nil, err
+			}
+
+			exported.StreamKeys = append(exported.StreamKeys, models.ExportStreamKey{
+				Name:    streamKey.Name,
+				Key:     key,
+				Profile: streamKey.Profile,
+			})
+		}
+
+		export.Streamers = append(export.Streamers, exported)
+	}
+
+	return export, nil
+}
+
+type update struct {
+	before models.Streamer
+	after  models.Streamer
+	keys   streamKeyChanges
+}
+
+type creation struct {
+	streamer *models.Streamer
+	keys     streamKeyChanges
+}
+
+// streamKeyChanges are the extra stream keys to remove and add.  A key that changed is removed and added
+// again
+type streamKeyChanges struct {
+	remove []models.StreamKey
+	add    []models.StreamKey
+}
+
+// Import applies an export.  Streamers are matched by name, merge only adds and updates while replace
+// also removes streamers, destinations, profiles and stream keys that aren't in the export.  Keys left out
+// of the export keep their current value.  Nothing is written on a dry run
+func Import(export models.Export, opts models.ImportOptions) (*models.ImportResult, error) {
+	if opts.Mode == "" {
+		opts.Mode = models.ImportMerge
+	}
+
+	if opts.Mode != models.ImportMerge && opts.Mode != models.ImportReplace {
+		return nil, fmt.Errorf("%w: mode must be %s or %s", ErrInvalid, models.ImportMerge, models.ImportReplace)
+	}
+
+	imported, err := openExport(export, opts.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := _dataStore.GetStreamers()
+	if err != nil {
+		return nil, err
+	}
+
+	existingByName := map[string]models.Streamer{}
+	existingKeys := map[int][]models.StreamKey{}
+	for _, streamer := range existing {
+		existingByName[streamer.Name] = streamer
+
+		if existingKeys[streamer.ID], err = _dataStore.GetStreamKeys(streamer.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &models.ImportResult{
+		Mode:    opts.Mode,
+		DryRun:  opts.DryRun,
+		Created: []models.ImportChange{},
+		Updated: []models.ImportChange{},
+		Deleted: []models.ImportChange{},
+	}
+
+	create := []creation{}
+	updates := []update{}
+	remove := []models.Streamer{}
+
+	importedNames := map[string]bool{}
+
+	for _, streamer := range imported {
+		importedNames[streamer.Name] = true
+
+		before, ok := existingByName[streamer.Name]
+		if !ok {
+			created, err := newStreamer(streamer, export.Version)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := checkDestinations(*created, 0); err != nil {
+				return nil, err
+			}
+
+			keys, err := planStreamKeys(nil, streamer.StreamKeys, opts.Mode, export.Version)
+			if err != nil {
+				return nil, err
+			}
+
+			create = append(create, creation{streamer: created, keys: keys})
+			continue
+		}
+
+		after := mergeStreamer(before, streamer, opts.Mode, export.Version)
+
+		if err := checkDestinations(after, len(before.Destinations)); err != nil {
+			return nil, err
+		}
+
+		keys, err := planStreamKeys(existingKeys[before.ID], streamer.StreamKeys, opts.Mode, export.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		changes, err := audit.Diff(diffView(before, existingKeys[before.ID]), diffView(after, keys.apply(existingKeys[before.ID])))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(changes) == 0 {
+			result.Unchanged++
+			continue
+		}
+
+		updates = append(updates, update{before: before, after: after, keys: keys})
+		result.Updated = append(result.Updated, models.ImportChange{ID: before.ID, Name: before.Name, Changes: changes})
+	}
+
+	for _, streamer := range existing {
+		if opts.Mode == models.ImportReplace && !importedNames[streamer.Name] {
+			remove = append(remove, streamer)
+			result.Deleted = append(result.Deleted, models.ImportChange{ID: streamer.ID, Name: streamer.Name})
+		}
+	}
+
+	if err := checkStreamKeys(existing, existingKeys, create, updates, remove); err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun {
+		for _, c := range create {
+			changes, err := audit.Diff(nil, diffView(*c.streamer, c.keys.add))
+			if err != nil {
+				return nil, err
+			}
+
+			result.Created = append(result.Created, models.ImportChange{Name: c.streamer.Name, Changes: changes})
+		}
+
+		return result, nil
+	}
+
+	created := []*models.Streamer{}
+	for _, c := range create {
+		created = append(created, c.streamer)
+	}
+
+	updated := []*models.Streamer{}
+	for i := range updates {
+		updated = append(updated, &updates[i].after)
+	}
+
+	removeIDs := []int{}
+	for _, streamer := range remove {
+		removeIDs = append(removeIDs, streamer.ID)
+	}
+
+	// Keys are removed first so one can move to another streamer
+	for _, u := range updates {
+		if err := removeStreamKeys(u.before, u.keys.remove); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := _dataStore.ImportStreamers(created, updated, removeIDs); err != nil {
+		return nil, err
+	}
+
+	for _, c := range create {
+		if err := addStreamKeys(*c.streamer, c.keys.add); err != nil {
+			return nil, err
+		}
+
+		changes, err := audit.Diff(nil, diffView(*c.streamer, c.keys.add))
+		if err != nil {
+			return nil, err
+		}
+
+		result.Created = append(result.Created, models.ImportChange{ID: c.streamer.ID, Name: c.streamer.Name, Changes: changes})
+
+		events.Publish(events.StreamerCreated, c.streamer.ID, models.StreamerEvent{ID: c.streamer.ID, Name: c.streamer.Name})
+	}
+
+	// Live sessions pick up the changes straight away
+	for _, u := range updates {
+		if err := addStreamKeys(u.after, u.keys.add); err != nil {
+			return nil, err
+		}
+
+		if err := streamers.SyncSession(u.before, u.after); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, streamer := range remove {
+		events.Publish(events.StreamerDeleted, streamer.ID, models.StreamerEvent{ID: streamer.ID, Name: streamer.Name})
+
+		if err := streamers.EndSession(streamer); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// removeStreamKeys deletes some of the streamer's extra stream keys and disconnects any encoder using them
+func removeStreamKeys(streamer models.Streamer, streamKeys []models.StreamKey) error {
+	session, _ := sessions.GetSession(streamer.StreamKey)
+
+	for _, streamKey := range streamKeys {
+		if err := _dataStore.DeleteStreamKey(streamKey.ID); err != nil {
+			return err
+		}
+
+		if session != nil {
+			session.DisconnectStreamKey(streamKey.ID)
+		}
+	}
+
+	return nil
+}
+
+func addStreamKeys(streamer models.Streamer, streamKeys []models.StreamKey) error {
+	for _, streamKey := range streamKeys {
+		streamKey.StreamerID = streamer.ID
+
+		if err := _dataStore.CreateStreamKey(&streamKey); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// openExport checks the export can be imported and decrypts its keys
+func openExport(export models.Export, passphrase string) ([]models.ExportStreamer, error) {
+	if export.Version < 1 || export.Version > models.ExportVersion {
+		return nil, fmt.Errorf("%w: export version %d isn't supported, this version reads up to %d", ErrInvalid, export.Version, models.ExportVersion)
+	}
+
+	open := func(value string) (string, error) { return value, nil }
+
+	if export.Secrets == models.ExportSecretsEncrypt {
+		if export.Encryption == nil {
+			return nil, fmt.Errorf("%w: encrypted export has no encryption details", ErrInvalid)
+		}
+
+		if passphrase == "" {
+			return nil, ErrPassphrase
+		}
+
+		s, err := openEncryption(passphrase, export.Encryption)
+		if err != nil {
+			return nil, err
+		}
+
+		open = s.decrypt
+	}
+
+	openIfSet := func(value string, what string) (string, error) {
+		if value == "" {
+			return "", nil
+		}
+
+		opened, err := open(value)
+		if err != nil {
+			return "", fmt.Errorf("%w: can't decrypt %s", ErrInvalid, what)
+		}
+
+		return opened, nil
+	}
+
+	names := map[string]bool{}
+	imported := []models.ExportStreamer{}
+
+	for _, streamer := range export.Streamers {
+		if streamer.Name == "" {
+			return nil, fmt.Errorf("%w: every streamer needs a name", ErrInvalid)
+		}
+
+		if names[streamer.Name] {
+			return nil, fmt.Errorf("%w: streamer %s is in the export more than once", ErrInvalid, streamer.Name)
+		}
+		names[streamer.Name] = true
+
+		if !models.ValidDuplicatePublisherPolicy(streamer.DuplicatePublisherPolicy) {
+			return nil, fmt.Errorf("%w: %s has an invalid duplicatePublisherPolicy", ErrInvalid, streamer.Name)
+		}
+
+		var err error
+		if streamer.StreamKey, err = openIfSet(streamer.StreamKey, "the stream key of "+streamer.Name); err != nil {
+			return nil, err
+		}
+
+		destinations := []models.ExportDestination{}
+		for _, destination := range streamer.Destinations {
+			if destination.Server == "" {
+				return nil, fmt.Errorf("%w: a destination of %s has no server", ErrInvalid, streamer.Name)
+			}
+
+			if destination.Key, err = openIfSet(destination.Key, "a destination key of "+streamer.Name); err != nil {
+				return nil, err
+			}
+
+			destinations = append(destinations, destination)
+		}
+
+		streamer.Destinations = destinations
+
+		if err := checkStreamer(streamer); err != nil {
+			return nil, err
+		}
+
+		streamKeys := []models.ExportStreamKey{}
+		for _, streamKey := range streamer.StreamKeys {
+			if streamKey.Key, err = openIfSet(streamKey.Key, "a stream key of "+streamer.Name); err != nil {
+				return nil, err
+			}
+
+			if strings.Contains(streamKey.Key, "/") || strings.HasSuffix(streamKey.Key, sessions.BackupKeySuffix) {
+				return nil, fmt.Errorf("%w: stream keys of %s can't contain slashes or end in %s", ErrInvalid, streamer.Name, sessions.BackupKeySuffix)
+			}
+
+			streamKeys = append(streamKeys, streamKey)
+		}
+
+		streamer.StreamKeys = streamKeys
+		imported = append(imported, streamer)
+	}
+
+	return imported, nil
+}
+
+// checkStreamer makes sure an imported streamer's profiles and stream keys only refer to destinations and
+// profiles in the same export
+func checkStreamer(streamer models.ExportStreamer) error {
+	destinationIDs := map[int]bool{}
+	for _, destination := range streamer.Destinations {
+		if destination.ID == 0 {
+			continue
+		}
+
+		if destinationIDs[destination.ID] {
+			return fmt.Errorf("%w: %s has more than one destination with id %d", ErrInvalid, streamer.Name, destination.ID)
+		}
+		destinationIDs[destination.ID] = true
+	}
+
+	profiles := map[string]bool{}
+	for _, profile := range streamer.Profiles {
+		if profile.Name == "" {
+			return fmt.Errorf("%w: every profile of %s needs a name", ErrInvalid, streamer.Name)
+		}
+
+		if profiles[profile.Name] {
+			return fmt.Errorf("%w: %s has more than one profile named %s", ErrInvalid, streamer.Name, profile.Name)
+		}
+		profiles[profile.Name] = true
+
+		for _, id := range profile.DestinationIDs {
+			if !destinationIDs[id] {
+				return fmt.Errorf("%w: profile %s of %s has unknown destination %d", ErrInvalid, profile.Name, streamer.Name, id)
+			}
+		}
+	}
+
+	if streamer.ActiveProfile != "" && !profiles[streamer.ActiveProfile] {
+		return fmt.Errorf("%w: %s has unknown active profile %s", ErrInvalid, streamer.Name, streamer.ActiveProfile)
+	}
+
+	names := map[string]bool{}
+	for _, streamKey := range streamer.StreamKeys {
+		if streamKey.Name == "" {
+			return fmt.Errorf("%w: every stream key of %s needs a name", ErrInvalid, streamer.Name)
+		}
+
+		if names[streamKey.Name] {
+			return fmt.Errorf("%w: %s has more than one stream key named %s", ErrInvalid, streamer.Name, streamKey.Name)
+		}
+		names[streamKey.Name] = true
+
+		if streamKey.Profile != "" && !profiles[streamKey.Profile] {
+			return fmt.Errorf("%w: stream key %s of %s has unknown profile %s", ErrInvalid, streamKey.Name, streamer.Name, streamKey.Profile)
+		}
+	}
+
+	if !models.ValidLimits(streamer.Limits) {
+		return fmt.Errorf("%w: %s has invalid limits", ErrInvalid, streamer.Name)
+	}
+
+	return nil
+}
+
+// checkDestinations refuses an import that gives a streamer more destinations than they're allowed.  Streamers
+// already over their limit can keep what they have
+func checkDestinations(streamer models.Streamer, before int) error {
+	if len(streamer.Destinations) <= before {
+		return nil
+	}
+
+	if err := quotas.CheckDestinationCount(streamer); err != nil {
+		return fmt.Errorf("%w: %s would have %d destinations, %s", ErrInvalid, streamer.Name, len(streamer.Destinations), err)
+	}
+
+	return nil
+}
+
+func newStreamer(imported models.ExportStreamer, version int) (*models.Streamer, error) {
+	streamer := &models.Streamer{
+		Name:                     imported.Name,
+		StreamKey:                imported.StreamKey,
+		DuplicatePublisherPolicy: imported.DuplicatePublisherPolicy,
+		Destinations:             []models.Destination{},
+		Profiles:                 []models.Profile{},
+		NextDestinationID:        1,
+	}
+
+	if streamer.StreamKey == "" {
+		uuid, err := helpers.NewUUID()
+		if err != nil {
+			return nil, err
+		}
+
+		streamer.StreamKey = uuid
+	}
+
+	ids := map[int]int{}
+
+	for _, destination := range imported.Destinations {
+		ids[destination.ID] = streamer.NextDestinationID
+
+		streamer.Destinations = append(streamer.Destinations, models.Destination{
+			ID:     streamer.NextDestinationID,
+			Name:   destination.Name,
+			Server: destination.Server,
+			Key:    destination.Key,
+		})
+		streamer.NextDestinationID++
+	}
+
+	if version >= 2 {
+		streamer.Profiles = importProfiles(imported.Profiles, ids)
+		streamer.ActiveProfile = imported.ActiveProfile
+		streamer.Limits = imported.Limits
+		streamer.Disabled = imported.Disabled
+	}
+
+	return streamer, nil
+}
+
+// importProfiles maps the destination ids of imported profiles to the ids the destinations were given
+func importProfiles(imported []models.ExportProfile, ids map[int]int) []models.Profile {
+	profiles := []models.Profile{}
+
+	for _, profile := range imported {
+		destinationIDs := []int{}
+		for _, id := range profile.DestinationIDs {
+			destinationIDs = append(destinationIDs, ids[id])
+		}
+
+		profiles = append(profiles, models.Profile{Name: profile.Name, DestinationIDs: destinationIDs})
+	}
+
+	return profiles
+}
+
+// mergeStreamer applies an imported streamer on top of an existing one.  Destinations are matched by
+// name and server so they keep their ids.  Profiles are matched by name, and lose any destination that
+// isn't there anymore
+func mergeStreamer(existing models.Streamer, imported models.ExportStreamer, mode string, version int) models.Streamer {
+	after := existing
+	after.Destinations = []models.Destination{}
+
+	if imported.StreamKey != "" {
+		after.StreamKey = imported.StreamKey
+	}
+
+	if mode == models.ImportReplace || imported.DuplicatePublisherPolicy != "" {
+		after.DuplicatePublisherPolicy = imported.DuplicatePublisherPolicy
+	}
+
+	for _, destination := range existing.Destinations {
+		if destination.ID >= after.NextDestinationID {
+			after.NextDestinationID = destination.ID + 1
+		}
+	}
+
+	if mode == models.ImportMerge {
+		after.Destinations = append(after.Destinations, existing.Destinations...)
+	}
+
+	matched := map[int]bool{}
+	ids := map[int]int{}
+
+	for _, destination := range imported.Destinations {
+		i := findDestination(existing.Destinations, destination, matched)
+		if i < 0 {
+			ids[destination.ID] = after.NextDestinationID
+
+			after.Destinations = append(after.Destinations, models.Destination{
+				ID:     after.NextDestinationID,
+				Name:   destination.Name,
+				Server: destination.Server,
+				Key:    destination.Key,
+			})
+			after.NextDestinationID++
+
+			continue
+		}
+
+		match := existing.Destinations[i]
+		matched[match.ID] = true
+		ids[destination.ID] = match.ID
+
+		if destination.Key != "" {
+			match.Key = destination.Key
+		}
+
+		if mode == models.ImportMerge {
+			after.Destinations[i] = match
+		} else {
+			after.Destinations = append(after.Destinations, match)
+		}
+	}
+
+	after.Profiles = []models.Profile{}
+
+	if version >= 2 {
+		after.Limits = imported.Limits
+		after.Disabled = imported.Disabled
+		after.ActiveProfile = imported.ActiveProfile
+
+		profiles := importProfiles(imported.Profiles, ids)
+
+		if mode == models.ImportMerge {
+			named := map[string]bool{}
+			for _, profile := range profiles {
+				named[profile.Name] = true
+			}
+
+			for _, profile := range existing.Profiles {
+				if !named[profile.Name] {
+					after.Profiles = append(after.Profiles, profile)
+				}
+			}
+		}
+
+		after.Profiles = append(after.Profiles, profiles...)
+	} else {
+		after.Profiles = append(after.Profiles, existing.Profiles...)
+	}
+
+	// Like removing a destination through the api, profiles drop the destinations that are gone
+	kept := map[int]bool{}
+	for _, destination := range after.Destinations {
+		kept[destination.ID] = true
+	}
+
+	for i, profile := range after.Profiles {
+		destinationIDs := []int{}
+		for _, id := range profile.DestinationIDs {
+			if kept[id] {
+				destinationIDs = append(destinationIDs, id)
+			}
+		}
+
+		after.Profiles[i].DestinationIDs = destinationIDs
+	}
+
+	if _, ok := after.GetProfile(after.ActiveProfile); !ok {
+		after.ActiveProfile = ""
+	}
+
+	return after
+}
+
+// planStreamKeys works out how to get from a streamer's extra stream keys to the imported ones.  Keys are
+// matched by name, one left out of the export keeps its current value.  Version 1 exports have no stream
+// keys so they are left alone
+func planStreamKeys(existing []models.StreamKey, imported []models.ExportStreamKey, mode string, version int) (streamKeyChanges, error) {
+	changes := streamKeyChanges{remove: []models.StreamKey{}, add: []models.StreamKey{}}
+
+	if version < 2 {
+		return changes, nil
+	}
+
+	byName := map[string]models.StreamKey{}
+	for _, streamKey := range existing {
+		byName[streamKey.Name] = streamKey
+	}
+
+	named := map[string]bool{}
+
+	for _, streamKey := range imported {
+		named[streamKey.Name] = true
+
+		current, ok := byName[streamKey.Name]
+		if ok && (streamKey.Key == "" || streamKey.Key == current.Key) && streamKey.Profile == current.Profile {
+			continue
+		}
+
+		key := streamKey.Key
+		if key == "" && ok {
+			key = current.Key
+		}
+
+		if key == "" {
+			uuid, err := helpers.NewUUID()
+			if err != nil {
+				return changes, err
+			}
+
+			key = uuid
+		}
+
+		if ok {
+			changes.remove = append(changes.remove, current)
+		}
+
+		changes.add = append(changes.add, models.StreamKey{Name: streamKey.Name, Key: key, Profile: streamKey.Profile})
+	}
+
+	if mode == models.ImportReplace {
+		for _, streamKey := range existing {
+			if !named[streamKey.Name] {
+				changes.remove = append(changes.remove, streamKey)
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// apply returns the stream keys left once the changes are made
+func (c streamKeyChanges) apply(existing []models.StreamKey) []models.StreamKey {
+	removed := map[int]bool{}
+	for _, streamKey := range c.remove {
+		removed[streamKey.ID] = true
+	}
+
+	streamKeys := []models.StreamKey{}
+	for _, streamKey := range existing {
+		if !removed[streamKey.ID] {
+			streamKeys = append(streamKeys, streamKey)
+		}
+	}
+
+	return append(streamKeys, c.add...)
+}
+
+func findDestination(destinations []models.Destination, imported models.ExportDestination, matched map[int]bool) int {
+	for i, destination := range destinations {
+		if !matched[destination.ID] && destination.Name == imported.Name && destination.Server == imported.Server {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// checkStreamKeys makes sure no two streamers would share a stream key once the import is done, counting
+// both their own keys and their extra ones.  Extra keys kept in merge mode must still name a profile
+func checkStreamKeys(existing []models.Streamer, existingKeys map[int][]models.StreamKey, create []creation, updates []update, remove []models.Streamer) error {
+	replaced := map[int]bool{}
+	for _, streamer := range remove {
+		replaced[streamer.ID] = true
+	}
+
+	type final struct {
+		streamer   models.Streamer
+		streamKeys []models.StreamKey
+	}
+
+	all := []final{}
+	for _, u := range updates {
+		replaced[u.before.ID] = true
+		all = append(all, final{u.after, u.keys.apply(existingKeys[u.before.ID])})
+	}
+
+	for _, streamer := range existing {
+		if !replaced[streamer.ID] {
+			all = append(all, final{streamer, existingKeys[streamer.ID]})
+		}
+	}
+
+	for _, c := range create {
+		all = append(all, final{*c.streamer, c.keys.add})
+	}
+
+	keys := map[string]string{}
+	taken := func(key string, name string) error {
+		if other, ok := keys[key]; ok {
+			return fmt.Errorf("%w: %s would have the same stream key as %s", ErrInvalid, name, other)
+		}
+
+		keys[key] = name
+		return nil
+	}
+
+	for _, f := range all {
+		if err := taken(f.streamer.StreamKey, f.streamer.Name); err != nil {
+			return err
+		}
+
+		for _, streamKey := range f.streamKeys {
+			if err := taken(streamKey.Key, f.streamer.Name); err != nil {
+				return err
+			}
+
+			if streamKey.Profile != "" {
+				if _, ok := f.streamer.GetProfile(streamKey.Profile); !ok {
+					return fmt.Errorf("%w: stream key %s of %s would have unknown profile %s", ErrInvalid, streamKey.Name, f.streamer.Name, streamKey.Profile)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// diffView is what an import can change on a streamer, with destinations keyed by id and stream keys by
+// name so the diff reads like the audit log
+func diffView(streamer models.Streamer, streamKeys []models.StreamKey) interface{} {
+	destinations := map[string]models.Destination{}
+	for _, destination := range streamer.Destinations {
+		destinations[fmt.Sprint(destination.ID)] = destination
+	}
+
+	profiles := map[string][]int{}
+	for _, profile := range streamer.Profiles {
+		profiles[profile.Name] = profile.DestinationIDs
+	}
+
+	type streamKeyView struct {
+		Key     string `json:"key"`
+		Profile string `json:"profile"`
+	}
+
+	keys := map[string]streamKeyView{}
+	for _, streamKey := range streamKeys {
+		keys[streamKey.Name] = streamKeyView{streamKey.Key, streamKey.Profile}
+	}
+
+	return struct {
+		StreamKey                string                        `json:"streamKey"`
+		DuplicatePublisherPolicy string                        `json:"duplicatePublisherPolicy"`
+		Destinations             map[string]models.Destination `json:"destinations"`
+		Profiles                 map[string][]int              `json:"profiles"`
+		ActiveProfile            string                        `json:"activeProfile"`
+		StreamKeys               map[string]streamKeyView      `json:"streamKeys"`
+		Limits                   models.Limits                 `json:"limits"`
+		Disabled                 *models.StreamerDisabled      `json:"disabled"`
+	}{streamer.StreamKey, streamer.DuplicatePublisherPolicy, destinations, profiles, streamer.ActiveProfile, keys, streamer.Limits, streamer.Disabled}
+}
@@ -0,0 +1,135 @@
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	algorithm = "scrypt-aes-256-gcm"
+
+	// scrypt parameters recommended for interactive use
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+
+	encryptedPrefix = "enc:"
+	checkValue      = "prismplus"
+)
+
+type sealer struct {
+	aead cipher.AEAD
+}
+
+// newEncryption derives a key from passphrase with a fresh salt
+func newEncryption(passphrase string) (*sealer, *models.ExportEncryption, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, err
+	}
+
+	encryption := &models.ExportEncryption{
+		Algorithm: algorithm,
+		Salt:      salt,
+		N:         scryptN,
+		R:         scryptR,
+		P:         scryptP,
+	}
+
+	s, err := newSealer(passphrase, encryption)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if encryption.Check, err = s.encrypt(checkValue); err != nil {
+		return nil, nil, err
+	}
+
+	return s, encryption, nil
+}
+
+// openEncryption derives the key an export was encrypted with and checks the passphrase is right
+func openEncryption(passphrase string, encryption *models.ExportEncryption) (*sealer, error) {
+	if encryption.Algorithm != algorithm {
+		return nil, fmt.Errorf("%w: unknown encryption %q", ErrInvalid, encryption.Algorithm)
+	}
+
+	// Keep a crafted export from making the key derivation take forever
+	if encryption.N <= 1 || encryption.N > 1<<20 || encryption.R <= 0 || encryption.R > 32 || encryption.P <= 0 || encryption.P > 16 {
+		return nil, fmt.Errorf("%w: unsupported encryption parameters", ErrInvalid)
+	}
+
+	s, err := newSealer(passphrase, encryption)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalid, err)
+	}
+
+	if check, err := s.decrypt(encryption.Check); err != nil || check != checkValue {
+		return nil, ErrPassphrase
+	}
+
+	return s, nil
+}
+
+func newSealer(passphrase string, encryption *models.ExportEncryption) (*sealer, error) {
+	key, err := scrypt.Key([]byte(passphrase), encryption.Salt, encryption.N, encryption.R, encryption.P, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sealer{aead: aead}, nil
+}
+
+// encrypt returns enc: followed by the base64 nonce and ciphertext
+func (s *sealer) encrypt(value string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := s.aead.Seal(nonce, nonce, []byte(value), nil)
+
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (s *sealer) decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return "", errors.New("not encrypted")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < s.aead.NonceSize() {
+		return "", errors.New("too short")
+	}
+
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+
+	plain, err := s.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plain), nil
+}
@@ -0,0 +1,239 @@
+package bruteforce
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// pruneInterval is how often failures, limiters and bans that no longer matter are forgotten
+const pruneInterval = time.Minute
+
+var (
+	_lock     sync.Mutex
+	_failures map[string]*failures
+	_bans     map[string]*Ban
+	_limiters map[string]*limiter
+
+	_maxFailures int
+	_window      time.Duration
+	_banDuration time.Duration
+
+	_rate  rate.Limit
+	_burst int
+
+	ErrNotFound = errors.New("not found")
+)
+
+type failures struct {
+	attempts []time.Time
+}
+
+// limiter is an ip's token bucket
+type limiter struct {
+	bucket   *rate.Limiter
+	lastSeen time.Time
+}
+
+type Ban struct {
+	IP       string    `json:"ip"`
+	Reason   string    `json:"reason"`
+	Failures int       `json:"failures"`
+	BannedAt time.Time `json:"bannedAt"`
+	Until    time.Time `json:"until"`
+}
+
+// Setup configures the tracker.  An IP is banned for banDuration once it has maxFailures failures
+// within window.  A maxFailures of 0 disables banning.  Each IP can also make ratePerMinute requests,
+// with bursts of up to burst, and a ratePerMinute of 0 disables rate limiting
+func Setup(maxFailures int, window time.Duration, banDuration time.Duration, ratePerMinute int, burst int) {
+	_lock.Lock()
+	defer _lock.Unlock()
+
+	_failures = make(map[string]*failures)
+	_bans = make(map[string]*Ban)
+	_limiters = make(map[string]*limiter)
+
+	_maxFailures = maxFailures
+	_window = window
+	_banDuration = banDuration
+
+	_rate = rate.Limit(float64(ratePerMinute) / 60)
+	_burst = burst
+
+	go func() {
+		for now := range time.Tick(pruneInterval) {
+			prune(now)
+		}
+	}()
+}
+
+// Allow takes a token from ip's bucket and reports whether it had one
+func Allow(ip string) bool {
+	_lock.Lock()
+	defer _lock.Unlock()
+
+	if _rate <= 0 {
+		return true
+	}
+
+	l := _limiters[ip]
+	if l == nil {
+		l = &limiter{bucket: rate.NewLimiter(_rate, _burst)}
+		_limiters[ip] = l
+	}
+
+	l.lastSeen = time.Now()
+
+	return l.bucket.Allow()
+}
+
+// prune forgets anyone who hasn't failed recently, buckets that have filled back up and expired bans so
+// the maps don't grow forever
+func prune(now time.Time) {
+	_lock.Lock()
+	defer _lock.Unlock()
+
+	for ip, f := range _failures {
+		if now.Sub(f.attempts[len(f.attempts)-1]) > _window {
+			delete(_failures, ip)
+		}
+	}
+
+	// A bucket that has been left alone long enough to refill is the same as a new one
+	if _rate > 0 {
+		refill := time.Duration(float64(_burst) / float64(_rate) * float64(time.Second))
+		for ip, l := range _limiters {
+			if now.Sub(l.lastSeen) > refill {
+				delete(_limiters, ip)
+			}
+		}
+	}
+
+	for ip, ban := range _bans {
+		if now.After(ban.Until) {
+			delete(_bans, ip)
+		}
+	}
+}
+
+// IsBanned reports whether the ip is currently banned
+func IsBanned(ip string) bool {
+	_lock.Lock()
+	defer _lock.Unlock()
+
+	ban := _bans[ip]
+	if ban == nil {
+		return false
+	}
+
+	if time.Now().After(ban.Until) {
+		delete(_bans, ip)
+		return false
+	}
+
+	return true
+}
+
+// RecordFailure records a failed attempt from ip and returns true if it caused the ip to be banned
+func RecordFailure(ip string, reason string) bool {
+	_lock.Lock()
+	defer _lock.Unlock()
+
+	if _maxFailures <= 0 {
+		return false
+	}
+
+	now := time.Now()
+
+	f := _failures[ip]
+	if f == nil {
+		f = &failures{}
+		_failures[ip] = f
+	}
+
+	// Only keep attempts that are still inside of the window
+	recent := f.attempts[:0]
+	for _, attempt := range f.attempts {
+		if now.Sub(attempt) < _window {
+			recent = append(recent, attempt)
+		}
+	}
+
+	f.attempts = append(recent, now)
+
+	if len(f.attempts) < _maxFailures {
+		return false
+	}
+
+	_bans[ip] = &Ban{
+		IP:       ip,
+		Reason:   reason,
+		Failures: len(f.attempts),
+		BannedAt: now,
+		Until:    now.Add(_banDuration),
+	}
+
+	delete(_failures, ip)
+
+	log.Printf("Banned %s until %s after %d failed attempts: %s", ip, now.Add(_banDuration).Format(time.RFC3339), _maxFailures, reason)
+
+	return true
+}
+
+// GetBans returns the currently active bans
+func GetBans() []Ban {
+	_lock.Lock()
+	defer _lock.Unlock()
+
+	now := time.Now()
+
+	bans := []Ban{}
+	for ip, ban := range _bans {
+		if now.After(ban.Until) {
+			delete(_bans, ip)
+			continue
+		}
+
+		bans = append(bans, *ban)
+	}
+
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].BannedAt.Before(bans[j].BannedAt)
+	})
+
+	return bans
+}
+
+// ClearBan lifts the ban on ip and forgets its failures
+func ClearBan(ip string) error {
+	_lock.Lock()
+	defer _lock.Unlock()
+
+	delete(_failures, ip)
+
+	if _bans[ip] == nil {
+		return ErrNotFound
+	}
+
+	delete(_bans, ip)
+
+	log.Println("Cleared ban for", ip)
+
+	return nil
+}
+
+// ClearBans lifts all bans
+func ClearBans() {
+	_lock.Lock()
+	defer _lock.Unlock()
+
+	_failures = make(map[string]*failures)
+	_bans = make(map[string]*Ban)
+
+	log.Println("Cleared all bans")
+}
@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+)
+
+// backend does the work of a command, either through the api or directly on the store
+type backend interface {
+	ListStreamers() ([]models.Streamer, error)
+	CreateStreamer(payload models.StreamerCreatePayload) (*models.Streamer, error)
+	DeleteStreamer(id int) error
+	RotateStreamKey(id int) (*models.Streamer, error)
+
+	AddDestination(streamerID int, destination models.Destination) (*models.Destination, error)
+	RemoveDestination(streamerID int, destinationID int) error
+
+	ListSessions() ([]session, error)
+	EndSession(key string) error
+
+	Close() error
+}
+
+// session is the part of a live session the cli shows
+type session struct {
+	StreamerID      int                    `json:"streamerId"`
+	Key             string                 `json:"key"`
+	Active          bool                   `json:"active"`
+	ActivePublisher string                 `json:"activePublisher"`
+	Destinations    map[string]destination `json:"destinations"`
+}
+
+type destination struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Server string `json:"server"`
+}
+
+type apiBackend struct {
+	server   string
+	adminKey string
+	client   *http.Client
+}
+
+func newAPIBackend(server string, adminKey string) (*apiBackend, error) {
+	if adminKey == "" {
+		return nil, errors.New("an admin key is needed, pass -adminKey or set PRISMPLUS_ADMIN_KEY")
+	}
+
+	u, err := url.Parse(server)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%q isn't an http or https url", server)
+	}
+
+	return &apiBackend{
+		server:   strings.TrimSuffix(server, "/"),
+		adminKey: adminKey,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// do calls the api and decodes the response into out if it isn't nil
+func (a *apiBackend) do(method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		buf, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		body = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequest(method, a.server+path, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+a.adminKey)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		message, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+
+		switch res.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return errors.New("the admin key was rejected")
+		case http.StatusNotFound:
+			return errors.New("not found")
+		}
+
+		if len(message) > 0 {
+			return fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(message)))
+		}
+
+		return errors.New(res.Status)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+func (a *apiBackend) ListStreamers() ([]models.Streamer, error) {
+	streamers := []models.Streamer{}
+
+	return streamers, a.do(http.MethodGet, "/api/v1/streamers", nil, &streamers)
+}
+
+func (a *apiBackend) CreateStreamer(payload models.StreamerCreatePayload) (*models.Streamer, error) {
+	streamer := &models.Streamer{}
+
+	return streamer, a.do(http.MethodPost, "/api/v1/streamers", payload, streamer)
+}
+
+func (a *apiBackend) DeleteStreamer(id int) error {
+	return a.do(http.MethodDelete, "/api/v1/streamers/"+strconv.Itoa(id), nil, nil)
+}
+
+func (a *apiBackend) RotateStreamKey(id int) (*models.Streamer, error) {
+	streamer := &models.Streamer{}
+
+	return streamer, a.do(http.MethodPost, "/api/v1/streamers/"+strconv.Itoa(id)+"/key", nil, streamer)
+}
+
+func (a *apiBackend) AddDestination(streamerID int, destination models.Destination) (*models.Destination, error) {
+	added := &models.Destination{}
+
+	return added, a.do(http.MethodPost, "/api/v1/streamers/"+strconv.Itoa(streamerID)+"/destinations", destination, added)
+}
+
+func (a *apiBackend) RemoveDestination(streamerID int, destinationID int) error {
+	return a.do(http.MethodDelete, "/api/v1/streamers/"+strconv.Itoa(streamerID)+"/destinations/"+strconv.Itoa(destinationID), nil, nil)
+}
+
+func (a *apiBackend) ListSessions() ([]session, error) {
+	sessions := []session{}
+
+	return sessions, a.do(http.MethodGet, "/api/v1/sessions", nil, &sessions)
+}
+
+func (a *apiBackend) EndSession(key string) error {
+	return a.do(http.MethodDelete, "/api/v1/sessions/"+url.PathEscape(key), nil, nil)
+}
+
+func (a *apiBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,239 @@
+// Package cli is the prismplus admin command line.  Commands talk to a running server's api with an admin
+// key, or with -offline work on the store directly
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/geekgonecrazy/prismplus/config"
+)
+
+type command struct {
+	usage string
+	run   func(opts *options, args []string) error
+}
+
+// commands is every group of subcommands by name
+var commands = map[string]map[string]command{
+	"streamers": {
+		"list":       {"", listStreamers},
+		"create":     {"-name <name> [-streamKey <key>] [-duplicatePublisherPolicy reject|takeover]", createStreamer},
+		"delete":     {"<streamer id>", deleteStreamer},
+		"rotate-key": {"<streamer id>", rotateStreamKey},
+	},
+	"sessions": {
+		"list": {"", listSessions},
+		"end":  {"<session key>", endSession},
+	},
+	"destinations": {
+		"add": {"-streamer <id> -name <name> -server <rtmp url> -key <key>", addDestination},
+		"rm":  {"-streamer <id> <destination id>", removeDestination},
+	},
+}
+
+var (
+	errUsage   = errors.New("usage")
+	errOffline = errors.New("sessions only exist while the server is running, leave out -offline")
+)
+
+// options are the flags every command takes
+type options struct {
+	server   string
+	adminKey string
+	offline  bool
+	config   string
+	dataPath string
+	store    string
+	json     bool
+
+	flags  *flag.FlagSet
+	out    io.Writer
+	client backend
+}
+
+// IsCommand reports whether args start with a cli command rather than server flags
+func IsCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	_, ok := commands[args[0]]
+
+	return ok || args[0] == "config" || args[0] == "help"
+}
+
+// Run runs the command in args and returns the exit code
+func Run(args []string) int {
+	switch args[0] {
+	case "config":
+		return configCommand(args[1:])
+	case "help":
+		printUsage(os.Stdout)
+		return 0
+	}
+
+	group, ok := commands[args[0]]
+	if !ok || len(args) < 2 {
+		printUsage(os.Stderr)
+		return 2
+	}
+
+	cmd, ok := group[args[1]]
+	if !ok {
+		printUsage(os.Stderr)
+		return 2
+	}
+
+	name := "prismplus " + args[0] + " " + args[1]
+
+	opts := &options{out: os.Stdout}
+	opts.flags = flag.NewFlagSet(name, flag.ContinueOnError)
+	opts.flags.StringVar(&opts.server, "api", envOr("PRISMPLUS_API", "http://localhost:5383"), "Address of the prismplus api.  Also set with PRISMPLUS_API")
+	opts.flags.StringVar(&opts.adminKey, "adminKey", "", "Admin key to call the api with.  Also set with PRISMPLUS_ADMIN_KEY")
+	opts.flags.BoolVar(&opts.offline, "offline", false, "Work on the data directory directly instead of the api.  The server must be stopped")
+	opts.flags.StringVar(&opts.config, "config", envOr("PRISMPLUS_CONFIG", ""), "Server config file for -offline, its limits apply and -dataPath and -store default to it")
+	opts.flags.StringVar(&opts.dataPath, "dataPath", envOr("PRISMPLUS_DATA_PATH", "./"), "Data directory for -offline")
+	opts.flags.StringVar(&opts.store, "store", envOr("PRISMPLUS_STORE", ""), "Store url for -offline, defaults to data.bbolt in -dataPath")
+	opts.flags.BoolVar(&opts.json, "json", false, "Print JSON instead of a table")
+	opts.flags.Usage = func() {
+		fmt.Fprintf(opts.flags.Output(), "Usage: %s [flags] %s\n", name, cmd.usage)
+		opts.flags.PrintDefaults()
+	}
+
+	err := cmd.run(opts, args[2:])
+	if opts.client != nil {
+		opts.client.Close()
+	}
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		opts.flags.Usage()
+		return 2
+	}
+
+	fmt.Fprintln(os.Stderr, "Error:", err)
+
+	return 1
+}
+
+// parse parses the flags and connects to the server or opens the data directory
+func (opts *options) parse(args []string, positional int) ([]string, error) {
+	if err := opts.flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+
+		return nil, errUsage
+	}
+
+	if opts.flags.NArg() != positional {
+		return nil, errUsage
+	}
+
+	// Not a flag default so it isn't printed in the usage
+	if opts.adminKey == "" {
+		opts.adminKey = os.Getenv("PRISMPLUS_ADMIN_KEY")
+	}
+
+	if opts.offline {
+		c, err := opts.offlineConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		client, err := newOfflineBackend(c)
+		if err != nil {
+			return nil, err
+		}
+
+		opts.client = client
+	} else {
+		client, err := newAPIBackend(opts.server, opts.adminKey)
+		if err != nil {
+			return nil, err
+		}
+
+		opts.client = client
+	}
+
+	return opts.flags.Args(), nil
+}
+
+// offlineConfig is the server's configuration from -config and the environment, with -dataPath and
+// -store given on the command line taking precedence
+func (opts *options) offlineConfig() (*config.Config, error) {
+	args := []string{}
+	if opts.config != "" {
+		args = append(args, "-config", opts.config)
+	}
+
+	c, err := config.Load("prismplus", args)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "dataPath":
+			c.DataPath = opts.dataPath
+		case "store":
+			c.Store = opts.store
+		}
+	})
+
+	return c, nil
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  prismplus [flags]                     Run the server")
+	fmt.Fprintln(w, "  prismplus config print [flags]        Print the effective configuration")
+
+	groups := []string{}
+	for group := range commands {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	for _, group := range groups {
+		names := []string{}
+		for name := range commands[group] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			fmt.Fprintf(w, "  prismplus %s %s [flags] %s\n", group, name, commands[group][name].usage)
+		}
+	}
+
+	fmt.Fprintln(w, "\nRun a command with -h to see its flags")
+}
+
+func envOr(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+func parseID(arg string, what string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimSpace(arg))
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%q isn't a valid %s id", arg, what)
+	}
+
+	return id, nil
+}
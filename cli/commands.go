@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/geekgonecrazy/prismplus/models"
+)
+
+func listStreamers(opts *options, args []string) error {
+	showKeys := opts.flags.Bool("showKeys", false, "Show stream keys instead of hiding them")
+
+	if _, err := opts.parse(args, 0); err != nil {
+		return err
+	}
+
+	streamers, err := opts.client.ListStreamers()
+	if err != nil {
+		return err
+	}
+
+	if opts.json {
+		return opts.printJSON(streamers)
+	}
+
+	w := tabwriter.NewWriter(opts.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSTREAM KEY\tDESTINATIONS\tDUPLICATE PUBLISHERS")
+
+	for _, streamer := range streamers {
+		key := "(hidden)"
+		if *showKeys {
+			key = streamer.StreamKey
+		}
+
+		policy := streamer.DuplicatePublisherPolicy
+		if policy == "" {
+			policy = models.DuplicatePublisherReject
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\n", streamer.ID, streamer.Name, key, len(streamer.Destinations), policy)
+	}
+
+	return w.Flush()
+}
+
+func createStreamer(opts *options, args []string) error {
+	payload := models.StreamerCreatePayload{}
+	opts.flags.StringVar(&payload.Name, "name", "", "Streamer name")
+	opts.flags.StringVar(&payload.StreamKey, "streamKey", "", "Stream key.  One is generated if empty")
+	opts.flags.StringVar(&payload.DuplicatePublisherPolicy, "duplicatePublisherPolicy", "", "reject or takeover")
+
+	if _, err := opts.parse(args, 0); err != nil {
+		return err
+	}
+
+	if payload.Name == "" {
+		return errUsage
+	}
+
+	if !models.ValidDuplicatePublisherPolicy(payload.DuplicatePublisherPolicy) {
+		return fmt.Errorf("%q isn't a duplicate publisher policy, use reject or takeover", payload.DuplicatePublisherPolicy)
+	}
+
+	streamer, err := opts.client.CreateStreamer(payload)
+	if err != nil {
+		return err
+	}
+
+	if opts.json {
+		return opts.printJSON(streamer)
+	}
+
+	fmt.Fprintf(opts.out, "Created streamer %d %s\nStream key: %s\n", streamer.ID, streamer.Name, streamer.StreamKey)
+
+	return nil
+}
+
+func deleteStreamer(opts *options, args []string) error {
+	args, err := opts.parse(args, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := parseID(args[0], "streamer")
+	if err != nil {
+		return err
+	}
+
+	if err := opts.client.DeleteStreamer(id); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(opts.out, "Deleted streamer %d\n", id)
+
+	return nil
+}
+
+func rotateStreamKey(opts *options, args []string) error {
+	args, err := opts.parse(args, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := parseID(args[0], "streamer")
+	if err != nil {
+		return err
+	}
+
+	streamer, err := opts.client.RotateStreamKey(id)
+	if err != nil {
+		return err
+	}
+
+	if opts.json {
+		return opts.printJSON(streamer)
+	}
+
+	fmt.Fprintf(opts.out, "New stream key for %s: %s\n", streamer.Name, streamer.StreamKey)
+
+	return nil
+}
+
+func listSessions(opts *options, args []string) error {
+	if _, err := opts.parse(args, 0); err != nil {
+		return err
+	}
+
+	sessions, err := opts.client.ListSessions()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Key < sessions[j].Key
+	})
+
+	if opts.json {
+		return opts.printJSON(sessions)
+	}
+
+	w := tabwriter.NewWriter(opts.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tSTREAMER\tLIVE\tPUBLISHER\tDESTINATIONS")
+
+	for _, session := range sessions {
+		streamer := "-"
+		if session.StreamerID != 0 {
+			streamer = fmt.Sprint(session.StreamerID)
+		}
+
+		names := []string{}
+		for _, destination := range session.Destinations {
+			names = append(names, destination.Name)
+		}
+		sort.Strings(names)
+
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\n", session.Key, streamer, session.Active, session.ActivePublisher, strings.Join(names, ", "))
+	}
+
+	return w.Flush()
+}
+
+func endSession(opts *options, args []string) error {
+	args, err := opts.parse(args, 1)
+	if err != nil {
+		return err
+	}
+
+	if err := opts.client.EndSession(args[0]); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(opts.out, "Ended session", args[0])
+
+	return nil
+}
+
+func addDestination(opts *options, args []string) error {
+	streamerID := opts.flags.Int("streamer", 0, "Streamer id")
+
+	destination := models.Destination{}
+	opts.flags.StringVar(&destination.Name, "name", "", "Destination name")
+	opts.flags.StringVar(&destination.Server, "server", "", "RTMP server, for example rtmp://host/live")
+	opts.flags.StringVar(&destination.Key, "key", "", "Stream key for the destination")
+
+	if _, err := opts.parse(args, 0); err != nil {
+		return err
+	}
+
+	if *streamerID <= 0 || destination.Server == "" {
+		return errUsage
+	}
+
+	added, err := opts.client.AddDestination(*streamerID, destination)
+	if err != nil {
+		return err
+	}
+
+	if opts.json {
+		return opts.printJSON(added)
+	}
+
+	fmt.Fprintf(opts.out, "Added destination %d %s\n", added.ID, added.Name)
+
+	return nil
+}
+
+func removeDestination(opts *options, args []string) error {
+	streamerID := opts.flags.Int("streamer", 0, "Streamer id")
+
+	args, err := opts.parse(args, 1)
+	if err != nil {
+		return err
+	}
+
+	if *streamerID <= 0 {
+		return errUsage
+	}
+
+	id, err := parseID(args[0], "destination")
+	if err != nil {
+		return err
+	}
+
+	if err := opts.client.RemoveDestination(*streamerID, id); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(opts.out, "Removed destination %d\n", id)
+
+	return nil
+}
+
+func (opts *options) printJSON(v interface{}) error {
+	encoder := json.NewEncoder(opts.out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(v)
+}
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/geekgonecrazy/prismplus/config"
+)
+
+// configCommand handles `prismplus config print`
+func configCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "Usage: prismplus config print [flags]")
+		return 2
+	}
+
+	c, err := config.Load("prismplus config print", args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err := c.Print(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
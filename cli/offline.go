@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/audit"
+	"github.com/geekgonecrazy/prismplus/config"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/quotas"
+	"github.com/geekgonecrazy/prismplus/store"
+	"github.com/geekgonecrazy/prismplus/store/boltstore"
+	"github.com/geekgonecrazy/prismplus/store/sqlitestore"
+	"github.com/geekgonecrazy/prismplus/streamers"
+	bolt "go.etcd.io/bbolt"
+)
+
+// offlineActor is who offline changes are recorded as in the audit log
+const offlineActor = "cli (offline)"
+
+// offlineBackend changes the store directly, the same way the api would
+type offlineBackend struct {
+	dataStore store.Store
+}
+
+// newOfflineBackend opens the store in c.  Streamers are checked against the limits in c, like the server
+// would
+func newOfflineBackend(c *config.Config) (*offlineBackend, error) {
+	storeURL := c.StoreURL()
+
+	if strings.HasPrefix(storeURL, "memory://") {
+		return nil, errors.New("a memory store only exists inside the server, leave out -offline")
+	}
+
+	// The server holds the database locked the whole time it runs, don't wait long for it
+	boltstore.OpenTimeout = time.Second
+	sqlitestore.BusyTimeout = time.Second
+
+	dataStore, err := store.Open(storeURL)
+	if err != nil {
+		if errors.Is(err, bolt.ErrTimeout) || errors.Is(err, sqlitestore.ErrLocked) {
+			return nil, fmt.Errorf("%s is in use, stop the server or leave out -offline", storeURL)
+		}
+
+		return nil, err
+	}
+
+	quotas.Defaults = c.Limits.Limits()
+
+	streamers.Setup(dataStore)
+	audit.Setup(dataStore)
+
+	return &offlineBackend{dataStore: dataStore}, nil
+}
+
+func (o *offlineBackend) ListStreamers() ([]models.Streamer, error) {
+	return streamers.GetStreamers()
+}
+
+func (o *offlineBackend) CreateStreamer(payload models.StreamerCreatePayload) (*models.Streamer, error) {
+	streamer, err := streamers.CreateStreamer(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	audit.RecordAs(offlineActor, "streamer.create", streamerTarget(streamer.ID), streamer.ID, nil, streamer)
+
+	return streamer, nil
+}
+
+func (o *offlineBackend) DeleteStreamer(id int) error {
+	before, err := streamers.GetStreamer(id)
+	if err != nil {
+		return notFound(err)
+	}
+
+	if err := streamers.DeleteStreamer(id); err != nil {
+		return notFound(err)
+	}
+
+	audit.RecordAs(offlineActor, "streamer.delete", streamerTarget(id), id, before, nil)
+
+	return nil
+}
+
+func (o *offlineBackend) RotateStreamKey(id int) (*models.Streamer, error) {
+	streamer, err := streamers.GetStreamer(id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	rotated, err := streamers.RotateStreamKey(streamer)
+	if err != nil {
+		return nil, err
+	}
+
+	audit.RecordAs(offlineActor, "streamer.key.rotate", streamerTarget(id), id, streamer, rotated)
+
+	return rotated, nil
+}
+
+func (o *offlineBackend) AddDestination(streamerID int, destination models.Destination) (*models.Destination, error) {
+	streamer, err := streamers.GetStreamer(streamerID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	if err := streamers.AddDestination(streamer, destination); err != nil {
+		return nil, err
+	}
+
+	updated, err := streamers.GetStreamer(streamerID)
+	if err != nil {
+		return nil, err
+	}
+
+	audit.RecordAs(offlineActor, "streamer.destination.add", streamerTarget(streamerID), streamerID, destinationsByID(streamer.Destinations), destinationsByID(updated.Destinations))
+
+	return &updated.Destinations[len(updated.Destinations)-1], nil
+}
+
+func (o *offlineBackend) RemoveDestination(streamerID int, destinationID int) error {
+	streamer, err := streamers.GetStreamer(streamerID)
+	if err != nil {
+		return notFound(err)
+	}
+
+	if err := streamers.RemoveDestination(streamer, destinationID); err != nil {
+		return notFound(err)
+	}
+
+	updated, err := streamers.GetStreamer(streamerID)
+	if err != nil {
+		return err
+	}
+
+	audit.RecordAs(offlineActor, "streamer.destination.remove", streamerTarget(streamerID), streamerID, destinationsByID(streamer.Destinations), destinationsByID(updated.Destinations))
+
+	return nil
+}
+
+func (o *offlineBackend) ListSessions() ([]session, error) {
+	return nil, errOffline
+}
+
+func (o *offlineBackend) EndSession(key string) error {
+	return errOffline
+}
+
+func (o *offlineBackend) Close() error {
+	if closer, ok := o.dataStore.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, store.ErrNotFound) {
+		return errors.New("not found")
+	}
+
+	return err
+}
+
+// streamerTarget and destinationsByID match the audit entries the api records
+func streamerTarget(id int) string {
+	return fmt.Sprintf("streamer:%d", id)
+}
+
+func destinationsByID(destinations []models.Destination) map[string]models.Destination {
+	byID := map[string]models.Destination{}
+	for _, destination := range destinations {
+		byID[fmt.Sprint(destination.ID)] = destination
+	}
+
+	return byID
+}
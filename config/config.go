@@ -0,0 +1,409 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/geekgonecrazy/prismplus/models"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is prepended to environment variable names, see envName
+const EnvPrefix = "PRISMPLUS_"
+
+// Config is all of prismplus' settings.  Settings come from the defaults, then the config file, then
+// PRISMPLUS_* environment variables and finally flags, each overriding the last
+type Config struct {
+	DataPath       string `yaml:"dataPath"`
+	Store          string `yaml:"store"`
+	AdminKey       string `yaml:"adminKey" secret:"true"`
+	PublishAuthURL string `yaml:"publishAuthURL"`
+
+	RTMP       RTMPConfig       `yaml:"rtmp"`
+	API        APIConfig        `yaml:"api"`
+	BruteForce BruteForceConfig `yaml:"bruteForce"`
+	Sessions   SessionsConfig   `yaml:"sessions"`
+	Streamers  StreamersConfig  `yaml:"streamers"`
+	Limits     LimitsConfig     `yaml:"limits"`
+	Metrics    MetricsConfig    `yaml:"metrics"`
+}
+
+type RTMPConfig struct {
+	Bind            string   `yaml:"bind"`
+	ChunkSize       int      `yaml:"chunkSize"`
+	FailoverTimeout Duration `yaml:"failoverTimeout"`
+}
+
+type APIConfig struct {
+	Bind              string    `yaml:"bind"`
+	StaticRoot        string    `yaml:"staticRoot"`
+	CORSAllowOrigins  []string  `yaml:"corsAllowOrigins"`
+	TrustProxyHeaders bool      `yaml:"trustProxyHeaders"`
+	TLS               TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig serves the api over HTTPS, either with certFile and keyFile or certificates fetched
+// with ACME for acme.domains
+type TLSConfig struct {
+	CertFile              string     `yaml:"certFile"`
+	KeyFile               string     `yaml:"keyFile"`
+	ACME                  ACMEConfig `yaml:"acme"`
+	RedirectBind          string     `yaml:"redirectBind"`
+	HSTSMaxAge            Duration   `yaml:"hstsMaxAge"`
+	HSTSIncludeSubdomains bool       `yaml:"hstsIncludeSubdomains"`
+}
+
+type ACMEConfig struct {
+	Domains      []string `yaml:"domains"`
+	Email        string   `yaml:"email"`
+	DirectoryURL string   `yaml:"directoryURL"`
+	CAFile       string   `yaml:"caFile"`
+	CacheDir     string   `yaml:"cacheDir"`
+}
+
+// Enabled is true if the api should be served over HTTPS
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.ACME.Enabled()
+}
+
+// Enabled is true if certificates should be fetched with ACME
+func (a ACMEConfig) Enabled() bool {
+	return len(a.Domains) > 0
+}
+
+type BruteForceConfig struct {
+	MaxFailures int      `yaml:"maxFailures"`
+	Window      Duration `yaml:"window"`
+	BanDuration Duration `yaml:"banDuration"`
+
+	// RatePerMinute is how many api requests and RTMP publishes each IP can make per minute, with bursts
+	// of up to RateBurst
+	RatePerMinute int `yaml:"ratePerMinute"`
+	RateBurst     int `yaml:"rateBurst"`
+}
+
+type SessionsConfig struct {
+	AdHocTTL Duration `yaml:"adHocTTL"`
+}
+
+type StreamersConfig struct {
+	TokenTTL     Duration `yaml:"tokenTTL"`
+	MagicLinkTTL Duration `yaml:"magicLinkTTL"`
+}
+
+// LimitsConfig is the default limits for every streamer, which can be changed per streamer.  0 is unlimited
+type LimitsConfig struct {
+	MaxDestinations    int      `yaml:"maxDestinations"`
+	MaxBitrateKbps     int      `yaml:"maxBitrateKbps"`
+	BitrateAction      string   `yaml:"bitrateAction"`
+	BitrateGrace       Duration `yaml:"bitrateGrace"`
+	MaxSessionDuration Duration `yaml:"maxSessionDuration"`
+	MonthlyHours       int      `yaml:"monthlyHours"`
+}
+
+type MetricsConfig struct {
+	Bind          string `yaml:"bind"`
+	StreamerNames bool   `yaml:"streamerNames"`
+}
+
+// Default is the configuration used when nothing is set
+func Default() *Config {
+	return &Config{
+		DataPath: "./",
+
+		RTMP: RTMPConfig{
+			Bind:            ":1935",
+			ChunkSize:       128,
+			FailoverTimeout: Duration(3 * time.Second),
+		},
+		API: APIConfig{
+			Bind:             ":5383",
+			StaticRoot:       "web/public",
+			CORSAllowOrigins: []string{"*"},
+			TLS: TLSConfig{
+				ACME: ACMEConfig{
+					DirectoryURL: "https://acme-v02.api.letsencrypt.org/directory",
+				},
+			},
+		},
+		BruteForce: BruteForceConfig{
+			MaxFailures: 10,
+			Window:      Duration(10 * time.Minute),
+			BanDuration: Duration(15 * time.Minute),
+
+			RatePerMinute: 600,
+			RateBurst:     60,
+		},
+		Streamers: StreamersConfig{
+			TokenTTL:     Duration(12 * time.Hour),
+			MagicLinkTTL: Duration(15 * time.Minute),
+		},
+		Limits: LimitsConfig{
+			BitrateAction: "warn",
+			BitrateGrace:  Duration(30 * time.Second),
+		},
+	}
+}
+
+// bindFlags registers a flag for each setting, defaulting to the setting's current value
+func (c *Config) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.DataPath, "dataPath", c.DataPath, "Path for data")
+	fs.StringVar(&c.Store, "store", c.Store, "Store url: bolt://path, sqlite://path or memory://.  Defaults to data.bbolt in dataPath")
+	fs.StringVar(&c.AdminKey, "adminKey", c.AdminKey, "Admin key for the first admin account.  Only used if no admin exists yet, if none passed one will be created")
+	fs.StringVar(&c.PublishAuthURL, "publishAuthURL", c.PublishAuthURL, "URL to POST publish attempts to for authorization.  Disabled if empty")
+
+	fs.StringVar(&c.RTMP.Bind, "bind", c.RTMP.Bind, "RTMP bind address")
+	fs.IntVar(&c.RTMP.ChunkSize, "chunkSize", c.RTMP.ChunkSize, "RTMP chunk size")
+	fs.Var(&c.RTMP.FailoverTimeout, "failoverTimeout", "How long the primary publisher can stall before switching to the backup")
+
+	fs.StringVar(&c.API.Bind, "apiBind", c.API.Bind, "API and web UI bind address")
+	fs.StringVar(&c.API.StaticRoot, "staticRoot", c.API.StaticRoot, "Directory the web UI is served from")
+	fs.Var((*stringList)(&c.API.CORSAllowOrigins), "corsAllowOrigins", "Comma separated origins allowed to call the API from a browser")
+	fs.BoolVar(&c.API.TrustProxyHeaders, "trustProxyHeaders", c.API.TrustProxyHeaders, "Use X-Forwarded-For to determine the client IP.  Only enable behind a trusted reverse proxy")
+
+	fs.StringVar(&c.API.TLS.CertFile, "tlsCert", c.API.TLS.CertFile, "Certificate file to serve the api over HTTPS with")
+	fs.StringVar(&c.API.TLS.KeyFile, "tlsKey", c.API.TLS.KeyFile, "Private key file for -tlsCert")
+	fs.Var((*stringList)(&c.API.TLS.ACME.Domains), "acmeDomains", "Comma separated domains to fetch certificates for with ACME.  Replaces -tlsCert")
+	fs.StringVar(&c.API.TLS.ACME.Email, "acmeEmail", c.API.TLS.ACME.Email, "Contact email for the ACME account")
+	fs.StringVar(&c.API.TLS.ACME.DirectoryURL, "acmeDirectoryURL", c.API.TLS.ACME.DirectoryURL, "ACME directory to fetch certificates from")
+	fs.StringVar(&c.API.TLS.ACME.CAFile, "acmeCAFile", c.API.TLS.ACME.CAFile, "CA certificate to trust when talking to the ACME directory, for private or test ACME servers")
+	fs.StringVar(&c.API.TLS.ACME.CacheDir, "acmeCacheDir", c.API.TLS.ACME.CacheDir, "Where ACME certificates are kept.  Defaults to certs in the data path")
+	fs.StringVar(&c.API.TLS.RedirectBind, "httpRedirectBind", c.API.TLS.RedirectBind, "Address to redirect plain HTTP to HTTPS on, for example :80.  Also answers ACME http-01 challenges")
+	fs.Var(&c.API.TLS.HSTSMaxAge, "hstsMaxAge", "Send Strict-Transport-Security with this max age over HTTPS.  0 disables it")
+	fs.BoolVar(&c.API.TLS.HSTSIncludeSubdomains, "hstsIncludeSubdomains", c.API.TLS.HSTSIncludeSubdomains, "Apply Strict-Transport-Security to subdomains too")
+
+	fs.IntVar(&c.BruteForce.MaxFailures, "banMaxFailures", c.BruteForce.MaxFailures, "Failed authentication attempts from an IP before it is banned.  0 disables banning")
+	fs.Var(&c.BruteForce.Window, "banWindow", "Window failed authentication attempts are counted in")
+	fs.Var(&c.BruteForce.BanDuration, "banDuration", "How long an IP stays banned")
+	fs.IntVar(&c.BruteForce.RatePerMinute, "rateLimit", c.BruteForce.RatePerMinute, "API requests and RTMP publishes allowed per minute from each IP.  0 disables rate limiting")
+	fs.IntVar(&c.BruteForce.RateBurst, "rateBurst", c.BruteForce.RateBurst, "Requests an IP can make in a burst before -rateLimit applies")
+
+	fs.Var(&c.Sessions.AdHocTTL, "adHocSessionTTL", "Remove sessions created through the api once nothing has published to or changed them for this long.  0 keeps them")
+
+	fs.Var(&c.Streamers.TokenTTL, "streamerTokenTTL", "How long a streamer login lasts")
+	fs.Var(&c.Streamers.MagicLinkTTL, "magicLinkTTL", "How long a streamer magic link can be used for")
+
+	fs.IntVar(&c.Limits.MaxDestinations, "maxDestinations", c.Limits.MaxDestinations, "Default limit on each streamer's destinations.  0 is unlimited")
+	fs.IntVar(&c.Limits.MaxBitrateKbps, "maxBitrateKbps", c.Limits.MaxBitrateKbps, "Default maximum ingest bitrate in kbps.  0 is unlimited")
+	fs.StringVar(&c.Limits.BitrateAction, "bitrateAction", c.Limits.BitrateAction, "What to do when a streamer stays over their bitrate for -bitrateGrace: warn or disconnect")
+	fs.Var(&c.Limits.BitrateGrace, "bitrateGrace", "How long ingest can stay over the maximum bitrate before -bitrateAction is taken")
+	fs.Var(&c.Limits.MaxSessionDuration, "maxSessionDuration", "Default longest a streamer can stay live in one go.  0 is unlimited")
+	fs.IntVar(&c.Limits.MonthlyHours, "monthlyHours", c.Limits.MonthlyHours, "Default hours each streamer can be live per calendar month.  0 is unlimited")
+
+	fs.StringVar(&c.Metrics.Bind, "metricsBind", c.Metrics.Bind, "Serve /metrics on its own address without authentication.  If empty it is served by the api and needs a viewer or higher")
+	fs.BoolVar(&c.Metrics.StreamerNames, "metricsStreamerNames", c.Metrics.StreamerNames, "Label metrics with streamer names instead of ids")
+}
+
+// Load builds the configuration from the config file, environment and args.  The config file is
+// given with -config or PRISMPLUS_CONFIG
+func Load(name string, args []string) (*Config, error) {
+	// The first pass only finds the config file, everything else is parsed again on top of it
+	configPath := os.Getenv(EnvPrefix + "CONFIG")
+
+	first := flag.NewFlagSet(name, flag.ContinueOnError)
+	first.SetOutput(io.Discard)
+	first.StringVar(&configPath, "config", configPath, "")
+	Default().bindFlags(first)
+
+	if err := first.Parse(args); err != nil && !errors.Is(err, flag.ErrHelp) {
+		return nil, err
+	}
+
+	c := Default()
+
+	if configPath != "" {
+		if err := c.loadFile(configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.String("config", configPath, "Path to a YAML or .toml config file.  Also set with "+EnvPrefix+"CONFIG")
+	c.bindFlags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// loadFile reads a YAML config file, or TOML if it ends in .toml.  TOML keys are the same as the YAML ones
+func (c *Config) loadFile(path string) error {
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		return c.loadTOML(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(c); err != nil && err != io.EOF {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (c *Config) loadTOML(path string) error {
+	metadata, err := toml.DecodeFile(path, c)
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, 0, len(undecoded))
+		for _, key := range undecoded {
+			keys = append(keys, key.String())
+		}
+
+		return fmt.Errorf("config file %s: unknown keys %s", path, strings.Join(keys, ", "))
+	}
+
+	return nil
+}
+
+// Limits are the limits for streamers that don't override them
+func (l LimitsConfig) Limits() models.Limits {
+	return models.Limits{
+		MaxDestinations:   l.MaxDestinations,
+		MaxBitrateKbps:    l.MaxBitrateKbps,
+		BitrateAction:     l.BitrateAction,
+		MaxSessionSeconds: int64(time.Duration(l.MaxSessionDuration) / time.Second),
+		MonthlyHours:      l.MonthlyHours,
+	}
+}
+
+// StoreURL is the store to open.  Without one set it's the bolt database in the data path
+func (c *Config) StoreURL() string {
+	if c.Store != "" {
+		return c.Store
+	}
+
+	return "bolt://" + c.DataPath + "data.bbolt"
+}
+
+// Validate checks that the settings make sense and lists everything that doesn't
+func (c *Config) Validate() error {
+	problems := []string{}
+
+	check := func(ok bool, format string, a ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, a...))
+		}
+	}
+
+	checkAddr := func(setting string, addr string) {
+		_, _, err := net.SplitHostPort(addr)
+		check(err == nil, "%s: %q isn't a valid address, expected host:port or :port", setting, addr)
+	}
+
+	check(c.DataPath != "", "dataPath: can't be empty")
+	check(c.Store == "" || strings.Contains(c.Store, "://"), "store: %q must look like bolt://path, sqlite://path or memory://", c.Store)
+
+	if c.PublishAuthURL != "" {
+		u, err := url.Parse(c.PublishAuthURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "publishAuthURL: %q must be an http or https url", c.PublishAuthURL)
+	}
+
+	checkAddr("rtmp.bind", c.RTMP.Bind)
+	check(c.RTMP.ChunkSize >= 128 && c.RTMP.ChunkSize <= 65536, "rtmp.chunkSize: %d must be between 128 and 65536", c.RTMP.ChunkSize)
+	check(c.RTMP.FailoverTimeout > 0, "rtmp.failoverTimeout: must be greater than 0")
+
+	checkAddr("api.bind", c.API.Bind)
+	check(c.API.StaticRoot != "", "api.staticRoot: can't be empty")
+	for _, origin := range c.API.CORSAllowOrigins {
+		check(origin != "", "api.corsAllowOrigins: origins can't be empty")
+	}
+
+	c.validateTLS(check, checkAddr)
+
+	check(c.BruteForce.MaxFailures >= 0, "bruteForce.maxFailures: %d can't be negative", c.BruteForce.MaxFailures)
+	check(c.BruteForce.MaxFailures == 0 || c.BruteForce.Window > 0, "bruteForce.window: must be greater than 0")
+	check(c.BruteForce.MaxFailures == 0 || c.BruteForce.BanDuration > 0, "bruteForce.banDuration: must be greater than 0")
+	check(c.BruteForce.RatePerMinute >= 0, "bruteForce.ratePerMinute: %d can't be negative", c.BruteForce.RatePerMinute)
+	check(c.BruteForce.RatePerMinute == 0 || c.BruteForce.RateBurst > 0, "bruteForce.rateBurst: must be greater than 0")
+
+	check(c.Sessions.AdHocTTL >= 0, "sessions.adHocTTL: can't be negative")
+
+	check(c.Streamers.TokenTTL > 0, "streamers.tokenTTL: must be greater than 0")
+	check(c.Streamers.MagicLinkTTL > 0, "streamers.magicLinkTTL: must be greater than 0")
+
+	check(c.Limits.MaxDestinations >= 0, "limits.maxDestinations: %d can't be negative", c.Limits.MaxDestinations)
+	check(c.Limits.MaxBitrateKbps >= 0, "limits.maxBitrateKbps: %d can't be negative", c.Limits.MaxBitrateKbps)
+	check(c.Limits.BitrateAction == "warn" || c.Limits.BitrateAction == "disconnect", "limits.bitrateAction: %q must be warn or disconnect", c.Limits.BitrateAction)
+	check(c.Limits.BitrateGrace >= 0, "limits.bitrateGrace: can't be negative")
+	check(c.Limits.MaxSessionDuration >= 0, "limits.maxSessionDuration: can't be negative")
+	check(c.Limits.MonthlyHours >= 0, "limits.monthlyHours: %d can't be negative", c.Limits.MonthlyHours)
+
+	if c.Metrics.Bind != "" {
+		checkAddr("metrics.bind", c.Metrics.Bind)
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration:\n  " + strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+func (c *Config) validateTLS(check func(ok bool, format string, a ...interface{}), checkAddr func(setting string, addr string)) {
+	t := c.API.TLS
+
+	check((t.CertFile == "") == (t.KeyFile == ""), "api.tls: certFile and keyFile must be set together")
+	check(t.CertFile == "" || !t.ACME.Enabled(), "api.tls: use either certFile and keyFile or acme, not both")
+
+	if t.ACME.Enabled() {
+		u, err := url.Parse(t.ACME.DirectoryURL)
+		check(err == nil && u.Scheme == "https" && u.Host != "", "api.tls.acme.directoryURL: %q must be an https url", t.ACME.DirectoryURL)
+
+		for _, domain := range t.ACME.Domains {
+			check(domain != "" && !strings.ContainsAny(domain, ":/*"), "api.tls.acme.domains: %q isn't a domain name", domain)
+		}
+	}
+
+	if t.RedirectBind != "" {
+		checkAddr("api.tls.redirectBind", t.RedirectBind)
+		check(t.Enabled(), "api.tls.redirectBind: needs certFile and keyFile or acme")
+	}
+
+	check(t.HSTSMaxAge >= 0, "api.tls.hstsMaxAge: can't be negative")
+	check(t.HSTSMaxAge == 0 || t.Enabled(), "api.tls.hstsMaxAge: needs certFile and keyFile or acme")
+}
+
+// Print writes the configuration as YAML with secrets masked
+func (c *Config) Print(w io.Writer) error {
+	masked := *c
+	maskSecrets(&masked)
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(&masked); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
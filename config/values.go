@@ -0,0 +1,208 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+const masked = "********"
+
+// Duration is a time.Duration written as a string like 10m in config files, env vars and flags
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d *Duration) Set(value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	if err := d.Set(node.Value); err != nil {
+		return fmt.Errorf("line %d: %q isn't a duration like 30s or 10m", node.Line, node.Value)
+	}
+
+	return nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	if err := d.Set(string(text)); err != nil {
+		return fmt.Errorf("%q isn't a duration like 30s or 10m", text)
+	}
+
+	return nil
+}
+
+// stringList is a comma separated flag
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = splitList(value)
+
+	return nil
+}
+
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+// envName turns a yaml path like api.corsAllowOrigins into PRISMPLUS_API_CORS_ALLOW_ORIGINS
+func envName(path []string) string {
+	parts := []string{}
+	for _, name := range path {
+		var b strings.Builder
+
+		runes := []rune(name)
+		for i, r := range runes {
+			if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+				b.WriteRune('_')
+			}
+
+			b.WriteRune(unicode.ToUpper(r))
+		}
+
+		parts = append(parts, b.String())
+	}
+
+	return EnvPrefix + strings.Join(parts, "_")
+}
+
+// loadEnv sets every field that has a PRISMPLUS_* environment variable
+func (c *Config) loadEnv() error {
+	return walk(reflect.ValueOf(c).Elem(), nil, func(field reflect.Value, path []string, _ reflect.StructField) error {
+		name := envName(path)
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil
+		}
+
+		if err := setValue(field, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		return nil
+	})
+}
+
+// EnvNames lists the environment variables that can be set, in config order
+func EnvNames() []string {
+	names := []string{EnvPrefix + "CONFIG"}
+
+	walk(reflect.ValueOf(Default()).Elem(), nil, func(_ reflect.Value, path []string, _ reflect.StructField) error {
+		names = append(names, envName(path))
+		return nil
+	})
+
+	return names
+}
+
+func maskSecrets(c *Config) {
+	walk(reflect.ValueOf(c).Elem(), nil, func(field reflect.Value, _ []string, structField reflect.StructField) error {
+		if structField.Tag.Get("secret") == "true" && field.Kind() == reflect.String && field.String() != "" {
+			field.SetString(masked)
+		}
+
+		return nil
+	})
+}
+
+// walk calls fn for every setting with its yaml path
+func walk(v reflect.Value, path []string, fn func(field reflect.Value, path []string, structField reflect.StructField) error) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+
+		name := strings.Split(structField.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		fieldPath := append(path[:len(path):len(path)], name)
+		field := v.Field(i)
+
+		if field.Kind() == reflect.Struct {
+			if err := walk(field, fieldPath, fn); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := fn(field, fieldPath, structField); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setValue(field reflect.Value, value string) error {
+	if d, ok := field.Addr().Interface().(*Duration); ok {
+		if err := d.Set(value); err != nil {
+			return fmt.Errorf("%q isn't a duration like 30s or 10m", value)
+		}
+
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q isn't true or false", value)
+		}
+
+		field.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q isn't a whole number", value)
+		}
+
+		field.SetInt(int64(n))
+	case reflect.Slice:
+		field.Set(reflect.ValueOf(splitList(value)))
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+
+	return nil
+}
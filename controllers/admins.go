@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/geekgonecrazy/prismplus/admins"
+	"github.com/geekgonecrazy/prismplus/audit"
+	"github.com/geekgonecrazy/prismplus/auth"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+	"github.com/labstack/echo/v4"
+)
+
+func GetAdminsHandler(c echo.Context) error {
+	a, err := admins.GetAdmins()
+	if err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, a)
+}
+
+func CreateAdminHandler(c echo.Context) error {
+	adminPayload := models.AdminCreatePayload{}
+
+	if err := c.Bind(&adminPayload); err != nil {
+		return err
+	}
+
+	if adminPayload.Name == "" {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	if !models.ValidAdminRole(adminPayload.Role) {
+		return c.String(http.StatusBadRequest, "Invalid role")
+	}
+
+	admin, err := admins.CreateAdmin(adminPayload)
+	if err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "admin.create", adminTarget(admin.ID), 0, nil, admin.Admin)
+
+	return c.JSON(http.StatusCreated, admin)
+}
+
+func UpdateAdminHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("admin"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	adminPayload := models.AdminUpdatePayload{}
+
+	if err := c.Bind(&adminPayload); err != nil {
+		return err
+	}
+
+	if !models.ValidAdminRole(adminPayload.Role) {
+		return c.String(http.StatusBadRequest, "Invalid role")
+	}
+
+	admin, err := admins.GetAdmin(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	updated, err := admins.UpdateAdmin(admin, adminPayload)
+	if err != nil {
+		if errors.Is(err, admins.ErrLastAdmin) {
+			return c.String(http.StatusConflict, err.Error())
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "admin.update", adminTarget(id), 0, admin, updated)
+
+	return c.JSON(http.StatusOK, updated)
+}
+
+func DeleteAdminHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("admin"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	if err := admins.DeleteAdmin(id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		if errors.Is(err, admins.ErrLastAdmin) {
+			return c.String(http.StatusConflict, err.Error())
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "admin.delete", adminTarget(id), 0, nil, nil)
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func GetAdminAPIKeysHandler(c echo.Context) error {
+	keys, err := admins.GetAPIKeys()
+	if err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, keys)
+}
+
+// CreateAdminAPIKeyHandler creates a key for the calling admin.  The key is only returned this once
+func CreateAdminAPIKeyHandler(c echo.Context) error {
+	principal, ok := auth.GetPrincipal(c)
+	if !ok || principal.Admin == nil {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	keyPayload := models.AdminAPIKeyCreatePayload{}
+
+	if err := c.Bind(&keyPayload); err != nil {
+		return err
+	}
+
+	if keyPayload.Name == "" {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	key, err := admins.CreateAPIKey(*principal.Admin, keyPayload)
+	if err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "admin.key.create", "adminKey:"+strconv.Itoa(key.ID), 0, nil, key.AdminAPIKey)
+
+	return c.JSON(http.StatusCreated, key)
+}
+
+func DeleteAdminAPIKeyHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("key"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	if err := admins.DeleteAPIKey(id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		if errors.Is(err, admins.ErrLastAdmin) {
+			return c.String(http.StatusConflict, err.Error())
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "admin.key.delete", "adminKey:"+strconv.Itoa(id), 0, nil, nil)
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func adminTarget(id int) string {
+	return "admin:" + strconv.Itoa(id)
+}
@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/geekgonecrazy/prismplus/audit"
+	"github.com/geekgonecrazy/prismplus/auth"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+	"github.com/geekgonecrazy/prismplus/streamers"
+	"github.com/geekgonecrazy/prismplus/tokens"
+	"github.com/labstack/echo/v4"
+)
+
+func GetAPITokensHandler(c echo.Context) error {
+	t, err := tokens.GetTokens(0)
+	if err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, t)
+}
+
+func CreateAPITokenHandler(c echo.Context) error {
+	principal, ok := auth.GetPrincipal(c)
+	if !ok || principal.Admin == nil {
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	tokenPayload := models.APITokenCreatePayload{}
+
+	if err := c.Bind(&tokenPayload); err != nil {
+		return err
+	}
+
+	if tokenPayload.Name == "" || len(tokenPayload.Scopes) == 0 {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	// Tokens bound to a streamer only work on the streamer api so only streamer scopes make sense
+	allowedScopes := models.Scopes
+	if tokenPayload.StreamerID != 0 {
+		allowedScopes = models.StreamerScopes
+
+		if _, err := streamers.GetStreamer(tokenPayload.StreamerID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return c.String(http.StatusBadRequest, "Streamer not found")
+			}
+
+			return c.NoContent(http.StatusInternalServerError)
+		}
+	}
+
+	if !models.ValidScopes(tokenPayload.Scopes, allowedScopes) {
+		return c.String(http.StatusBadRequest, "Invalid scopes")
+	}
+
+	token, err := tokens.CreateToken(tokenPayload, principal.Admin.ID, 0)
+	if err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "token.create", tokenTarget(token.ID), token.StreamerID, nil, token.APIToken)
+
+	return c.JSON(http.StatusCreated, token)
+}
+
+func DeleteAPITokenHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("token"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	token, err := tokens.GetToken(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	if err := tokens.DeleteToken(id); err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "token.delete", tokenTarget(id), token.StreamerID, token, nil)
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func GetMyStreamerAPITokensHandler(c echo.Context) error {
+	myStreamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
+	}
+
+	t, err := tokens.GetTokens(myStreamer.ID)
+	if err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, t)
+}
+
+func CreateMyStreamerAPITokenHandler(c echo.Context) error {
+	myStreamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
+	}
+
+	tokenPayload := models.APITokenCreatePayload{}
+
+	if err := c.Bind(&tokenPayload); err != nil {
+		return err
+	}
+
+	if tokenPayload.Name == "" || len(tokenPayload.Scopes) == 0 {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	if !models.ValidScopes(tokenPayload.Scopes, models.StreamerScopes) {
+		return c.String(http.StatusBadRequest, "Invalid scopes")
+	}
+
+	// Streamers can only issue tokens for themselves
+	tokenPayload.StreamerID = myStreamer.ID
+
+	token, err := tokens.CreateToken(tokenPayload, 0, myStreamer.ID)
+	if err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "token.create", tokenTarget(token.ID), myStreamer.ID, nil, token.APIToken)
+
+	return c.JSON(http.StatusCreated, token)
+}
+
+func DeleteMyStreamerAPITokenHandler(c echo.Context) error {
+	myStreamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(c.Param("token"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	token, err := tokens.GetToken(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	if token.StreamerID != myStreamer.ID {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	if err := tokens.DeleteToken(id); err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "token.delete", tokenTarget(id), myStreamer.ID, token, nil)
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func tokenTarget(id int) string {
+	return "token:" + strconv.Itoa(id)
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/audit"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/labstack/echo/v4"
+)
+
+func GetAuditHandler(c echo.Context) error {
+	query := models.AuditQuery{}
+
+	if streamer := c.QueryParam("streamer"); streamer != "" {
+		id, err := strconv.Atoi(streamer)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "invalid streamer")
+		}
+
+		query.StreamerID = id
+	}
+
+	if since := c.QueryParam("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "since must be an RFC3339 time")
+		}
+
+		query.Since = t
+	}
+
+	if before := c.QueryParam("before"); before != "" {
+		id, err := strconv.Atoi(before)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "invalid before")
+		}
+
+		query.Before = id
+	}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "invalid limit")
+		}
+
+		query.Limit = l
+	}
+
+	page, err := audit.GetEntries(query)
+	if err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/audit"
+	"github.com/geekgonecrazy/prismplus/backup"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/labstack/echo/v4"
+)
+
+// passphraseHeader carries the export passphrase so it doesn't end up in request logs
+const passphraseHeader = "X-Prismplus-Passphrase"
+
+func GetExportHandler(c echo.Context) error {
+	secrets := c.QueryParam("secrets")
+
+	export, err := backup.Export(secrets, c.Request().Header.Get(passphraseHeader))
+	if err != nil {
+		if errors.Is(err, backup.ErrInvalid) || errors.Is(err, backup.ErrPassphrase) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "config.export", "streamers", 0, nil, map[string]string{"secrets": export.Secrets})
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, attachment("prismplus-export", "json"))
+
+	return c.JSON(http.StatusOK, export)
+}
+
+func ImportHandler(c echo.Context) error {
+	export := models.Export{}
+
+	if err := c.Bind(&export); err != nil {
+		return err
+	}
+
+	opts := models.ImportOptions{
+		Mode:       c.QueryParam("mode"),
+		Passphrase: c.Request().Header.Get(passphraseHeader),
+	}
+
+	if dryRun := c.QueryParam("dryRun"); dryRun != "" {
+		parsed, err := strconv.ParseBool(dryRun)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "Invalid dryRun")
+		}
+
+		opts.DryRun = parsed
+	}
+
+	result, err := backup.Import(export, opts)
+	if err != nil {
+		if errors.Is(err, backup.ErrInvalid) || errors.Is(err, backup.ErrPassphrase) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	changed := len(result.Created) + len(result.Updated) + len(result.Deleted)
+
+	if !result.DryRun && changed > 0 {
+		audit.Record(c, "config.import", "streamers", 0, nil, map[string]interface{}{
+			"mode":    result.Mode,
+			"created": len(result.Created),
+			"updated": len(result.Updated),
+			"deleted": len(result.Deleted),
+		})
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+func GetBackupHandler(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMEOctetStream)
+	c.Response().Header().Set(echo.HeaderContentDisposition, attachment("prismplus-backup", backup.Extension()))
+	c.Response().WriteHeader(http.StatusOK)
+
+	// Once the body has started the status can't change, a failed backup is a truncated download
+	if err := backup.Backup(c.Response()); err != nil {
+		log.Println("Error writing backup:", err)
+		return nil
+	}
+
+	audit.Record(c, "config.backup", "database", 0, nil, nil)
+
+	return nil
+}
+
+func attachment(name string, extension string) string {
+	return fmt.Sprintf("attachment; filename=%s-%s.%s", name, time.Now().UTC().Format("20060102T150405Z"), extension)
+}
@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geekgonecrazy/prismplus/audit"
+	"github.com/geekgonecrazy/prismplus/bruteforce"
+	"github.com/labstack/echo/v4"
+)
+
+func GetBansHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, bruteforce.GetBans())
+}
+
+func DeleteBansHandler(c echo.Context) error {
+	bruteforce.ClearBans()
+
+	audit.Record(c, "ban.clear_all", "bans", 0, nil, nil)
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func DeleteBanHandler(c echo.Context) error {
+	ip := c.Param("ip")
+
+	if err := bruteforce.ClearBan(ip); err != nil {
+		if errors.Is(err, bruteforce.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "ban.clear", "ip:"+ip, 0, nil, nil)
+
+	return c.NoContent(http.StatusAccepted)
+}
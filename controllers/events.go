@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/events"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/net/websocket"
+)
+
+// eventKeepAlive keeps proxies from closing idle event streams
+const eventKeepAlive = 15 * time.Second
+
+func GetEventsHandler(c echo.Context) error {
+	return eventStream(c, 0)
+}
+
+func GetEventsWebSocketHandler(c echo.Context) error {
+	return eventWebSocket(c, 0)
+}
+
+func GetMyStreamerEventsHandler(c echo.Context) error {
+	myStreamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
+	}
+
+	return eventStream(c, myStreamer.ID)
+}
+
+func GetMyStreamerEventsWebSocketHandler(c echo.Context) error {
+	myStreamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
+	}
+
+	return eventWebSocket(c, myStreamer.ID)
+}
+
+// subscribe subscribes to events for the streamer (0 for all) limited to the comma separated ?types=
+func subscribe(c echo.Context, streamerID int) *events.Subscription {
+	types := []string{}
+	for _, eventType := range strings.Split(c.QueryParam("types"), ",") {
+		if eventType = strings.TrimSpace(eventType); eventType != "" {
+			types = append(types, eventType)
+		}
+	}
+
+	return events.Subscribe(streamerID, types...)
+}
+
+// eventStream sends events as server-sent events until the client goes away
+func eventStream(c echo.Context, streamerID int) error {
+	subscription := subscribe(c, streamerID)
+	defer subscription.Unsubscribe()
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "text/event-stream")
+	response.Header().Set("Cache-Control", "no-cache")
+	response.Header().Set("Connection", "keep-alive")
+	response.WriteHeader(http.StatusOK)
+	response.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(response, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		case event := <-subscription.Events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+
+			if _, err := fmt.Fprintf(response, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return nil
+			}
+		}
+
+		response.Flush()
+	}
+}
+
+// eventWebSocket sends each event as a json text message until the client goes away
+func eventWebSocket(c echo.Context, streamerID int) error {
+	subscription := subscribe(c, streamerID)
+	defer subscription.Unsubscribe()
+
+	// Callers authenticate with the Authorization header rather than cookies, so the origin isn't checked
+	server := websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+
+			// Nothing is expected from the client, reading just tells us when it goes away
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+
+				var discard string
+				for {
+					if err := websocket.Message.Receive(ws, &discard); err != nil {
+						return
+					}
+				}
+			}()
+
+			for {
+				select {
+				case <-closed:
+					return
+				case event := <-subscription.Events:
+					if err := websocket.JSON.Send(ws, event); err != nil {
+						return
+					}
+				}
+			}
+		},
+	}
+
+	server.ServeHTTP(c.Response(), c.Request())
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/sessions"
+	"github.com/labstack/echo/v4"
+)
+
+func GetSessionHistoryHandler(c echo.Context) error {
+	query, err := historyQuery(c)
+	if err != nil {
+		return err
+	}
+
+	if streamer := c.QueryParam("streamer"); streamer != "" {
+		id, err := strconv.Atoi(streamer)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "invalid streamer")
+		}
+
+		query.StreamerID = id
+	}
+
+	return sessionHistory(c, query)
+}
+
+func GetMyStreamerHistoryHandler(c echo.Context) error {
+	myStreamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
+	}
+
+	query, err := historyQuery(c)
+	if err != nil {
+		return err
+	}
+
+	query.StreamerID = myStreamer.ID
+
+	return sessionHistory(c, query)
+}
+
+func historyQuery(c echo.Context) (models.SessionHistoryQuery, error) {
+	query := models.SessionHistoryQuery{}
+
+	if since := c.QueryParam("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return query, echo.NewHTTPError(http.StatusBadRequest, "since must be an RFC3339 time")
+		}
+
+		query.Since = t
+	}
+
+	if before := c.QueryParam("before"); before != "" {
+		id, err := strconv.Atoi(before)
+		if err != nil {
+			return query, echo.NewHTTPError(http.StatusBadRequest, "invalid before")
+		}
+
+		query.Before = id
+	}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return query, echo.NewHTTPError(http.StatusBadRequest, "invalid limit")
+		}
+
+		query.Limit = l
+	}
+
+	return query, nil
+}
+
+// sessionHistory responds with the page as json, or as csv with ?format=csv
+func sessionHistory(c echo.Context, query models.SessionHistoryQuery) error {
+	page, err := sessions.GetHistory(query)
+	if err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	if c.QueryParam("format") != "csv" {
+		return jsonRedacted(c, http.StatusOK, page)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv")
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="session-history.csv"`)
+	c.Response().WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(c.Response())
+
+	// One row per destination so each destination's uptime can be compared, sessions without any get one row
+	w.Write([]string{
+		"session_id", "streamer_id", "started_at", "ended_at", "duration_seconds",
+		"ingest_bytes", "ingest_packets", "average_bitrate", "publisher_connects", "failovers",
+		"destination_id", "destination_name", "destination_server", "uptime_seconds", "reconnects", "bytes_sent",
+	})
+
+	for _, history := range page.Sessions {
+		session := []string{
+			strconv.Itoa(history.ID),
+			strconv.Itoa(history.StreamerID),
+			history.StartedAt.Format(time.RFC3339),
+			history.EndedAt.Format(time.RFC3339),
+			strconv.FormatInt(history.DurationSeconds, 10),
+			strconv.FormatInt(history.Ingest.Bytes, 10),
+			strconv.FormatInt(history.Ingest.Packets, 10),
+			strconv.FormatInt(history.Ingest.AverageBitrate, 10),
+			strconv.Itoa(history.Ingest.PublisherConnects),
+			strconv.Itoa(history.Ingest.Failovers),
+		}
+
+		if len(history.Destinations) == 0 {
+			w.Write(append(session, "", "", "", "", "", ""))
+			continue
+		}
+
+		for _, destination := range history.Destinations {
+			w.Write(append(session[:len(session):len(session)],
+				strconv.Itoa(destination.ID),
+				destination.Name,
+				destination.Server,
+				strconv.FormatInt(destination.UptimeSeconds, 10),
+				strconv.Itoa(destination.Reconnects),
+				strconv.FormatInt(destination.BytesSent, 10),
+			))
+		}
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
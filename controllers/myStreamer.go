@@ -5,51 +5,139 @@ import (
 	"log"
 	"net/http"
 	"strconv"
-	"strings"
+	"time"
 
+	"github.com/geekgonecrazy/prismplus/audit"
+	"github.com/geekgonecrazy/prismplus/auth"
+	"github.com/geekgonecrazy/prismplus/bruteforce"
 	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/quotas"
 	"github.com/geekgonecrazy/prismplus/sessions"
-	"github.com/geekgonecrazy/prismplus/store"
 	"github.com/geekgonecrazy/prismplus/streamers"
 	"github.com/labstack/echo/v4"
 )
 
-func getStreamKeyFromHeader(c echo.Context) (string, bool) {
-	authorizationHeader := c.Request().Header.Get("Authorization")
+// getMyStreamer returns the streamer the caller logged in as.  auth.Require has already made sure the
+// caller is a streamer
+func getMyStreamer(c echo.Context) (models.Streamer, error) {
+	principal, ok := auth.GetPrincipal(c)
+	if !ok || principal.Streamer == nil {
+		return models.Streamer{}, echo.NewHTTPError(http.StatusUnauthorized)
+	}
+
+	return *principal.Streamer, nil
+}
 
-	split := strings.Split(authorizationHeader, " ")
+func StreamerLoginHandler(c echo.Context) error {
+	loginPayload := models.StreamerLoginPayload{}
 
-	if len(split) < 2 {
-		return "", false
+	if err := c.Bind(&loginPayload); err != nil {
+		return err
 	}
 
-	if len(split[1]) == 0 {
-		return "", false
+	login, err := streamers.Login(loginPayload.Name, loginPayload.Password)
+	if err != nil {
+		if errors.Is(err, streamers.ErrInvalidCredentials) {
+			bruteforce.RecordFailure(c.RealIP(), "streamer login")
+			return c.NoContent(http.StatusUnauthorized)
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
 	}
 
-	return split[1], true
+	return c.JSON(http.StatusOK, login)
 }
 
-func GetMyStreamerHandler(c echo.Context) error {
-	streamKey, ok := getStreamKeyFromHeader(c)
+func StreamerMagicLinkLoginHandler(c echo.Context) error {
+	loginPayload := models.StreamerMagicLinkLoginPayload{}
+
+	if err := c.Bind(&loginPayload); err != nil {
+		return err
+	}
+
+	login, err := streamers.LoginWithMagicLink(loginPayload.Token)
+	if err != nil {
+		if errors.Is(err, streamers.ErrInvalidCredentials) {
+			bruteforce.RecordFailure(c.RealIP(), "streamer magic link login")
+			return c.NoContent(http.StatusUnauthorized)
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, login)
+}
+
+func StreamerLogoutHandler(c echo.Context) error {
+	token, ok := auth.GetBearerToken(c)
 	if !ok {
 		return c.NoContent(http.StatusUnauthorized)
 	}
 
-	streamer, err := streamers.GetStreamerByStreamKey(streamKey)
+	if err := streamers.Logout(token); err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	if principal, ok := auth.GetPrincipal(c); ok && principal.Streamer != nil {
+		audit.Record(c, "streamer.logout", streamerTarget(principal.Streamer.ID), principal.Streamer.ID, nil, nil)
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func SetMyStreamerPasswordHandler(c echo.Context) error {
+	myStreamer, err := getMyStreamer(c)
 	if err != nil {
-		if errors.Is(err, store.ErrNotFound) {
-			return c.NoContent(http.StatusNotFound)
+		return err
+	}
+
+	passwordPayload := models.StreamerPasswordPayload{}
+
+	if err := c.Bind(&passwordPayload); err != nil {
+		return err
+	}
+
+	if err := streamers.ChangePassword(myStreamer, passwordPayload.CurrentPassword, passwordPayload.Password); err != nil {
+		if errors.Is(err, streamers.ErrPasswordTooShort) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		if errors.Is(err, streamers.ErrWrongPassword) {
+			bruteforce.RecordFailure(c.RealIP(), "streamer password change")
+			return c.String(http.StatusForbidden, err.Error())
 		}
 
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "streamer.password.set", streamerTarget(myStreamer.ID), myStreamer.ID, nil, nil)
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func GetMyStreamerHandler(c echo.Context) error {
+	streamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
+	}
+
+	usage, err := quotas.GetUsage(streamer.ID, time.Now())
+	if err != nil {
+		log.Println("Error:", err)
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
 	myStreamer := models.MyStreamer{
 		Streamer: streamer,
+		Limits:   quotas.Effective(streamer),
+		Usage:    usage,
 	}
 
-	session, _ := sessions.GetSession(streamKey)
+	session, _ := sessions.GetSession(streamer.StreamKey)
 
 	if session != nil && session.Active {
 		myStreamer.Live = true
@@ -59,18 +147,9 @@ func GetMyStreamerHandler(c echo.Context) error {
 }
 
 func CreateMyStreamerDestinationHandler(c echo.Context) error {
-	streamKey, ok := getStreamKeyFromHeader(c)
-	if !ok {
-		return c.NoContent(http.StatusUnauthorized)
-	}
-
-	myStreamer, err := streamers.GetStreamerByStreamKey(streamKey)
+	myStreamer, err := getMyStreamer(c)
 	if err != nil {
-		if errors.Is(err, store.ErrNotFound) {
-			return c.NoContent(http.StatusNotFound)
-		}
-
-		return c.NoContent(http.StatusInternalServerError)
+		return err
 	}
 
 	destinationPayload := models.Destination{}
@@ -80,26 +159,23 @@ func CreateMyStreamerDestinationHandler(c echo.Context) error {
 	}
 
 	if err := streamers.AddDestination(myStreamer, destinationPayload); err != nil {
+		if errors.Is(err, quotas.ErrLimitReached) {
+			return c.String(http.StatusForbidden, err.Error())
+		}
+
 		log.Println(err)
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
+	recordDestinationsChange(c, "streamer.destination.add", myStreamer)
+
 	return c.NoContent(http.StatusCreated)
 }
 
 func RemoveMyStreamerDestinationHandler(c echo.Context) error {
-	streamKey, ok := getStreamKeyFromHeader(c)
-	if !ok {
-		return c.NoContent(http.StatusUnauthorized)
-	}
-
-	myStreamer, err := streamers.GetStreamerByStreamKey(streamKey)
+	myStreamer, err := getMyStreamer(c)
 	if err != nil {
-		if errors.Is(err, store.ErrNotFound) {
-			return c.NoContent(http.StatusNotFound)
-		}
-
-		return c.NoContent(http.StatusInternalServerError)
+		return err
 	}
 
 	destination := c.Param("destination")
@@ -116,23 +192,37 @@ func RemoveMyStreamerDestinationHandler(c echo.Context) error {
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
+	recordDestinationsChange(c, "streamer.destination.remove", myStreamer)
+
 	return c.NoContent(http.StatusAccepted)
 }
 
 func GetMyStreamerDestinationsHandler(c echo.Context) error {
-	streamKey, ok := getStreamKeyFromHeader(c)
-	if !ok {
-		return c.NoContent(http.StatusUnauthorized)
+	myStreamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
 	}
 
-	myStreamer, err := streamers.GetStreamerByStreamKey(streamKey)
+	return c.JSON(http.StatusOK, myStreamer.Destinations)
+}
+
+// recordDestinationsChange audits the streamer's destinations before and after a change.  Destinations
+// are keyed by id so removing one doesn't show up as every later destination changing
+func recordDestinationsChange(c echo.Context, action string, before models.Streamer) {
+	after, err := streamers.GetStreamer(before.ID)
 	if err != nil {
-		if errors.Is(err, store.ErrNotFound) {
-			return c.NoContent(http.StatusNotFound)
-		}
+		log.Println("Error:", err)
+		return
+	}
 
-		return c.NoContent(http.StatusInternalServerError)
+	audit.Record(c, action, streamerTarget(before.ID), before.ID, destinationsByID(before.Destinations), destinationsByID(after.Destinations))
+}
+
+func destinationsByID(destinations []models.Destination) map[string]models.Destination {
+	byID := map[string]models.Destination{}
+	for _, destination := range destinations {
+		byID[strconv.Itoa(destination.ID)] = destination
 	}
 
-	return c.JSON(http.StatusOK, myStreamer.Destinations)
+	return byID
 }
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/geekgonecrazy/prismplus/audit"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+	"github.com/geekgonecrazy/prismplus/streamers"
+	"github.com/labstack/echo/v4"
+)
+
+func GetMyStreamerProfilesHandler(c echo.Context) error {
+	myStreamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
+	}
+
+	profiles := myStreamer.Profiles
+	if profiles == nil {
+		profiles = []models.Profile{}
+	}
+
+	return c.JSON(http.StatusOK, profiles)
+}
+
+func SetMyStreamerProfileHandler(c echo.Context) error {
+	myStreamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
+	}
+
+	profilePayload := models.ProfilePayload{}
+
+	if err := c.Bind(&profilePayload); err != nil {
+		return err
+	}
+
+	name := c.Param("profile")
+	before, _ := myStreamer.GetProfile(name)
+
+	profile, err := streamers.SetProfile(myStreamer, name, profilePayload)
+	if err != nil {
+		if errors.Is(err, streamers.ErrInvalidProfile) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "streamer.profile.set", streamerTarget(myStreamer.ID), myStreamer.ID, before, profile)
+
+	return c.JSON(http.StatusOK, profile)
+}
+
+func DeleteMyStreamerProfileHandler(c echo.Context) error {
+	myStreamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
+	}
+
+	name := c.Param("profile")
+	before, _ := myStreamer.GetProfile(name)
+
+	if err := streamers.DeleteProfile(myStreamer, name); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		if errors.Is(err, streamers.ErrInvalidProfile) {
+			return c.String(http.StatusConflict, err.Error())
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "streamer.profile.delete", streamerTarget(myStreamer.ID), myStreamer.ID, before, nil)
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func SetMyStreamerActiveProfileHandler(c echo.Context) error {
+	myStreamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
+	}
+
+	return setActiveProfile(c, myStreamer)
+}
+
+func SetStreamerActiveProfileHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("streamer"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	streamer, err := streamers.GetStreamer(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return setActiveProfile(c, streamer)
+}
+
+// setActiveProfile switches the streamer to the profile in the request, "" for all destinations
+func setActiveProfile(c echo.Context, streamer models.Streamer) error {
+	activeProfilePayload := models.ActiveProfilePayload{}
+
+	if err := c.Bind(&activeProfilePayload); err != nil {
+		return err
+	}
+
+	updated, err := streamers.SetActiveProfile(streamer, activeProfilePayload.Profile)
+	if err != nil {
+		if errors.Is(err, streamers.ErrUnknownProfile) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "streamer.profile.activate", streamerTarget(streamer.ID), streamer.ID, streamer.ActiveProfile, updated.ActiveProfile)
+
+	return c.JSON(http.StatusOK, updated)
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"encoding/json"
+
+	"github.com/geekgonecrazy/prismplus/auth"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/labstack/echo/v4"
+)
+
+const redacted = "[redacted]"
+
+// secretFields hold keys that can publish to a session or reach a streamer's destinations
+var secretFields = map[string]bool{
+	"key":       true,
+	"streamKey": true,
+}
+
+// canSeeSecrets reports whether the caller can see stream and destination keys.  Only admins can, viewers,
+// operators and api tokens see them redacted
+func canSeeSecrets(c echo.Context) bool {
+	principal, ok := auth.GetPrincipal(c)
+
+	return ok && principal.HasRole(models.RoleAdmin)
+}
+
+// jsonRedacted responds with v as json, with its keys redacted for callers who can't see them
+func jsonRedacted(c echo.Context, status int, v interface{}) error {
+	if canSeeSecrets(c) {
+		return c.JSON(status, v)
+	}
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(buf, &generic); err != nil {
+		return err
+	}
+
+	return c.JSON(status, redactSecrets(generic))
+}
+
+func redactSecrets(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for name, child := range value {
+			if s, ok := child.(string); ok && s != "" && secretFields[name] {
+				value[name] = redacted
+				continue
+			}
+
+			value[name] = redactSecrets(child)
+		}
+	case []interface{}:
+		for i, child := range value {
+			value[i] = redactSecrets(child)
+		}
+	}
+
+	return v
+}
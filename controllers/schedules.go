@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/geekgonecrazy/prismplus/audit"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/schedules"
+	"github.com/geekgonecrazy/prismplus/store"
+	"github.com/labstack/echo/v4"
+)
+
+func GetMyStreamerSchedulesHandler(c echo.Context) error {
+	myStreamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, schedules.GetSchedules(myStreamer.ID))
+}
+
+func CreateMyStreamerScheduleHandler(c echo.Context) error {
+	myStreamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
+	}
+
+	schedulePayload := models.SchedulePayload{}
+
+	if err := c.Bind(&schedulePayload); err != nil {
+		return err
+	}
+
+	schedule, err := schedules.CreateSchedule(myStreamer, schedulePayload)
+	if err != nil {
+		if errors.Is(err, schedules.ErrInvalid) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "streamer.schedule.create", scheduleTarget(schedule.ID), myStreamer.ID, nil, schedule.Schedule)
+
+	return c.JSON(http.StatusCreated, schedule)
+}
+
+func UpdateMyStreamerScheduleHandler(c echo.Context) error {
+	myStreamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(c.Param("schedule"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	before, err := schedules.GetSchedule(myStreamer.ID, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	schedulePayload := models.SchedulePayload{}
+
+	if err := c.Bind(&schedulePayload); err != nil {
+		return err
+	}
+
+	schedule, err := schedules.UpdateSchedule(myStreamer, id, schedulePayload)
+	if err != nil {
+		if errors.Is(err, schedules.ErrInvalid) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "streamer.schedule.update", scheduleTarget(id), myStreamer.ID, before, schedule.Schedule)
+
+	return c.JSON(http.StatusOK, schedule)
+}
+
+func DeleteMyStreamerScheduleHandler(c echo.Context) error {
+	myStreamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(c.Param("schedule"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	before, err := schedules.GetSchedule(myStreamer.ID, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	if err := schedules.DeleteSchedule(myStreamer, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "streamer.schedule.delete", scheduleTarget(id), myStreamer.ID, before, nil)
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func scheduleTarget(id int) string {
+	return "schedule:" + strconv.Itoa(id)
+}
@@ -6,13 +6,20 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/geekgonecrazy/prismplus/audit"
 	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/quotas"
 	"github.com/geekgonecrazy/prismplus/sessions"
 	"github.com/labstack/echo/v4"
 )
 
 func GetSessionsHandler(c echo.Context) error {
-	return c.JSON(http.StatusOK, sessions.GetSessions())
+	snapshots := []*sessions.Session{}
+	for _, session := range sessions.GetSessions() {
+		snapshots = append(snapshots, session.Snapshot())
+	}
+
+	return jsonRedacted(c, http.StatusOK, snapshots)
 }
 
 func CreateSessionHandler(c echo.Context) error {
@@ -22,7 +29,19 @@ func CreateSessionHandler(c echo.Context) error {
 		return err
 	}
 
-	if err := sessions.CreateSession(sessionPayload); err != nil {
+	if !models.ValidDuplicatePublisherPolicy(sessionPayload.DuplicatePublisherPolicy) {
+		return c.String(http.StatusBadRequest, "Invalid duplicatePublisherPolicy")
+	}
+
+	if err := quotas.CheckSessionDestinations(sessionPayload.StreamerID, len(sessionPayload.Destinations)); err != nil {
+		if errors.Is(err, quotas.ErrLimitReached) {
+			return c.String(http.StatusForbidden, err.Error())
+		}
+
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	if err := sessions.CreateAdHocSession(sessionPayload); err != nil {
 		if err.Error() == "Already Exists" {
 			return c.NoContent(http.StatusConflict)
 		}
@@ -30,13 +49,20 @@ func CreateSessionHandler(c echo.Context) error {
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
+	target := "session:"
+	if session, err := sessions.GetSession(sessionPayload.Key); err == nil {
+		target += session.ID
+	}
+
+	audit.Record(c, "session.create", target, sessionPayload.StreamerID, nil, sessionPayload)
+
 	return c.JSON(http.StatusCreated, sessionPayload)
 }
 
 func GetSessionHandler(c echo.Context) error {
 	key := c.Param("session")
 
-	session, err := sessions.GetSession(key)
+	session, err := sessions.FindSession(key)
 	if err != nil {
 		if errors.Is(err, sessions.ErrNotFound) {
 			return c.NoContent(http.StatusNotFound)
@@ -45,13 +71,13 @@ func GetSessionHandler(c echo.Context) error {
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
-	return c.JSON(http.StatusOK, session)
+	return jsonRedacted(c, http.StatusOK, session.Snapshot())
 }
 
 func GetDestinationsHandler(c echo.Context) error {
 	key := c.Param("session")
 
-	session, err := sessions.GetSession(key)
+	session, err := sessions.FindSession(key)
 	if err != nil {
 		if errors.Is(err, sessions.ErrNotFound) {
 			return c.NoContent(http.StatusNotFound)
@@ -62,13 +88,13 @@ func GetDestinationsHandler(c echo.Context) error {
 
 	destinations := session.GetDestinations()
 
-	return c.JSON(http.StatusOK, destinations)
+	return jsonRedacted(c, http.StatusOK, destinations)
 }
 
 func AddDestinationHandler(c echo.Context) error {
 	key := c.Param("session")
 
-	session, err := sessions.GetSession(key)
+	session, err := sessions.FindSession(key)
 	if err != nil {
 		if errors.Is(err, sessions.ErrNotFound) {
 			return c.NoContent(http.StatusNotFound)
@@ -83,11 +109,22 @@ func AddDestinationHandler(c echo.Context) error {
 		return err
 	}
 
+	if err := quotas.CheckSessionDestinations(session.StreamerID, len(session.GetDestinations())+1); err != nil {
+		if errors.Is(err, quotas.ErrLimitReached) {
+			return c.String(http.StatusForbidden, err.Error())
+		}
+
+		log.Println(err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
 	if err := session.AddDestination(destinationPayload); err != nil {
 		log.Println(err)
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
+	audit.Record(c, "session.destination.add", "session:"+session.ID, session.StreamerID, nil, destinationPayload)
+
 	return c.NoContent(http.StatusCreated)
 }
 
@@ -100,7 +137,7 @@ func RemoveDestinationHandler(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "Not Found")
 	}
 
-	session, err := sessions.GetSession(key)
+	session, err := sessions.FindSession(key)
 	if err != nil {
 		if errors.Is(err, sessions.ErrNotFound) {
 			return c.NoContent(http.StatusNotFound)
@@ -109,6 +146,11 @@ func RemoveDestinationHandler(c echo.Context) error {
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
+	before, err := session.GetDestination(id)
+	if err != nil {
+		return c.NoContent(http.StatusNotFound)
+	}
+
 	if err := session.RemoveDestination(id); err != nil {
 		if errors.Is(err, sessions.ErrNotFound) {
 			return c.NoContent(http.StatusNotFound)
@@ -117,13 +159,15 @@ func RemoveDestinationHandler(c echo.Context) error {
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
+	audit.Record(c, "session.destination.remove", "session:"+session.ID, session.StreamerID, auditDestination(before), nil)
+
 	return c.NoContent(http.StatusAccepted)
 }
 
 func DeleteSessionHandler(c echo.Context) error {
 	key := c.Param("session")
 
-	session, err := sessions.GetSession(key)
+	session, err := sessions.FindSession(key)
 	if err != nil {
 		if errors.Is(err, sessions.ErrNotFound) {
 			return c.NoContent(http.StatusNotFound)
@@ -132,7 +176,25 @@ func DeleteSessionHandler(c echo.Context) error {
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
-	session.EndSession()
+	// A live session is cleaned up by its publish loop, one nobody is publishing to is removed straight away
+	if session.Snapshot().Active {
+		session.EndSession()
+	} else if err := sessions.DeleteSession(session.Key); err != nil && !errors.Is(err, sessions.ErrNotFound) {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "session.end", "session:"+session.ID, session.StreamerID, nil, nil)
 
 	return c.NoContent(http.StatusAccepted)
 }
+
+// auditDestination strips the live connection from a session destination so it can be diffed
+func auditDestination(destination *sessions.Destination) models.Destination {
+	return models.Destination{
+		ID:     destination.ID,
+		Name:   destination.Name,
+		Server: destination.Server,
+		Key:    destination.Key,
+	}
+}
@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/geekgonecrazy/prismplus/status"
+	"github.com/labstack/echo/v4"
+)
+
+// HealthzHandler only shows the process is up and serving requests
+func HealthzHandler(c echo.Context) error {
+	return c.String(http.StatusOK, "ok")
+}
+
+func ReadyzHandler(c echo.Context) error {
+	readiness := status.Ready()
+
+	if !readiness.Ready {
+		return c.JSON(http.StatusServiceUnavailable, readiness)
+	}
+
+	return c.JSON(http.StatusOK, readiness)
+}
+
+func GetStatusHandler(c echo.Context) error {
+	s, err := status.GetStatus()
+	if err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, s)
+}
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/geekgonecrazy/prismplus/audit"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+	"github.com/geekgonecrazy/prismplus/streamers"
+	"github.com/labstack/echo/v4"
+)
+
+func GetMyStreamerStreamKeysHandler(c echo.Context) error {
+	myStreamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
+	}
+
+	return getStreamKeys(c, myStreamer)
+}
+
+func CreateMyStreamerStreamKeyHandler(c echo.Context) error {
+	myStreamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
+	}
+
+	return createStreamKey(c, myStreamer)
+}
+
+func RevokeMyStreamerStreamKeyHandler(c echo.Context) error {
+	myStreamer, err := getMyStreamer(c)
+	if err != nil {
+		return err
+	}
+
+	return revokeStreamKey(c, myStreamer)
+}
+
+func GetStreamerStreamKeysHandler(c echo.Context) error {
+	streamer, status := streamerFromParam(c)
+	if status != 0 {
+		return c.NoContent(status)
+	}
+
+	return getStreamKeys(c, streamer)
+}
+
+func CreateStreamerStreamKeyHandler(c echo.Context) error {
+	streamer, status := streamerFromParam(c)
+	if status != 0 {
+		return c.NoContent(status)
+	}
+
+	return createStreamKey(c, streamer)
+}
+
+func RevokeStreamerStreamKeyHandler(c echo.Context) error {
+	streamer, status := streamerFromParam(c)
+	if status != 0 {
+		return c.NoContent(status)
+	}
+
+	return revokeStreamKey(c, streamer)
+}
+
+// streamerFromParam loads the streamer in the :streamer path parameter.  status is the response to send
+// if it can't be loaded
+func streamerFromParam(c echo.Context) (streamer models.Streamer, status int) {
+	id, err := strconv.Atoi(c.Param("streamer"))
+	if err != nil {
+		return streamer, http.StatusBadRequest
+	}
+
+	streamer, err = streamers.GetStreamer(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return streamer, http.StatusNotFound
+		}
+
+		return streamer, http.StatusInternalServerError
+	}
+
+	return streamer, 0
+}
+
+func getStreamKeys(c echo.Context, streamer models.Streamer) error {
+	streamKeys, err := streamers.GetStreamKeys(streamer)
+	if err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, streamKeys)
+}
+
+func createStreamKey(c echo.Context, streamer models.Streamer) error {
+	streamKeyPayload := models.StreamKeyPayload{}
+
+	if err := c.Bind(&streamKeyPayload); err != nil {
+		return err
+	}
+
+	streamKey, err := streamers.CreateStreamKey(streamer, streamKeyPayload)
+	if err != nil {
+		if errors.Is(err, streamers.ErrInvalidStreamKey) || errors.Is(err, streamers.ErrUnknownProfile) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		if errors.Is(err, streamers.ErrStreamKeyTaken) {
+			return c.String(http.StatusConflict, err.Error())
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "streamer.streamkey.create", streamerTarget(streamer.ID), streamer.ID, nil, streamKey)
+
+	return c.JSON(http.StatusCreated, streamKey)
+}
+
+func revokeStreamKey(c echo.Context, streamer models.Streamer) error {
+	id, err := strconv.Atoi(c.Param("key"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	streamKey, err := streamers.RevokeStreamKey(streamer, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "streamer.streamkey.revoke", streamerTarget(streamer.ID), streamer.ID, streamKey, nil)
+
+	return c.NoContent(http.StatusAccepted)
+}
@@ -6,7 +6,9 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/geekgonecrazy/prismplus/audit"
 	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/quotas"
 	"github.com/geekgonecrazy/prismplus/store"
 	"github.com/geekgonecrazy/prismplus/streamers"
 	"github.com/labstack/echo/v4"
@@ -19,7 +21,7 @@ func GetStreamersHandler(c echo.Context) error {
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
-	return c.JSON(http.StatusOK, s)
+	return jsonRedacted(c, http.StatusOK, s)
 }
 
 func CreateStreamerHandler(c echo.Context) error {
@@ -33,8 +35,20 @@ func CreateStreamerHandler(c echo.Context) error {
 		return c.NoContent(http.StatusBadRequest)
 	}
 
+	if !models.ValidDuplicatePublisherPolicy(streamerPayload.DuplicatePublisherPolicy) {
+		return c.String(http.StatusBadRequest, "Invalid duplicatePublisherPolicy")
+	}
+
 	streamer, err := streamers.CreateStreamer(streamerPayload)
 	if err != nil {
+		if errors.Is(err, streamers.ErrNameTaken) || errors.Is(err, streamers.ErrStreamKeyTaken) {
+			return c.String(http.StatusConflict, err.Error())
+		}
+
+		if errors.Is(err, streamers.ErrInvalidStreamKey) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
 		if err.Error() == "Already Exists" {
 			return c.NoContent(http.StatusConflict)
 		}
@@ -44,6 +58,8 @@ func CreateStreamerHandler(c echo.Context) error {
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
+	audit.Record(c, "streamer.create", streamerTarget(streamer.ID), streamer.ID, nil, streamer)
+
 	return c.JSON(http.StatusCreated, streamer)
 }
 
@@ -64,7 +80,273 @@ func GetStreamerHandler(c echo.Context) error {
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
-	return c.JSON(http.StatusOK, streamer)
+	return jsonRedacted(c, http.StatusOK, streamer)
+}
+
+func UpdateStreamerHandler(c echo.Context) error {
+	key := c.Param("streamer")
+
+	id, err := strconv.Atoi(key)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	streamerPayload := models.StreamerUpdatePayload{}
+
+	if err := c.Bind(&streamerPayload); err != nil {
+		return err
+	}
+
+	if !models.ValidDuplicatePublisherPolicy(streamerPayload.DuplicatePublisherPolicy) {
+		return c.String(http.StatusBadRequest, "Invalid duplicatePublisherPolicy")
+	}
+
+	if streamerPayload.Limits != nil && !models.ValidLimits(*streamerPayload.Limits) {
+		return c.String(http.StatusBadRequest, "Invalid limits")
+	}
+
+	streamer, err := streamers.GetStreamer(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	updated, err := streamers.UpdateStreamer(streamer, streamerPayload)
+	if err != nil {
+		if errors.Is(err, streamers.ErrNameTaken) {
+			return c.String(http.StatusConflict, err.Error())
+		}
+
+		if errors.Is(err, streamers.ErrInvalidStreamKey) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "streamer.update", streamerTarget(id), id, streamer, updated)
+
+	return c.JSON(http.StatusOK, updated)
+}
+
+func SetStreamerPasswordHandler(c echo.Context) error {
+	key := c.Param("streamer")
+
+	id, err := strconv.Atoi(key)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	passwordPayload := models.StreamerPasswordPayload{}
+
+	if err := c.Bind(&passwordPayload); err != nil {
+		return err
+	}
+
+	streamer, err := streamers.GetStreamer(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	if err := streamers.SetPassword(streamer, passwordPayload.Password); err != nil {
+		if errors.Is(err, streamers.ErrPasswordTooShort) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "streamer.password.set", streamerTarget(id), id, nil, nil)
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func CreateStreamerMagicLinkHandler(c echo.Context) error {
+	key := c.Param("streamer")
+
+	id, err := strconv.Atoi(key)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	streamer, err := streamers.GetStreamer(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	magicLink, err := streamers.CreateMagicLink(streamer)
+	if err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "streamer.magiclink.create", streamerTarget(id), id, nil, nil)
+
+	return c.JSON(http.StatusCreated, magicLink)
+}
+
+func RotateStreamerKeyHandler(c echo.Context) error {
+	key := c.Param("streamer")
+
+	id, err := strconv.Atoi(key)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	streamer, err := streamers.GetStreamer(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	rotated, err := streamers.RotateStreamKey(streamer)
+	if err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "streamer.key.rotate", streamerTarget(id), id, streamer, rotated)
+
+	return c.JSON(http.StatusOK, rotated)
+}
+
+func DisableStreamerHandler(c echo.Context) error {
+	streamer, status := streamerFromParam(c)
+	if status != 0 {
+		return c.NoContent(status)
+	}
+
+	disablePayload := models.StreamerDisablePayload{}
+
+	if err := c.Bind(&disablePayload); err != nil {
+		return err
+	}
+
+	disabled, err := streamers.Disable(streamer, disablePayload)
+	if err != nil {
+		if errors.Is(err, streamers.ErrInvalidUntil) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "streamer.disable", streamerTarget(streamer.ID), streamer.ID, streamer.Disabled, disabled.Disabled)
+
+	return c.JSON(http.StatusOK, disabled)
+}
+
+func EnableStreamerHandler(c echo.Context) error {
+	streamer, status := streamerFromParam(c)
+	if status != 0 {
+		return c.NoContent(status)
+	}
+
+	enabled, err := streamers.Enable(streamer)
+	if err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "streamer.enable", streamerTarget(streamer.ID), streamer.ID, streamer.Disabled, nil)
+
+	return c.JSON(http.StatusOK, enabled)
+}
+
+func CreateStreamerDestinationHandler(c echo.Context) error {
+	key := c.Param("streamer")
+
+	id, err := strconv.Atoi(key)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	destinationPayload := models.Destination{}
+
+	if err := c.Bind(&destinationPayload); err != nil {
+		return err
+	}
+
+	if destinationPayload.Server == "" {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	streamer, err := streamers.GetStreamer(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	if err := streamers.AddDestination(streamer, destinationPayload); err != nil {
+		if errors.Is(err, quotas.ErrLimitReached) {
+			return c.String(http.StatusForbidden, err.Error())
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	recordDestinationsChange(c, "streamer.destination.add", streamer)
+
+	updated, err := streamers.GetStreamer(id)
+	if err != nil {
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusCreated, updated.Destinations[len(updated.Destinations)-1])
+}
+
+func RemoveStreamerDestinationHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("streamer"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	destinationID, err := strconv.Atoi(c.Param("destination"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	streamer, err := streamers.GetStreamer(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	if err := streamers.RemoveDestination(streamer, destinationID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	recordDestinationsChange(c, "streamer.destination.remove", streamer)
+
+	return c.NoContent(http.StatusAccepted)
 }
 
 func DeleteStreamerHandler(c echo.Context) error {
@@ -75,6 +357,15 @@ func DeleteStreamerHandler(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "Not Found")
 	}
 
+	before, err := streamers.GetStreamer(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
 	if err := streamers.DeleteStreamer(id); err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			return c.NoContent(http.StatusNotFound)
@@ -83,5 +374,11 @@ func DeleteStreamerHandler(c echo.Context) error {
 		return c.NoContent(http.StatusInternalServerError)
 	}
 
+	audit.Record(c, "streamer.delete", streamerTarget(id), id, before, nil)
+
 	return c.NoContent(http.StatusAccepted)
 }
+
+func streamerTarget(id int) string {
+	return "streamer:" + strconv.Itoa(id)
+}
@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/geekgonecrazy/prismplus/audit"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+	"github.com/geekgonecrazy/prismplus/webhooks"
+	"github.com/labstack/echo/v4"
+)
+
+func GetWebhooksHandler(c echo.Context) error {
+	w, err := webhooks.GetWebhooks()
+	if err != nil {
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, w)
+}
+
+func CreateWebhookHandler(c echo.Context) error {
+	webhookPayload := models.WebhookCreatePayload{}
+
+	if err := c.Bind(&webhookPayload); err != nil {
+		return err
+	}
+
+	webhook, err := webhooks.CreateWebhook(webhookPayload)
+	if err != nil {
+		if errors.Is(err, webhooks.ErrInvalidURL) || errors.Is(err, webhooks.ErrInvalidEvents) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "webhook.create", webhookTarget(webhook.ID), 0, nil, webhook)
+
+	return c.JSON(http.StatusCreated, webhook)
+}
+
+func UpdateWebhookHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("webhook"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	before, err := webhooks.GetWebhook(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	webhookPayload := models.WebhookUpdatePayload{}
+
+	if err := c.Bind(&webhookPayload); err != nil {
+		return err
+	}
+
+	webhook, err := webhooks.UpdateWebhook(id, webhookPayload)
+	if err != nil {
+		if errors.Is(err, webhooks.ErrInvalidURL) || errors.Is(err, webhooks.ErrInvalidEvents) {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "webhook.update", webhookTarget(id), 0, before, webhook)
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+func DeleteWebhookHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("webhook"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	if err := webhooks.DeleteWebhook(id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	audit.Record(c, "webhook.delete", webhookTarget(id), 0, nil, nil)
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func PingWebhookHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("webhook"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	if err := webhooks.Ping(id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func GetWebhookDeliveriesHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("webhook"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Not Found")
+	}
+
+	limit := 0
+	if l := c.QueryParam("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil {
+			return c.String(http.StatusBadRequest, "invalid limit")
+		}
+	}
+
+	deliveries, err := webhooks.GetDeliveries(id, limit)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		log.Println("Error:", err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+func webhookTarget(id int) string {
+	return "webhook:" + strconv.Itoa(id)
+}
@@ -0,0 +1,172 @@
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	SessionStarted = "session.started"
+	SessionEnded   = "session.ended"
+	SessionStats   = "session.stats"
+
+	// Destination events are named after the connection state, see DestinationEvent
+	DestinationConnected    = "destination.connected"
+	DestinationReconnecting = "destination.reconnecting"
+	DestinationFailed       = "destination.failed"
+	DestinationDisconnected = "destination.disconnected"
+
+	StreamerCreated = "streamer.created"
+	StreamerDeleted = "streamer.deleted"
+
+	ConfigChanged = "config.changed"
+)
+
+// Types is every event type that is published
+var Types = []string{
+	SessionStarted,
+	SessionEnded,
+	SessionStats,
+	DestinationConnected,
+	DestinationReconnecting,
+	DestinationFailed,
+	DestinationDisconnected,
+	StreamerCreated,
+	StreamerDeleted,
+	ConfigChanged,
+}
+
+// DestinationEvent is the event type for a destination connection state
+func DestinationEvent(state string) string {
+	return "destination." + state
+}
+
+// ValidType reports whether eventType is one that is published
+func ValidType(eventType string) bool {
+	for _, t := range Types {
+		if t == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InWildcard reports whether a "*" subscription gets eventType.  Stats are published every few seconds for
+// every live session, so they have to be subscribed to by name
+func InWildcard(eventType string) bool {
+	return eventType != SessionStats
+}
+
+// subscriberBuffer is how many events a subscriber can fall behind by before events are dropped for it
+const subscriberBuffer = 64
+
+var (
+	_lock        sync.Mutex
+	_subscribers = map[*Subscription]struct{}{}
+	_nextID      int64
+)
+
+// Event is something that happened inside prismplus.  StreamerID is the streamer it concerns, 0 if none
+type Event struct {
+	ID         int64       `json:"id"`
+	Type       string      `json:"type"`
+	Time       time.Time   `json:"time"`
+	StreamerID int         `json:"streamerId"`
+	Data       interface{} `json:"data,omitempty"`
+}
+
+// Subscription receives published events on Events until Unsubscribe is called
+type Subscription struct {
+	Events <-chan Event
+
+	events     chan Event
+	streamerID int
+	types      map[string]bool
+	dropped    bool
+}
+
+// Subscribe starts receiving events.  If streamerID isn't 0 only events for that streamer are received,
+// and if types are given only events of those types
+func Subscribe(streamerID int, types ...string) *Subscription {
+	subscription := &Subscription{
+		events:     make(chan Event, subscriberBuffer),
+		streamerID: streamerID,
+	}
+
+	subscription.Events = subscription.events
+
+	if len(types) > 0 {
+		subscription.types = map[string]bool{}
+		for _, eventType := range types {
+			subscription.types[eventType] = true
+		}
+	}
+
+	_lock.Lock()
+	_subscribers[subscription] = struct{}{}
+	_lock.Unlock()
+
+	return subscription
+}
+
+// Unsubscribe stops the subscription and closes its channel
+func (s *Subscription) Unsubscribe() {
+	_lock.Lock()
+	defer _lock.Unlock()
+
+	if _, ok := _subscribers[s]; !ok {
+		return
+	}
+
+	delete(_subscribers, s)
+	close(s.events)
+}
+
+func (s *Subscription) wants(event Event) bool {
+	if s.streamerID != 0 && event.StreamerID != s.streamerID {
+		return false
+	}
+
+	if s.types != nil && !s.types[event.Type] {
+		return false
+	}
+
+	return true
+}
+
+// Publish sends an event to every interested subscriber.  It never blocks, subscribers that have fallen
+// too far behind miss the event
+func Publish(eventType string, streamerID int, data interface{}) {
+	_lock.Lock()
+	defer _lock.Unlock()
+
+	_nextID++
+
+	event := Event{
+		ID:         _nextID,
+		Type:       eventType,
+		Time:       time.Now(),
+		StreamerID: streamerID,
+		Data:       data,
+	}
+
+	for subscription := range _subscribers {
+		if !subscription.wants(event) {
+			continue
+		}
+
+		select {
+		case subscription.events <- event:
+			subscription.dropped = false
+		default:
+			// Only log the first drop until the subscriber catches up again
+			if !subscription.dropped {
+				log.Println("Event subscriber is falling behind, dropping events")
+				subscription.dropped = true
+			}
+		}
+	}
+}
@@ -3,15 +3,19 @@ module github.com/geekgonecrazy/prismplus
 go 1.15
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/geekgonecrazy/rtmp-lib v0.0.0-20220117215435-0613a79061b3
 	github.com/kr/pretty v0.3.0 // indirect
 	github.com/labstack/echo/v4 v4.6.3
 	github.com/mattn/go-colorable v0.1.12 // indirect
+	github.com/prometheus/client_golang v1.11.1
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/rogpeppe/go-internal v1.8.1 // indirect
 	go.etcd.io/bbolt v1.3.6
-	golang.org/x/crypto v0.0.0-20220112180741-5e0467b6c7ce // indirect
-	golang.org/x/net v0.0.0-20220114011407-0dd24b26b47d // indirect
-	golang.org/x/sys v0.0.0-20220114195835-da31bd327af9 // indirect
-	golang.org/x/time v0.0.0-20211116232009-f0f3c7e86c11 // indirect
+	golang.org/x/crypto v0.0.0-20220112180741-5e0467b6c7ce
+	golang.org/x/net v0.0.0-20220114011407-0dd24b26b47d
+	golang.org/x/time v0.0.0-20211116232009-f0f3c7e86c11
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
+	modernc.org/sqlite v1.20.3
 )
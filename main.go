@@ -2,61 +2,156 @@ package main
 
 import (
 	"crypto/rand"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"os"
+	"time"
 
 	// TODO: switch to joy5?
 
-	"github.com/geekgonecrazy/prismplus/helpers"
+	"github.com/geekgonecrazy/prismplus/admins"
+	"github.com/geekgonecrazy/prismplus/audit"
+	"github.com/geekgonecrazy/prismplus/backup"
+	"github.com/geekgonecrazy/prismplus/bruteforce"
+	"github.com/geekgonecrazy/prismplus/cli"
+	"github.com/geekgonecrazy/prismplus/config"
+	"github.com/geekgonecrazy/prismplus/quotas"
+	"github.com/geekgonecrazy/prismplus/schedules"
+	"github.com/geekgonecrazy/prismplus/sessions"
+	"github.com/geekgonecrazy/prismplus/status"
+	"github.com/geekgonecrazy/prismplus/store"
+	_ "github.com/geekgonecrazy/prismplus/store/boltstore"
+	_ "github.com/geekgonecrazy/prismplus/store/memstore"
+	_ "github.com/geekgonecrazy/prismplus/store/sqlitestore"
 	"github.com/geekgonecrazy/prismplus/streamers"
+	"github.com/geekgonecrazy/prismplus/tokens"
+	"github.com/geekgonecrazy/prismplus/webhooks"
 	rtmp "github.com/geekgonecrazy/rtmp-lib"
 )
 
-var (
-	bind     = flag.String("bind", ":1935", "bind address")
-	adminKey = flag.String("adminKey", "", "Admin key.  If none passed one will be created")
-	dataPath = flag.String("dataPath", "", "Path for data")
-)
+// cfg is the effective configuration, see the config package for where it comes from
+var cfg *config.Config
 
 func main() {
-	flag.Parse()
+	if cli.IsCommand(os.Args[1:]) {
+		os.Exit(cli.Run(os.Args[1:]))
+	}
+
+	var err error
 
-	if *adminKey == "" {
-		uuid, err := helpers.NewUUID()
-		if err != nil {
-			fmt.Println("Can't generate admin authorization key:", err)
-			os.Exit(1)
+	cfg, err = config.Load(os.Args[0], os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
 		}
 
-		*adminKey = uuid
+		log.Fatalln(err)
+	}
+
+	sessions.FailoverTimeout = time.Duration(cfg.RTMP.FailoverTimeout)
+	sessions.AdHocSessionTTL = time.Duration(cfg.Sessions.AdHocTTL)
+
+	quotas.Defaults = cfg.Limits.Limits()
+	quotas.BitrateGrace = time.Duration(cfg.Limits.BitrateGrace)
+
+	bruteforce.Setup(cfg.BruteForce.MaxFailures, time.Duration(cfg.BruteForce.Window), time.Duration(cfg.BruteForce.BanDuration), cfg.BruteForce.RatePerMinute, cfg.BruteForce.RateBurst)
 
-		log.Println("Admin Authorization Key Generated:", *adminKey)
+	streamers.TokenTTL = time.Duration(cfg.Streamers.TokenTTL)
+	streamers.MagicLinkTTL = time.Duration(cfg.Streamers.MagicLinkTTL)
+
+	dataStore, err := store.Open(cfg.StoreURL())
+	if err != nil {
+		log.Fatalln(err)
 	}
 
-	streamers.Setup(*dataPath)
+	streamers.Setup(dataStore)
+	admins.Setup(dataStore)
+	tokens.Setup(dataStore)
+	audit.Setup(dataStore)
+	if err := sessions.InitializeSessionStore(dataStore); err != nil {
+		log.Fatalln("Can't restore sessions:", err)
+	}
+	webhooks.Setup(dataStore)
+	backup.Setup(dataStore)
+
+	if err := schedules.Setup(dataStore); err != nil {
+		log.Fatalln("Can't load schedules:", err)
+	}
+
+	quotas.Setup(dataStore)
+
+	status.Setup(dataStore)
+
+	setupMetrics()
+
+	if err := admins.Bootstrap(cfg.AdminKey); err != nil {
+		log.Fatalln("Can't bootstrap admin account:", err)
+	}
 
 	fmt.Println("Starting RTMP server...")
-	config := &rtmp.Config{
-		ChunkSize:  128,
+	rtmpConfig := &rtmp.Config{
+		ChunkSize:  cfg.RTMP.ChunkSize,
 		BufferSize: 0,
 	}
 
-	server := rtmp.NewServer(config)
-	server.Addr = *bind
+	server := rtmp.NewServer(rtmpConfig)
+	server.Addr = cfg.RTMP.Bind
 
 	server.HandlePublish = rtmpConnectionHandler
 
 	go apiServer()
 
-	fmt.Println("Waiting for incoming connection...")
-	err := server.ListenAndServe()
-	if err != nil {
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- server.ListenAndServe()
+	}()
+
+	if err := waitForListener(cfg.RTMP.Bind, listenErr); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+
+	status.SetRTMPListening(true)
+
+	fmt.Println("Waiting for incoming connection...")
+	err = <-listenErr
+	status.SetRTMPListening(false)
+
+	fmt.Println(err)
+	os.Exit(1)
+}
+
+// waitForListener returns once addr accepts connections, or with the error the server stopped with.  The
+// rtmp server doesn't say when it's listening, so this connects to it, using loopback if it listens on
+// every interface
+func waitForListener(addr string, listenErr chan error) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+
+	for {
+		select {
+		case err := <-listenErr:
+			return err
+		default:
+		}
+
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), time.Second)
+		if err == nil {
+			return conn.Close()
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
 }
 
 // newUUID generates a random UUID according to the RFC 4122, https://play.golang.org/p/4FkNSiUDMg
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/geekgonecrazy/prismplus/rtmp"
+	"github.com/geekgonecrazy/prismplus/sessions"
+	"github.com/geekgonecrazy/prismplus/streamers"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	publishRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prismplus_rtmp_publish_rejected_total",
+		Help: "RTMP publish attempts that were refused",
+	}, []string{"reason"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "prismplus_api_request_duration_seconds",
+		Help:    "API request latency",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// destinationStates are always reported so a state dropping to zero shows up
+var destinationStates = []string{
+	rtmp.StateIdle,
+	rtmp.StateConnected,
+	rtmp.StateReconnecting,
+	rtmp.StateFailed,
+	rtmp.StateDisconnected,
+}
+
+var (
+	sessionLabels     = []string{"streamer", "session"}
+	destinationLabels = []string{"streamer", "session", "destination", "name"}
+
+	ingestBitrateDesc      = prometheus.NewDesc("prismplus_session_ingest_bitrate", "Ingest bitrate in bits per second over the last few seconds", sessionLabels, nil)
+	ingestBytesDesc        = prometheus.NewDesc("prismplus_session_ingest_bytes_total", "Bytes forwarded from the publisher since the session went live", sessionLabels, nil)
+	ingestPacketsDesc      = prometheus.NewDesc("prismplus_session_ingest_packets_total", "Packets forwarded from the publisher since the session went live", sessionLabels, nil)
+	failoversDesc          = prometheus.NewDesc("prismplus_session_failovers_total", "Switches between primary and backup publishers since the session went live", sessionLabels, nil)
+	destinationBytesDesc   = prometheus.NewDesc("prismplus_destination_bytes_sent_total", "Bytes sent to the destination since the session went live", destinationLabels, nil)
+	destinationPacketsDesc = prometheus.NewDesc("prismplus_destination_packets_sent_total", "Packets sent to the destination since the session went live", destinationLabels, nil)
+	reconnectsDesc         = prometheus.NewDesc("prismplus_destination_reconnects_total", "Times the destination reconnected since the session went live", destinationLabels, nil)
+	destinationDroppedDesc = prometheus.NewDesc("prismplus_destination_dropped_packets_total", "Packets dropped because the destination was down", destinationLabels, nil)
+	sessionsDesc           = prometheus.NewDesc("prismplus_sessions", "Sessions, live or waiting for a publisher", nil, nil)
+	sessionsActiveDesc     = prometheus.NewDesc("prismplus_sessions_active", "Sessions that are live", nil, nil)
+	destinationsDesc       = prometheus.NewDesc("prismplus_destinations", "Destinations of live sessions by connection state", []string{"state"}, nil)
+)
+
+func setupMetrics() {
+	prometheus.MustRegister(sessionCollector{})
+
+	if cfg.Metrics.Bind == "" {
+		return
+	}
+
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+
+		log.Println("Serving metrics on", cfg.Metrics.Bind)
+		log.Fatalln(http.ListenAndServe(cfg.Metrics.Bind, mux))
+	}()
+}
+
+// sessionCollector reports on the sessions as they are at scrape time
+type sessionCollector struct{}
+
+func (sessionCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		ingestBitrateDesc, ingestBytesDesc, ingestPacketsDesc, failoversDesc,
+		destinationBytesDesc, destinationPacketsDesc, reconnectsDesc, destinationDroppedDesc,
+		sessionsDesc, sessionsActiveDesc, destinationsDesc,
+	} {
+		ch <- desc
+	}
+}
+
+func (sessionCollector) Collect(ch chan<- prometheus.Metric) {
+	names := map[int]string{}
+	if cfg.Metrics.StreamerNames {
+		list, err := streamers.GetStreamers()
+		if err != nil {
+			log.Println("Error:", err)
+		}
+
+		for _, streamer := range list {
+			names[streamer.ID] = streamer.Name
+		}
+	}
+
+	streamerLabel := func(id int) string {
+		if id == 0 {
+			return ""
+		}
+
+		if name, ok := names[id]; ok {
+			return name
+		}
+
+		return strconv.Itoa(id)
+	}
+
+	all := sessions.GetSessions()
+
+	states := map[string]int{}
+	live := 0
+
+	for _, session := range all {
+		stats, ok := session.Stats()
+		if !ok {
+			continue
+		}
+
+		live++
+
+		labels := []string{streamerLabel(stats.StreamerID), stats.Session}
+
+		ch <- prometheus.MustNewConstMetric(ingestBitrateDesc, prometheus.GaugeValue, float64(stats.Bitrate), labels...)
+		ch <- prometheus.MustNewConstMetric(ingestBytesDesc, prometheus.CounterValue, float64(stats.Ingest.Bytes), labels...)
+		ch <- prometheus.MustNewConstMetric(ingestPacketsDesc, prometheus.CounterValue, float64(stats.Ingest.Packets), labels...)
+		ch <- prometheus.MustNewConstMetric(failoversDesc, prometheus.CounterValue, float64(stats.Ingest.Failovers), labels...)
+
+		for _, destination := range stats.Destinations {
+			states[destination.State]++
+
+			destinationLabels := append(labels[:len(labels):len(labels)], strconv.Itoa(destination.ID), destination.Name)
+
+			ch <- prometheus.MustNewConstMetric(destinationBytesDesc, prometheus.CounterValue, float64(destination.BytesSent), destinationLabels...)
+			ch <- prometheus.MustNewConstMetric(destinationPacketsDesc, prometheus.CounterValue, float64(destination.PacketsSent), destinationLabels...)
+			ch <- prometheus.MustNewConstMetric(reconnectsDesc, prometheus.CounterValue, float64(destination.Reconnects), destinationLabels...)
+			ch <- prometheus.MustNewConstMetric(destinationDroppedDesc, prometheus.CounterValue, float64(destination.Dropped), destinationLabels...)
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(sessionsDesc, prometheus.GaugeValue, float64(len(all)))
+	ch <- prometheus.MustNewConstMetric(sessionsActiveDesc, prometheus.GaugeValue, float64(live))
+
+	for _, state := range destinationStates {
+		ch <- prometheus.MustNewConstMetric(destinationsDesc, prometheus.GaugeValue, float64(states[state]), state)
+	}
+}
@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+const (
+	// RoleAdmin can do everything
+	RoleAdmin = "admin"
+	// RoleOperator can manage sessions but not streamers or admins
+	RoleOperator = "operator"
+	// RoleStreamer can only manage their own streamer
+	RoleStreamer = "streamer"
+	// RoleViewer has read only access to sessions and streamers
+	RoleViewer = "viewer"
+	// RoleToken is a scoped api token.  What it can do depends on its scopes
+	RoleToken = "token"
+)
+
+type Admin struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type AdminCreatePayload struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+type AdminUpdatePayload struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// ValidAdminRole reports whether role can be given to an admin account.  Empty means admin
+func ValidAdminRole(role string) bool {
+	switch role {
+	case "", RoleAdmin, RoleOperator, RoleViewer:
+		return true
+	}
+
+	return false
+}
+
+// AdminAPIKey is a named key an admin authenticates with.  Only the hash of the key is stored and the
+// hash is never returned from the API
+type AdminAPIKey struct {
+	ID      int    `json:"id"`
+	AdminID int    `json:"adminId"`
+	Name    string `json:"name"`
+	Prefix  string `json:"prefix"`
+	Hash    string `json:"hash,omitempty"`
+
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+}
+
+type AdminAPIKeyCreatePayload struct {
+	Name      string     `json:"name"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// AdminAPIKeyCreated is returned once when a key is created.  It is the only time the key is visible
+type AdminAPIKeyCreated struct {
+	AdminAPIKey
+	Key string `json:"key"`
+}
+
+type AdminCreated struct {
+	Admin
+	APIKey AdminAPIKeyCreated `json:"apiKey"`
+}
@@ -0,0 +1,91 @@
+package models
+
+import "time"
+
+const (
+	ScopeStreamersRead     = "streamers:read"
+	ScopeStreamersWrite    = "streamers:write"
+	ScopeSessionsRead      = "sessions:read"
+	ScopeSessionsWrite     = "sessions:write"
+	ScopeDestinationsRead  = "destinations:read"
+	ScopeDestinationsWrite = "destinations:write"
+)
+
+// Scopes is every scope an api token can be given
+var Scopes = []string{
+	ScopeStreamersRead,
+	ScopeStreamersWrite,
+	ScopeSessionsRead,
+	ScopeSessionsWrite,
+	ScopeDestinationsRead,
+	ScopeDestinationsWrite,
+}
+
+// StreamerScopes are the scopes that make sense for a token bound to a streamer
+var StreamerScopes = []string{
+	ScopeStreamersRead,
+	ScopeSessionsRead,
+	ScopeDestinationsRead,
+	ScopeDestinationsWrite,
+}
+
+// APIToken is a scoped token for automation.  If StreamerID is set the token can only act as that
+// streamer on the streamer api.  Only the hash of the token is stored and it is never returned from the API
+type APIToken struct {
+	ID         int      `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Hash       string   `json:"hash,omitempty"`
+	Scopes     []string `json:"scopes"`
+	StreamerID int      `json:"streamerId"`
+
+	CreatedByAdminID    int `json:"createdByAdminId"`
+	CreatedByStreamerID int `json:"createdByStreamerId"`
+
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+}
+
+type APITokenCreatePayload struct {
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	StreamerID int        `json:"streamerId"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+}
+
+// APITokenCreated is returned once when a token is created.  It is the only time the token is visible
+type APITokenCreated struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+// HasScope reports whether the token was given scope
+func (t APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidScopes reports whether every scope is one of allowed
+func ValidScopes(scopes []string, allowed []string) bool {
+	for _, scope := range scopes {
+		found := false
+		for _, a := range allowed {
+			if scope == a {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// AuditEntry records a single mutating api call
+type AuditEntry struct {
+	ID         int                    `json:"id"`
+	Time       time.Time              `json:"time"`
+	Actor      string                 `json:"actor"`
+	ActorID    int                    `json:"actorId"`
+	Role       string                 `json:"role"`
+	Action     string                 `json:"action"`
+	Target     string                 `json:"target"`
+	StreamerID int                    `json:"streamerId"`
+	Changes    map[string]AuditChange `json:"changes"`
+	ClientIP   string                 `json:"clientIp"`
+}
+
+// AuditChange is the before and after of a single field.  Secrets are redacted
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type AuditQuery struct {
+	StreamerID int
+	Since      time.Time
+	// Before only returns entries older than this id, for paging.  0 starts from the newest
+	Before int
+	Limit  int
+}
+
+type AuditPage struct {
+	Entries []AuditEntry `json:"entries"`
+	// Next is the before value for the next page, 0 if there are no more entries
+	Next int `json:"next"`
+}
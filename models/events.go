@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// SessionStartedEvent is sent when the first publisher connects.  Session is only set for sessions that
+// aren't backed by a streamer, so stream keys never end up in events
+type SessionStartedEvent struct {
+	Session   string    `json:"session,omitempty"`
+	Publisher string    `json:"publisher"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+type DestinationStateEvent struct {
+	Session       string `json:"session,omitempty"`
+	DestinationID int    `json:"destinationId"`
+	Name          string `json:"name"`
+	State         string `json:"state"`
+}
+
+type StreamerEvent struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type ConfigChangedEvent struct {
+	Action string `json:"action"`
+	Target string `json:"target"`
+	Actor  string `json:"actor"`
+}
+
+// SessionStats is a snapshot of a live session
+type SessionStats struct {
+	StreamerID   int                `json:"streamerId"`
+	Session      string             `json:"session,omitempty"`
+	StartedAt    time.Time          `json:"startedAt"`
+	Ingest       IngestStats        `json:"ingest"`
+	Bitrate      int64              `json:"bitrate"` // bits per second over the last few seconds
+	Destinations []DestinationStats `json:"destinations"`
+}
+
+type DestinationStats struct {
+	DestinationHistory
+	State string `json:"state"`
+}
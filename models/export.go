@@ -0,0 +1,100 @@
+package models
+
+import "time"
+
+// ExportVersion is the version of the export document written by this build.  Version 1 only had stream
+// keys, duplicate publisher policies and destinations, version 2 added everything else on a streamer
+const ExportVersion = 2
+
+const (
+	// ExportSecretsOmit leaves stream and destination keys out of an export
+	ExportSecretsOmit = "omit"
+	// ExportSecretsPlain includes keys as they are
+	ExportSecretsPlain = "plain"
+	// ExportSecretsEncrypt includes keys encrypted with a passphrase
+	ExportSecretsEncrypt = "encrypt"
+
+	// ImportMerge adds and updates streamers and destinations but never removes any
+	ImportMerge = "merge"
+	// ImportReplace makes the streamers and their destinations exactly what was imported
+	ImportReplace = "replace"
+)
+
+// Export is every streamer and its destinations.  Streamers are matched by name on import, ids and
+// streamer logins aren't exported
+type Export struct {
+	Version    int               `json:"version"`
+	ExportedAt time.Time         `json:"exportedAt"`
+	Secrets    string            `json:"secrets"`
+	Encryption *ExportEncryption `json:"encryption,omitempty"`
+	Streamers  []ExportStreamer  `json:"streamers"`
+}
+
+// ExportEncryption describes how the keys in an export were encrypted.  Check is a known value encrypted
+// with the same key so a wrong passphrase is caught before anything is imported
+type ExportEncryption struct {
+	Algorithm string `json:"algorithm"`
+	Salt      []byte `json:"salt"`
+	N         int    `json:"n"`
+	R         int    `json:"r"`
+	P         int    `json:"p"`
+	Check     string `json:"check"`
+}
+
+type ExportStreamer struct {
+	Name      string `json:"name"`
+	StreamKey string `json:"streamKey,omitempty"`
+
+	DuplicatePublisherPolicy string `json:"duplicatePublisherPolicy"`
+
+	Destinations []ExportDestination `json:"destinations"`
+
+	Profiles      []ExportProfile   `json:"profiles"`
+	ActiveProfile string            `json:"activeProfile"`
+	StreamKeys    []ExportStreamKey `json:"streamKeys"`
+	Limits        Limits            `json:"limits"`
+	Disabled      *StreamerDisabled `json:"disabled"`
+}
+
+type ExportDestination struct {
+	// ID only links the destination to profiles in the same export, it gets a new id when imported
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Server string `json:"server"`
+	Key    string `json:"key,omitempty"`
+}
+
+// ExportProfile lists its destinations by their id in the export
+type ExportProfile struct {
+	Name           string `json:"name"`
+	DestinationIDs []int  `json:"destinationIds"`
+}
+
+// ExportStreamKey is one of the streamer's extra stream keys
+type ExportStreamKey struct {
+	Name    string `json:"name"`
+	Key     string `json:"key,omitempty"`
+	Profile string `json:"profile"`
+}
+
+type ImportOptions struct {
+	Mode       string
+	DryRun     bool
+	Passphrase string
+}
+
+// ImportResult lists what an import changed, or would change on a dry run.  Changes have secrets redacted
+type ImportResult struct {
+	Mode      string         `json:"mode"`
+	DryRun    bool           `json:"dryRun"`
+	Created   []ImportChange `json:"created"`
+	Updated   []ImportChange `json:"updated"`
+	Deleted   []ImportChange `json:"deleted"`
+	Unchanged int            `json:"unchanged"`
+}
+
+type ImportChange struct {
+	ID      int                    `json:"id,omitempty"`
+	Name    string                 `json:"name"`
+	Changes map[string]AuditChange `json:"changes,omitempty"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// SessionHistory is the report for a single broadcast, from the first publisher connecting until the last
+// one leaves
+type SessionHistory struct {
+	ID              int                  `json:"id"`
+	StreamerID      int                  `json:"streamerId"`
+	Key             string               `json:"key,omitempty"` // ad-hoc session id
+	StartedAt       time.Time            `json:"startedAt"`
+	EndedAt         time.Time            `json:"endedAt"`
+	DurationSeconds int64                `json:"durationSeconds"`
+	Ingest          IngestStats          `json:"ingest"`
+	Destinations    []DestinationHistory `json:"destinations"`
+}
+
+type IngestStats struct {
+	Packets           int64 `json:"packets"`
+	Bytes             int64 `json:"bytes"`
+	Keyframes         int64 `json:"keyframes"`
+	AverageBitrate    int64 `json:"averageBitrate"` // bits per second
+	PublisherConnects int   `json:"publisherConnects"`
+	Failovers         int   `json:"failovers"`
+}
+
+// DestinationHistory is how a destination fared during a broadcast.  The key is left out on purpose
+type DestinationHistory struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	Server        string `json:"server"`
+	UptimeSeconds int64  `json:"uptimeSeconds"`
+	Reconnects    int    `json:"reconnects"`
+	BytesSent     int64  `json:"bytesSent"`
+	PacketsSent   int64  `json:"packetsSent"`
+	Dropped       int64  `json:"dropped"`
+}
+
+type SessionHistoryQuery struct {
+	StreamerID int
+	Since      time.Time
+	// Before only returns records older than this id, for paging.  0 starts from the newest
+	Before int
+	Limit  int
+}
+
+type SessionHistoryPage struct {
+	Sessions []SessionHistory `json:"sessions"`
+	// Next is the before value for the next page, 0 if there are no more records
+	Next int `json:"next"`
+}
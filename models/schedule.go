@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Schedule opens a window, once or on a cron schedule, during which the streamer's destinations listed in
+// DestinationIDs are sent the stream.  Outside of every window that lists them they're paused.  Destinations
+// that aren't in any schedule are always sent the stream
+type Schedule struct {
+	ID         int    `json:"id"`
+	StreamerID int    `json:"streamerId"`
+	Name       string `json:"name"`
+	// Cron is a five field cron expression for when a recurring window opens, in TimeZone
+	Cron string `json:"cron,omitempty"`
+	// StartsAt is when a one-off window opens.  Only one of Cron and StartsAt is set
+	StartsAt        *time.Time `json:"startsAt,omitempty"`
+	DurationSeconds int64      `json:"durationSeconds"`
+	TimeZone        string     `json:"timeZone"`
+	DestinationIDs  []int      `json:"destinationIds"`
+	// HardStop ends the live session when a window closes
+	HardStop bool `json:"hardStop"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type SchedulePayload struct {
+	Name            string     `json:"name"`
+	Cron            string     `json:"cron"`
+	StartsAt        *time.Time `json:"startsAt"`
+	DurationSeconds int64      `json:"durationSeconds"`
+	TimeZone        string     `json:"timeZone"`
+	DestinationIDs  []int      `json:"destinationIds"`
+	HardStop        bool       `json:"hardStop"`
+}
+
+type ScheduleWindow struct {
+	OpensAt  time.Time `json:"opensAt"`
+	ClosesAt time.Time `json:"closesAt"`
+}
+
+// ScheduleState is a schedule along with its current or next window
+type ScheduleState struct {
+	Schedule
+	Open bool `json:"open"`
+	// Window is the open window, or the next one.  nil if there won't be another
+	Window *ScheduleWindow `json:"window"`
+}
@@ -1,7 +1,24 @@
 package models
 
+import "time"
+
 type SessionPayload struct {
 	StreamerID   int           `json:"streamerId"`
 	Key          string        `json:"key"`
 	Destinations []Destination `json:"destinations"`
+
+	DuplicatePublisherPolicy string `json:"duplicatePublisherPolicy"`
+}
+
+// AdHocSession is a session created through the api without a streamer.  They're kept in the store so they
+// survive restarts
+type AdHocSession struct {
+	Key                      string        `json:"key"`
+	Destinations             []Destination `json:"destinations"`
+	NextDestinationID        int           `json:"nextDestinationId"`
+	DuplicatePublisherPolicy string        `json:"duplicatePublisherPolicy"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	// LastActiveAt is when the session was last changed or published to, expiry counts from here
+	LastActiveAt time.Time `json:"lastActiveAt"`
 }
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type Status struct {
+	Version       string    `json:"version"`
+	Commit        string    `json:"commit"`
+	BuildDate     string    `json:"buildDate"`
+	GoVersion     string    `json:"goVersion"`
+	StartedAt     time.Time `json:"startedAt"`
+	UptimeSeconds int64     `json:"uptimeSeconds"`
+	Streamers     int       `json:"streamers"`
+	Sessions      int       `json:"sessions"`
+	LiveSessions  int       `json:"liveSessions"`
+}
+
+// Readiness lists each check with "ok" or what is wrong with it
+type Readiness struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
@@ -2,9 +2,27 @@ package models
 
 import "time"
 
+const (
+	// DuplicatePublisherReject refuses a second publisher on a stream key that is already live
+	DuplicatePublisherReject = "reject"
+	// DuplicatePublisherTakeover disconnects the existing publisher in favor of the new one
+	DuplicatePublisherTakeover = "takeover"
+)
+
 type StreamerCreatePayload struct {
 	Name      string `json:"name"`
 	StreamKey string `json:"streamKey"`
+
+	DuplicatePublisherPolicy string `json:"duplicatePublisherPolicy"`
+}
+
+type StreamerUpdatePayload struct {
+	Name string `json:"name"`
+
+	DuplicatePublisherPolicy string `json:"duplicatePublisherPolicy"`
+
+	// Limits replaces the streamer's limits if set
+	Limits *Limits `json:"limits"`
 }
 
 type Streamer struct {
@@ -12,9 +30,22 @@ type Streamer struct {
 	Name      string `json:"name"`
 	StreamKey string `json:"streamKey"`
 
+	DuplicatePublisherPolicy string `json:"duplicatePublisherPolicy"`
+
 	NextDestinationID int           `json:"nextDestinationId"`
 	Destinations      []Destination `json:"destinations"`
 
+	// Profiles are named sets of destinations.  While ActiveProfile is set only its destinations are sent to
+	Profiles      []Profile `json:"profiles"`
+	ActiveProfile string    `json:"activeProfile"`
+
+	// Limits override the server's default limits for this streamer
+	Limits Limits `json:"limits"`
+
+	// Disabled streamers can't publish or make changes through the streamer api.  The rest of their
+	// configuration is kept for when they are enabled again
+	Disabled *StreamerDisabled `json:"disabled"`
+
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
@@ -26,7 +57,195 @@ type Destination struct {
 	Key    string `json:"key"`
 }
 
+// Profile is a named set of the streamer's destinations, like "Twitch and YouTube" or "Owncast only"
+type Profile struct {
+	Name           string `json:"name"`
+	DestinationIDs []int  `json:"destinationIds"`
+}
+
+type ProfilePayload struct {
+	DestinationIDs []int `json:"destinationIds"`
+}
+
+type ActiveProfilePayload struct {
+	Profile string `json:"profile"`
+}
+
+// GetProfile returns the named profile
+func (s Streamer) GetProfile(name string) (Profile, bool) {
+	for _, profile := range s.Profiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+
+	return Profile{}, false
+}
+
+// ProfileDestinations returns the destinations in the named profile, or all of them for "".  ok is false
+// if there is no such profile
+func (s Streamer) ProfileDestinations(name string) (destinations []Destination, ok bool) {
+	if name == "" {
+		return s.Destinations, true
+	}
+
+	profile, ok := s.GetProfile(name)
+	if !ok {
+		return nil, false
+	}
+
+	inProfile := map[int]bool{}
+	for _, id := range profile.DestinationIDs {
+		inProfile[id] = true
+	}
+
+	destinations = []Destination{}
+	for _, destination := range s.Destinations {
+		if inProfile[destination.ID] {
+			destinations = append(destinations, destination)
+		}
+	}
+
+	return destinations, true
+}
+
+// StreamKey is an extra ingest key for a streamer, like one per encoder.  Publishing with it goes to the
+// streamer's session, on Profile if it is set
+type StreamKey struct {
+	ID         int       `json:"id"`
+	StreamerID int       `json:"streamerId"`
+	Name       string    `json:"name"`
+	Key        string    `json:"key"`
+	Profile    string    `json:"profile"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type StreamKeyPayload struct {
+	Name    string `json:"name"`
+	Key     string `json:"key"`
+	Profile string `json:"profile"`
+}
+
+const (
+	// BitrateWarn records a warning on the session when ingest stays over the maximum bitrate
+	BitrateWarn = "warn"
+	// BitrateDisconnect ends the session when ingest stays over the maximum bitrate
+	BitrateDisconnect = "disconnect"
+)
+
+// Limits caps what a streamer can do.  On a streamer 0 uses the server default and -1 lifts the limit.  In
+// the limits that apply 0 is unlimited.  MaxSessionSeconds counts from when the session first went live,
+// through any reconnects within a few minutes
+type Limits struct {
+	MaxDestinations   int    `json:"maxDestinations"`
+	MaxBitrateKbps    int    `json:"maxBitrateKbps"`
+	BitrateAction     string `json:"bitrateAction"`
+	MaxSessionSeconds int64  `json:"maxSessionSeconds"`
+	MonthlyHours      int    `json:"monthlyHours"`
+}
+
+// ValidLimits reports whether limits can be set on a streamer
+func ValidLimits(limits Limits) bool {
+	switch limits.BitrateAction {
+	case "", BitrateWarn, BitrateDisconnect:
+	default:
+		return false
+	}
+
+	return limits.MaxDestinations >= -1 && limits.MaxBitrateKbps >= -1 && limits.MaxSessionSeconds >= -1 && limits.MonthlyHours >= -1
+}
+
+// StreamerDisabled is why a streamer was disabled and, optionally, when they are enabled again
+type StreamerDisabled struct {
+	Reason     string     `json:"reason"`
+	Until      *time.Time `json:"until"`
+	DisabledAt time.Time  `json:"disabledAt"`
+}
+
+type StreamerDisablePayload struct {
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"until"`
+}
+
+// IsDisabled reports whether the streamer is disabled at now.  A disable that has run out no longer counts
+func (s Streamer) IsDisabled(now time.Time) bool {
+	if s.Disabled == nil {
+		return false
+	}
+
+	return s.Disabled.Until == nil || now.Before(*s.Disabled.Until)
+}
+
+// StreamerUsage is how long a streamer has been live in a calendar month, in UTC
+type StreamerUsage struct {
+	StreamerID      int    `json:"streamerId"`
+	Month           string `json:"month"`
+	StreamedSeconds int64  `json:"streamedSeconds"`
+}
+
 type MyStreamer struct {
 	Streamer
 	Live bool `json:"live"`
+
+	// Limits are the limits that apply, with the server defaults filled in.  They replace the streamer's
+	// own overrides in the response
+	Limits Limits        `json:"limits"`
+	Usage  StreamerUsage `json:"usage"`
+}
+
+// ValidDuplicatePublisherPolicy reports whether policy is a known policy.  Empty means the default of reject
+func ValidDuplicatePublisherPolicy(policy string) bool {
+	switch policy {
+	case "", DuplicatePublisherReject, DuplicatePublisherTakeover:
+		return true
+	}
+
+	return false
+}
+
+// StreamerCredentials are the streamer's login credentials.  They are stored apart from the streamer so
+// they never end up in an API response
+type StreamerCredentials struct {
+	StreamerID   int    `json:"streamerId"`
+	PasswordHash []byte `json:"passwordHash"`
+
+	MagicLinkHash      string    `json:"magicLinkHash"`
+	MagicLinkExpiresAt time.Time `json:"magicLinkExpiresAt"`
+}
+
+// StreamerToken is a short lived session token issued on login.  Only the hash of the token is stored
+type StreamerToken struct {
+	Hash       string    `json:"hash"`
+	StreamerID int       `json:"streamerId"`
+	CreatedAt  time.Time `json:"createdAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+type StreamerLoginPayload struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+type StreamerMagicLinkLoginPayload struct {
+	Token string `json:"token"`
+}
+
+type StreamerPasswordPayload struct {
+	Password string `json:"password"`
+
+	// CurrentPassword is needed for streamers to change their own password once they have one
+	CurrentPassword string `json:"currentPassword,omitempty"`
+}
+
+// StreamerLogin is returned on a successful login and holds the token for the streamer API
+type StreamerLogin struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// StreamerMagicLink is a one-time login token created by an admin
+type StreamerMagicLink struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
 }
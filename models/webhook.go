@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/events"
+)
+
+// Webhook posts events to URL.  The secret signs every delivery and is only returned when the webhook is
+// created
+type Webhook struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	Disabled  bool      `json:"disabled"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type WebhookCreatePayload struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret is generated if empty
+	Secret string `json:"secret"`
+}
+
+type WebhookUpdatePayload struct {
+	URL      string   `json:"url"`
+	Events   []string `json:"events"`
+	Disabled *bool    `json:"disabled"`
+}
+
+// WantsEvent reports whether the webhook is subscribed to eventType.  "*" subscribes to everything except
+// session.stats
+func (w Webhook) WantsEvent(eventType string) bool {
+	if w.Disabled {
+		return false
+	}
+
+	for _, e := range w.Events {
+		if e == eventType || (e == "*" && events.InWildcard(eventType)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WebhookDelivery is a single attempt at delivering an event
+type WebhookDelivery struct {
+	ID         int       `json:"id"`
+	WebhookID  int       `json:"webhookId"`
+	EventID    int64     `json:"eventId"`
+	EventType  string    `json:"eventType"`
+	Attempt    int       `json:"attempt"`
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"statusCode"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+	Success    bool      `json:"success"`
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+)
+
+type publishAuthRequest struct {
+	App      string `json:"app"`
+	Key      string `json:"key"`
+	Role     string `json:"role"`
+	ClientIP string `json:"clientIp"`
+	Query    string `json:"query"`
+}
+
+type publishAuthResponse struct {
+	Allow        bool                 `json:"allow"`
+	Reason       string               `json:"reason"`
+	Destinations []models.Destination `json:"destinations"`
+
+	DuplicatePublisherPolicy string `json:"duplicatePublisherPolicy"`
+}
+
+var publishAuthClient = &http.Client{Timeout: 5 * time.Second}
+
+// authorizePublish asks the publishAuthURL whether a publish is allowed.  Anything other than a 2xx
+// response with allow set is treated as a denial.
+func authorizePublish(authRequest publishAuthRequest) (*publishAuthResponse, error) {
+	body, err := json.Marshal(authRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := publishAuthClient.Post(cfg.PublishAuthURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("publish auth returned status %d", res.StatusCode)
+	}
+
+	authResponse := &publishAuthResponse{}
+	if err := json.NewDecoder(res.Body).Decode(authResponse); err != nil {
+		return nil, err
+	}
+
+	if !models.ValidDuplicatePublisherPolicy(authResponse.DuplicatePublisherPolicy) {
+		return nil, fmt.Errorf("publish auth returned invalid duplicatePublisherPolicy %q", authResponse.DuplicatePublisherPolicy)
+	}
+
+	return authResponse, nil
+}
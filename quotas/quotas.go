@@ -0,0 +1,399 @@
+package quotas
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/events"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/sessions"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+// checkInterval is how often live sessions are counted towards usage and checked against their limits.  It
+// matches how often the ingest bitrate is measured
+const checkInterval = 5 * time.Second
+
+// reconnectWindow is how long a streamer can be offline and still carry on the same session as far as
+// MaxSessionSeconds is concerned, so reconnecting doesn't start a fresh allowance
+const reconnectWindow = 5 * time.Minute
+
+var (
+	// Defaults are the limits for streamers that don't override them.  0 is unlimited
+	Defaults = models.Limits{BitrateAction: models.BitrateWarn}
+
+	// BitrateGrace is how long ingest can stay over the maximum bitrate before the bitrate action is taken
+	BitrateGrace = 30 * time.Second
+
+	ErrLimitReached = errors.New("limit reached")
+
+	_dataStore store.Store
+
+	_lock sync.Mutex
+	// _broadcasts is how far each live broadcast has been counted towards its streamer's usage, and
+	// whether it was ended for a limit
+	_broadcasts = map[broadcastKey]*broadcastState{}
+	// _sessions is when each streamer's session first started, through any reconnects
+	_sessions = map[int]*sessionState{}
+	// _overBitrate is when each live session went over its maximum bitrate, and whether it was warned
+	_overBitrate = map[string]*overage{}
+)
+
+// broadcastKey identifies a broadcast both while it's live and in the history published when it ends
+type broadcastKey struct {
+	streamerID int
+	startedAt  int64
+}
+
+func newBroadcastKey(streamerID int, startedAt time.Time) broadcastKey {
+	return broadcastKey{streamerID: streamerID, startedAt: startedAt.UnixNano()}
+}
+
+type broadcastState struct {
+	counted time.Time
+	// ended is set once the broadcast is ended for a limit, so that's only done once
+	ended bool
+}
+
+type sessionState struct {
+	startedAt time.Time
+	// liveAt is when the streamer was last seen live
+	liveAt time.Time
+}
+
+type overage struct {
+	since  time.Time
+	warned bool
+}
+
+// Setup starts counting usage and enforcing limits on live sessions
+func Setup(dataStore store.Store) {
+	_dataStore = dataStore
+
+	go func() {
+		for now := range time.Tick(checkInterval) {
+			apply(now)
+		}
+	}()
+
+	subscription := events.Subscribe(0, events.SessionEnded)
+
+	go func() {
+		for event := range subscription.Events {
+			history, ok := event.Data.(models.SessionHistory)
+			if !ok {
+				continue
+			}
+
+			if err := ended(history); err != nil {
+				log.Println("Error counting usage:", err)
+			}
+		}
+	}()
+}
+
+// Effective returns the limits that apply to the streamer, its overrides on top of the defaults
+func Effective(streamer models.Streamer) models.Limits {
+	limits := models.Limits{
+		MaxDestinations:   int(override(int64(Defaults.MaxDestinations), int64(streamer.Limits.MaxDestinations))),
+		MaxBitrateKbps:    int(override(int64(Defaults.MaxBitrateKbps), int64(streamer.Limits.MaxBitrateKbps))),
+		BitrateAction:     Defaults.BitrateAction,
+		MaxSessionSeconds: override(Defaults.MaxSessionSeconds, streamer.Limits.MaxSessionSeconds),
+		MonthlyHours:      int(override(int64(Defaults.MonthlyHours), int64(streamer.Limits.MonthlyHours))),
+	}
+
+	if streamer.Limits.BitrateAction != "" {
+		limits.BitrateAction = streamer.Limits.BitrateAction
+	}
+
+	return limits
+}
+
+// override is the streamer's value for a limit if it set one, -1 meaning unlimited
+func override(limit int64, value int64) int64 {
+	switch {
+	case value < 0:
+		return 0
+	case value > 0:
+		return value
+	}
+
+	return limit
+}
+
+// GetUsage returns how long the streamer has been live this month
+func GetUsage(streamerID int, now time.Time) (models.StreamerUsage, error) {
+	month := now.UTC().Format("2006-01")
+
+	usage, err := _dataStore.GetStreamerUsage(streamerID, month)
+	if errors.Is(err, store.ErrNotFound) {
+		return models.StreamerUsage{StreamerID: streamerID, Month: month}, nil
+	}
+
+	return usage, err
+}
+
+// CheckPublish refuses a publish once the streamer has used up their monthly hours, or when it would
+// carry on a session that already reached its maximum duration
+func CheckPublish(streamer models.Streamer) error {
+	limits := Effective(streamer)
+
+	if limits.MaxSessionSeconds > 0 {
+		now := time.Now()
+
+		_lock.Lock()
+		state := _sessions[streamer.ID]
+		_lock.Unlock()
+
+		if state != nil && now.Sub(state.liveAt) <= reconnectWindow && now.Sub(state.startedAt) >= time.Duration(limits.MaxSessionSeconds)*time.Second {
+			wait := state.liveAt.Add(reconnectWindow).Sub(now).Round(time.Second)
+			return fmt.Errorf("%w: session reached its maximum duration, try again in %s", ErrLimitReached, wait)
+		}
+	}
+
+	if limits.MonthlyHours == 0 {
+		return nil
+	}
+
+	usage, err := GetUsage(streamer.ID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if usage.StreamedSeconds >= int64(limits.MonthlyHours)*3600 {
+		return fmt.Errorf("%w: %d streaming hours used this month", ErrLimitReached, limits.MonthlyHours)
+	}
+
+	return nil
+}
+
+// CheckDestinations refuses another destination once the streamer has as many as they're allowed
+func CheckDestinations(streamer models.Streamer) error {
+	return checkDestinationCount(Effective(streamer), len(streamer.Destinations)+1)
+}
+
+// CheckDestinationCount refuses a streamer with more destinations than they're allowed
+func CheckDestinationCount(streamer models.Streamer) error {
+	return checkDestinationCount(Effective(streamer), len(streamer.Destinations))
+}
+
+// CheckSessionDestinations refuses a session with more destinations than its streamer is allowed.  Sessions
+// without a streamer get the defaults
+func CheckSessionDestinations(streamerID int, count int) error {
+	limits := Defaults
+
+	if streamerID != 0 {
+		streamer, err := _dataStore.GetStreamerByID(streamerID)
+		if err != nil {
+			return err
+		}
+
+		limits = Effective(streamer)
+	}
+
+	return checkDestinationCount(limits, count)
+}
+
+func checkDestinationCount(limits models.Limits, count int) error {
+	if limits.MaxDestinations > 0 && count > limits.MaxDestinations {
+		return fmt.Errorf("%w: at most %d destinations", ErrLimitReached, limits.MaxDestinations)
+	}
+
+	return nil
+}
+
+// apply counts live sessions towards their streamer's usage and ends or warns those over a limit
+func apply(now time.Time) {
+	_lock.Lock()
+	defer _lock.Unlock()
+
+	live := map[string]bool{}
+	broadcasts := map[broadcastKey]bool{}
+	streamersLive := map[int]bool{}
+
+	for _, session := range sessions.GetSessions() {
+		snapshot := session.Snapshot()
+		if snapshot.StreamerID == 0 {
+			continue
+		}
+
+		stats, ok := session.Stats()
+		if !ok {
+			continue
+		}
+
+		live[snapshot.Key] = true
+		broadcasts[newBroadcastKey(snapshot.StreamerID, stats.StartedAt)] = true
+		streamersLive[snapshot.StreamerID] = true
+
+		streamer, err := _dataStore.GetStreamerByID(snapshot.StreamerID)
+		if err != nil {
+			continue
+		}
+
+		if err := check(session, streamer, stats, now); err != nil {
+			log.Println("Error checking limits:", err)
+		}
+	}
+
+	for key := range _overBitrate {
+		if !live[key] {
+			delete(_overBitrate, key)
+		}
+	}
+
+	// Ended broadcasts are counted and forgotten when their history is published.  This only catches
+	// those whose event was dropped
+	for key, state := range _broadcasts {
+		if !broadcasts[key] && now.Sub(state.counted) > time.Minute {
+			delete(_broadcasts, key)
+		}
+	}
+
+	for streamerID, state := range _sessions {
+		if !streamersLive[streamerID] && now.Sub(state.liveAt) > reconnectWindow {
+			delete(_sessions, streamerID)
+		}
+	}
+}
+
+// ended counts the rest of a broadcast, since it was last checked, towards its streamer's usage
+func ended(history models.SessionHistory) error {
+	if history.StreamerID == 0 {
+		return nil
+	}
+
+	_lock.Lock()
+	defer _lock.Unlock()
+
+	if state := _sessions[history.StreamerID]; state != nil && history.EndedAt.After(state.liveAt) {
+		state.liveAt = history.EndedAt
+	}
+
+	key := newBroadcastKey(history.StreamerID, history.StartedAt)
+
+	counted := history.StartedAt
+	if state, ok := _broadcasts[key]; ok {
+		counted = state.counted
+	}
+
+	delete(_broadcasts, key)
+
+	seconds := int64(history.EndedAt.Sub(counted).Round(time.Second) / time.Second)
+	if seconds <= 0 {
+		return nil
+	}
+
+	return _dataStore.AddStreamerUsage(history.StreamerID, history.EndedAt.UTC().Format("2006-01"), seconds)
+}
+
+// sessionStarted returns when the streamer's session first started.  A broadcast starting within
+// reconnectWindow of the streamer last being live carries on the same session.  Caller must hold the lock
+func sessionStarted(streamerID int, broadcastStartedAt time.Time, now time.Time) time.Time {
+	state := _sessions[streamerID]
+	if state == nil || broadcastStartedAt.Sub(state.liveAt) > reconnectWindow {
+		state = &sessionState{startedAt: broadcastStartedAt}
+		_sessions[streamerID] = state
+	}
+
+	state.liveAt = now
+
+	return state.startedAt
+}
+
+// check counts the time since the session was last checked and enforces the streamer's limits.  A
+// broadcast is only ended once, it stays live until its publishers notice.  Caller must hold the lock
+func check(session *sessions.Session, streamer models.Streamer, stats models.SessionStats, now time.Time) error {
+	limits := Effective(streamer)
+
+	key := newBroadcastKey(streamer.ID, stats.StartedAt)
+
+	state, ok := _broadcasts[key]
+	if !ok {
+		state = &broadcastState{counted: stats.StartedAt}
+		_broadcasts[key] = state
+	}
+
+	// Whole seconds only, the rest is counted next time
+	seconds := int64(now.Sub(state.counted) / time.Second)
+	if seconds > 0 {
+		if err := _dataStore.AddStreamerUsage(streamer.ID, now.UTC().Format("2006-01"), seconds); err != nil {
+			return err
+		}
+
+		state.counted = state.counted.Add(time.Duration(seconds) * time.Second)
+	}
+
+	startedAt := sessionStarted(streamer.ID, stats.StartedAt, now)
+
+	if state.ended {
+		return nil
+	}
+
+	if limits.MaxSessionSeconds > 0 && now.Sub(startedAt) >= time.Duration(limits.MaxSessionSeconds)*time.Second {
+		log.Println("Ending session for streamer", streamer.ID, "at its maximum duration")
+		state.ended = true
+		session.EndSessionFor("limit_reached", "session reached its maximum duration")
+		return nil
+	}
+
+	if limits.MonthlyHours > 0 {
+		usage, err := GetUsage(streamer.ID, now)
+		if err != nil {
+			return err
+		}
+
+		if usage.StreamedSeconds >= int64(limits.MonthlyHours)*3600 {
+			log.Println("Ending session for streamer", streamer.ID, "with its monthly hours used up")
+			state.ended = true
+			session.EndSessionFor("limit_reached", "monthly streaming hours used up")
+			return nil
+		}
+	}
+
+	if checkBitrate(session, streamer, limits, stats.Bitrate, now) {
+		state.ended = true
+	}
+
+	return nil
+}
+
+// checkBitrate takes the bitrate action once ingest has been over the maximum for longer than
+// BitrateGrace, and reports whether it ended the session.  Warnings are recorded once per overage.  Caller
+// must hold the lock
+func checkBitrate(session *sessions.Session, streamer models.Streamer, limits models.Limits, bitrate int64, now time.Time) bool {
+	maxBitrate := int64(limits.MaxBitrateKbps) * 1000
+
+	if maxBitrate == 0 || bitrate <= maxBitrate {
+		delete(_overBitrate, session.Key)
+		return false
+	}
+
+	over := _overBitrate[session.Key]
+	if over == nil {
+		over = &overage{since: now}
+		_overBitrate[session.Key] = over
+	}
+
+	if now.Sub(over.since) < BitrateGrace {
+		return false
+	}
+
+	message := fmt.Sprintf("ingest at %d kbps has been over the %d kbps limit for %s", bitrate/1000, limits.MaxBitrateKbps, now.Sub(over.since).Round(time.Second))
+
+	if limits.BitrateAction == models.BitrateDisconnect {
+		log.Println("Ending session for streamer", streamer.ID, message)
+		session.EndSessionFor("limit_reached", message)
+		return true
+	}
+
+	if !over.warned {
+		over.warned = true
+		session.Warn("bitrate_warning", message)
+	}
+
+	return false
+}
@@ -2,6 +2,7 @@ package rtmp
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	rtmp "github.com/geekgonecrazy/rtmp-lib"
@@ -14,12 +15,45 @@ This is all almost as is from original prism
 
 */
 
+// Connection states reported to OnStateChange
+const (
+	StateIdle         = "idle"
+	StateConnected    = "connected"
+	StateReconnecting = "reconnecting"
+	StateFailed       = "failed"
+	StateDisconnected = "disconnected"
+)
+
 type RTMPConnection struct {
 	url  string
 	conn *rtmp.Conn
 
+	// OnStateChange is called whenever the connection changes state, if set
+	OnStateChange func(state string) `json:"-"`
+
 	header  []av.CodecData
 	packets chan av.Packet
+
+	statsLock   sync.Mutex
+	state       string
+	connectedAt time.Time
+	uptime      time.Duration
+	reconnects  int
+	bytesSent   int64
+	packetsSent int64
+	dropped     int64
+}
+
+// Stats covers the connection since it was last disconnected
+type Stats struct {
+	State       string
+	Connected   bool
+	Uptime      time.Duration
+	Reconnects  int
+	BytesSent   int64
+	PacketsSent int64
+	// Dropped counts packets that couldn't be queued because the connection was down
+	Dropped int64
 }
 
 func NewRTMPConnection(u string) *RTMPConnection {
@@ -35,6 +69,61 @@ func (r *RTMPConnection) reset() {
 	r.packets = make(chan av.Packet, 2)
 	r.conn = nil
 	r.header = nil
+
+	r.statsLock.Lock()
+	r.state = StateIdle
+	r.connectedAt = time.Time{}
+	r.uptime = 0
+	r.reconnects = 0
+	r.bytesSent = 0
+	r.packetsSent = 0
+	r.dropped = 0
+	r.statsLock.Unlock()
+}
+
+// Stats returns how long the connection has been up along with what it has sent
+func (r *RTMPConnection) Stats() Stats {
+	r.statsLock.Lock()
+	defer r.statsLock.Unlock()
+
+	stats := Stats{
+		State:       r.state,
+		Connected:   !r.connectedAt.IsZero(),
+		Uptime:      r.uptime,
+		Reconnects:  r.reconnects,
+		BytesSent:   r.bytesSent,
+		PacketsSent: r.packetsSent,
+		Dropped:     r.dropped,
+	}
+
+	if stats.Connected {
+		stats.Uptime += time.Since(r.connectedAt)
+	}
+
+	return stats
+}
+
+// setState records the new state, counting uptime while connected
+func (r *RTMPConnection) setState(state string) {
+	r.statsLock.Lock()
+
+	if state == StateConnected && r.connectedAt.IsZero() {
+		r.connectedAt = time.Now()
+	}
+
+	if state != StateConnected && !r.connectedAt.IsZero() {
+		r.uptime += time.Since(r.connectedAt)
+		r.connectedAt = time.Time{}
+	}
+
+	changed := r.state != state
+	r.state = state
+
+	r.statsLock.Unlock()
+
+	if changed && r.OnStateChange != nil {
+		r.OnStateChange(state)
+	}
 }
 
 func (r *RTMPConnection) Dial() error {
@@ -53,6 +142,9 @@ func (r *RTMPConnection) Dial() error {
 
 	fmt.Println("connection established:", r.url)
 	r.conn = c
+
+	r.setState(StateConnected)
+
 	return nil
 }
 
@@ -65,6 +157,7 @@ func (r *RTMPConnection) Disconnect() error {
 	}
 
 	close(r.packets)
+	r.setState(StateDisconnected)
 	r.reset()
 
 	fmt.Println("connection closed:", r.url)
@@ -74,7 +167,12 @@ func (r *RTMPConnection) Disconnect() error {
 func (r *RTMPConnection) WriteHeader(h []av.CodecData) error {
 	r.header = h
 	if r.conn == nil {
-		return r.Dial()
+		if err := r.Dial(); err != nil {
+			r.setState(StateFailed)
+			return err
+		}
+
+		return nil
 	}
 
 	return r.conn.WriteHeader(h)
@@ -82,6 +180,10 @@ func (r *RTMPConnection) WriteHeader(h []av.CodecData) error {
 
 func (r *RTMPConnection) WritePacket(p av.Packet) {
 	if r.conn == nil {
+		r.statsLock.Lock()
+		r.dropped++
+		r.statsLock.Unlock()
+
 		return
 	}
 
@@ -110,6 +212,7 @@ func (r *RTMPConnection) Loop() error {
 	for p := range r.packets {
 		if err := r.conn.WritePacket(p); err != nil {
 			r.conn = nil
+			r.setState(StateReconnecting)
 			fmt.Println(err)
 
 			for {
@@ -122,9 +225,20 @@ func (r *RTMPConnection) Loop() error {
 				}
 
 				// successful re-connect
+				r.statsLock.Lock()
+				r.reconnects++
+				r.statsLock.Unlock()
+
 				break
 			}
+
+			continue
 		}
+
+		r.statsLock.Lock()
+		r.bytesSent += int64(len(p.Data))
+		r.packetsSent++
+		r.statsLock.Unlock()
 	}
 
 	return nil
@@ -4,33 +4,152 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"os"
+	"net"
 	"strings"
 	"time"
 
+	"github.com/geekgonecrazy/prismplus/bruteforce"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/quotas"
 	"github.com/geekgonecrazy/prismplus/sessions"
 	"github.com/geekgonecrazy/prismplus/streamers"
 	rtmp "github.com/geekgonecrazy/rtmp-lib"
 )
 
 func rtmpConnectionHandler(conn *rtmp.Conn) {
-	urlSegments := strings.Split(conn.URL.Path, "/")
-	key := urlSegments[len(urlSegments)-1:][0]
+	remoteAddr := conn.NetConn().RemoteAddr().String()
+	clientIP, _, _ := net.SplitHostPort(remoteAddr)
 
-	fmt.Println("Incoming rtmp connection", key)
+	if bruteforce.IsBanned(clientIP) {
+		log.Println("Refusing rtmp connection from banned ip", clientIP)
+		publishRejected.WithLabelValues("banned").Inc()
+		conn.Close()
+		return
+	}
+
+	if !bruteforce.Allow(clientIP) {
+		log.Println("Refusing rtmp connection from rate limited ip", clientIP)
+		publishRejected.WithLabelValues("rate_limited").Inc()
+		conn.Close()
+		return
+	}
+
+	key, profile := splitStreamPath(conn.URL.Path)
+
+	// A backup encoder publishes to the same session with the backup suffix on its key
+	role := sessions.PublisherPrimary
+	if strings.HasSuffix(key, sessions.BackupKeySuffix) {
+		key = strings.TrimSuffix(key, sessions.BackupKeySuffix)
+		role = sessions.PublisherBackup
+	}
+
+	fmt.Println("Incoming rtmp connection", key, role)
+
+	var authResponse *publishAuthResponse
+	if cfg.PublishAuthURL != "" {
+		app, _ := rtmp.SplitPath(conn.URL)
+
+		response, err := authorizePublish(publishAuthRequest{
+			App:      app,
+			Key:      key,
+			Role:     role,
+			ClientIP: clientIP,
+			Query:    conn.URL.RawQuery,
+		})
+		if err != nil {
+			log.Println("Publish authorization failed for", key, err)
+			publishRejected.WithLabelValues("auth_error").Inc()
+			conn.Close()
+			return
+		}
+
+		if !response.Allow {
+			log.Println("Publish denied for", key, response.Reason)
+			bruteforce.RecordFailure(clientIP, "rtmp publish denied by publish authorization")
+			publishRejected.WithLabelValues("auth_denied").Inc()
+			conn.Close()
+			return
+		}
+
+		authResponse = response
+	}
+
+	// A streamer's extra stream keys all publish to the session on its own key
+	var streamer *models.Streamer
+	var streamKey *models.StreamKey
+	if resolved, extra, err := streamers.ResolveStreamKey(key); err == nil {
+		streamer = &resolved
+		streamKey = extra
+		key = resolved.StreamKey
+
+		if profile == "" && extra != nil {
+			profile = extra.Profile
+		}
+
+		if resolved.IsDisabled(time.Now()) {
+			log.Println("Refusing publish for disabled streamer", resolved.Name)
+			publishRejected.WithLabelValues("disabled").Inc()
+			conn.Close()
+			return
+		}
+
+		if err := quotas.CheckPublish(resolved); err != nil {
+			log.Println("Refusing publish for", resolved.Name, err)
+			publishRejected.WithLabelValues("limit_reached").Inc()
+			conn.Close()
+			return
+		}
+	}
 
 	// TODO: This could probably be more efficient
 	session, err := sessions.GetSession(key)
-	if err != nil {
-		if errors.Is(err, sessions.ErrNotFound) {
-			streamer, err := streamers.GetStreamerByStreamKey(key)
-			if err == nil {
-				session, _ = sessions.CreateSessionFromStreamer(streamer)
-			}
+
+	// A session from an earlier authorization that isn't live any more is replaced with this one's
+	if session != nil && authResponse != nil && sessions.ReleaseAuthorizedSession(session) {
+		session, err = nil, sessions.ErrNotFound
+	}
+
+	if errors.Is(err, sessions.ErrNotFound) && authResponse != nil && len(authResponse.Destinations) > 0 {
+		// The authorizing system told us where this stream goes
+		if err := quotas.CheckSessionDestinations(0, len(authResponse.Destinations)); err != nil {
+			log.Println("Refusing publish for", key, err)
+			publishRejected.WithLabelValues("limit_reached").Inc()
+			conn.Close()
+			return
+		}
+
+		session, err = sessions.CreateAuthorizedSession(models.SessionPayload{
+			Key:          key,
+			Destinations: authResponse.Destinations,
+
+			DuplicatePublisherPolicy: authResponse.DuplicatePublisherPolicy,
+		})
+		if err != nil {
+			// Another publisher on the key, like the backup, created it first
+			session, _ = sessions.GetSession(key)
+		}
+	} else if streamer != nil && (session == nil || (session.StreamerID == streamer.ID && role == sessions.PublisherPrimary && !session.Active)) {
+		// A new broadcast for a streamer, possibly on a different profile than the last one
+		session, err = streamers.PrepareSession(*streamer, profile)
+		if errors.Is(err, streamers.ErrUnknownProfile) {
+			log.Println("Refusing publish to unknown profile", profile, "for", key)
+			publishRejected.WithLabelValues("unknown_profile").Inc()
+			conn.Close()
+			return
 		}
 	}
 
+	// The key is fine as far as the authorizing system is concerned, there's just nowhere to send it
+	if session == nil && authResponse != nil {
+		log.Println("Refusing publish for", key, "allowed by publish authorization without destinations")
+		publishRejected.WithLabelValues("no_destinations").Inc()
+		conn.Close()
+		return
+	}
+
 	if session == nil {
+		bruteforce.RecordFailure(clientIP, "rtmp publish with unknown stream key")
+		publishRejected.WithLabelValues("unknown_key").Inc()
 		conn.Close()
 		return
 	}
@@ -38,23 +157,21 @@ func rtmpConnectionHandler(conn *rtmp.Conn) {
 	streams, err := conn.Streams()
 	if err != nil {
 		fmt.Println("can't retrieve streams:", err)
-		os.Exit(1)
+		conn.Close()
+		return
 	}
 
-	// Mark session as active and stash headers for replay on new destinations
-	session.ChangeState(true) // Mark active
-	session.SetHeaders(streams)
-
-	log.Println("RTMP connection now active for session", key)
-
-	for _, destination := range session.Destinations {
-		if err := destination.RTMP.WriteHeader(streams); err != nil {
-			fmt.Println("can't write header to destination stream:", err)
-			// os.Exit(1)
-		}
-		go destination.RTMP.Loop()
+	// Attaching the first publisher marks the session active and stashes headers for replay on new destinations
+	publisher, err := session.AttachPublisher(role, remoteAddr, streamKey, conn, streams)
+	if err != nil {
+		log.Println("Rejecting", role, "publisher for session", key, err)
+		publishRejected.WithLabelValues("duplicate_publisher").Inc()
+		conn.Close()
+		return
 	}
 
+	log.Println("RTMP connection now active for session", key, role)
+
 	lastTime := time.Now()
 	for {
 		if session.End {
@@ -73,20 +190,11 @@ func rtmpConnectionHandler(conn *rtmp.Conn) {
 			lastTime = time.Now()
 		}
 
-		for _, destination := range session.Destinations {
-			destination.RTMP.WritePacket(packet)
-		}
+		session.WritePacket(publisher, packet)
 	}
 
-	session.ChangeState(false) // Mark inactive
-
-	for _, destination := range session.Destinations {
-		err := destination.RTMP.Disconnect()
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-	}
+	// Once the last publisher detaches the session is marked inactive and destinations disconnected
+	session.DetachPublisher(publisher)
 
 	if session.End {
 		fmt.Printf("Session %s ended\n", key)
@@ -98,5 +206,35 @@ func rtmpConnectionHandler(conn *rtmp.Conn) {
 		if err := sessions.DeleteSession(key); err != nil {
 			log.Println(err)
 		}
+
+		return
 	}
+
+	sessions.ReleaseAuthorizedSession(session)
+}
+
+// splitStreamPath finds the stream key and profile in rtmp://host/<app>/<key> or
+// rtmp://host/<app>/<key>/<profile>.  Apps can have more than one segment, like live/instance, so the last
+// segment is only a profile when the segment before it is a stream key and the streamer has a profile by
+// that name.  Otherwise the last segment is the key
+func splitStreamPath(path string) (key string, profile string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	key = segments[len(segments)-1]
+
+	if len(segments) < 3 {
+		return key, ""
+	}
+
+	candidate := segments[len(segments)-2]
+
+	streamer, _, err := streamers.ResolveStreamKey(strings.TrimSuffix(candidate, sessions.BackupKeySuffix))
+	if err != nil {
+		return key, ""
+	}
+
+	if _, ok := streamer.GetProfile(key); !ok {
+		return key, ""
+	}
+
+	return candidate, key
 }
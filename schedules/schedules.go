@@ -0,0 +1,372 @@
+package schedules
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	// Time zones have to work on hosts without a zoneinfo database
+	_ "time/tzdata"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/sessions"
+	"github.com/geekgonecrazy/prismplus/store"
+	"github.com/geekgonecrazy/prismplus/streamers"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// checkInterval is how often windows are checked for opening or closing
+	checkInterval = 5 * time.Second
+
+	maxDuration = 7 * 24 * time.Hour
+)
+
+var (
+	_dataStore store.Store
+
+	_lock      sync.RWMutex
+	_schedules []models.Schedule
+	// _open is whether each schedule's window was open at the last check, to spot hard stops
+	_open map[int]bool
+
+	ErrInvalid = errors.New("invalid schedule")
+)
+
+// Setup loads the schedules and starts pausing and resuming destinations as their windows open and close
+func Setup(dataStore store.Store) error {
+	_dataStore = dataStore
+
+	if err := reload(); err != nil {
+		return err
+	}
+
+	sessions.DestinationPaused = destinationPaused
+	streamers.SchedulesChanged = func() {
+		if err := reload(); err != nil {
+			log.Println("Failed to reload schedules:", err)
+		}
+	}
+
+	go func() {
+		for now := range time.Tick(checkInterval) {
+			apply(now)
+		}
+	}()
+
+	return nil
+}
+
+// GetSchedules returns the streamer's schedules with their current or next window
+func GetSchedules(streamerID int) []models.ScheduleState {
+	_lock.RLock()
+	defer _lock.RUnlock()
+
+	now := time.Now()
+
+	states := []models.ScheduleState{}
+	for _, schedule := range _schedules {
+		if schedule.StreamerID == streamerID {
+			states = append(states, State(schedule, now))
+		}
+	}
+
+	return states
+}
+
+// GetSchedule returns one of the streamer's schedules, store.ErrNotFound if it belongs to someone else
+func GetSchedule(streamerID int, id int) (models.Schedule, error) {
+	schedule, err := _dataStore.GetScheduleByID(id)
+	if err != nil {
+		return schedule, err
+	}
+
+	if schedule.StreamerID != streamerID {
+		return models.Schedule{}, store.ErrNotFound
+	}
+
+	return schedule, nil
+}
+
+func CreateSchedule(streamer models.Streamer, schedulePayload models.SchedulePayload) (*models.ScheduleState, error) {
+	schedule, err := newSchedule(streamer, schedulePayload)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := _dataStore.CreateSchedule(&schedule); err != nil {
+		return nil, err
+	}
+
+	return changed(schedule)
+}
+
+// UpdateSchedule replaces the schedule with the payload
+func UpdateSchedule(streamer models.Streamer, id int, schedulePayload models.SchedulePayload) (*models.ScheduleState, error) {
+	existing, err := GetSchedule(streamer.ID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := newSchedule(streamer, schedulePayload)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.ID = existing.ID
+	schedule.CreatedAt = existing.CreatedAt
+
+	if err := _dataStore.UpdateSchedule(&schedule); err != nil {
+		return nil, err
+	}
+
+	return changed(schedule)
+}
+
+func DeleteSchedule(streamer models.Streamer, id int) error {
+	if _, err := GetSchedule(streamer.ID, id); err != nil {
+		return err
+	}
+
+	if err := _dataStore.DeleteSchedule(id); err != nil {
+		return err
+	}
+
+	if err := reload(); err != nil {
+		return err
+	}
+
+	apply(time.Now())
+
+	return nil
+}
+
+// changed applies a new or updated schedule to the live sessions straight away
+func changed(schedule models.Schedule) (*models.ScheduleState, error) {
+	if err := reload(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	apply(now)
+
+	state := State(schedule, now)
+
+	return &state, nil
+}
+
+func reload() error {
+	schedules, err := _dataStore.GetSchedules()
+	if err != nil {
+		return err
+	}
+
+	_lock.Lock()
+	_schedules = schedules
+	_lock.Unlock()
+
+	return nil
+}
+
+// newSchedule checks the payload and builds a schedule for the streamer from it
+func newSchedule(streamer models.Streamer, schedulePayload models.SchedulePayload) (models.Schedule, error) {
+	schedule := models.Schedule{
+		StreamerID:      streamer.ID,
+		Name:            schedulePayload.Name,
+		Cron:            schedulePayload.Cron,
+		StartsAt:        schedulePayload.StartsAt,
+		DurationSeconds: schedulePayload.DurationSeconds,
+		TimeZone:        schedulePayload.TimeZone,
+		DestinationIDs:  schedulePayload.DestinationIDs,
+		HardStop:        schedulePayload.HardStop,
+	}
+
+	if schedule.TimeZone == "" {
+		schedule.TimeZone = "UTC"
+	}
+
+	if schedule.DestinationIDs == nil {
+		schedule.DestinationIDs = []int{}
+	}
+
+	if (schedule.Cron == "") == (schedule.StartsAt == nil) {
+		return schedule, fmt.Errorf("%w: set either cron for a recurring window or startsAt for a one-off window", ErrInvalid)
+	}
+
+	duration := time.Duration(schedule.DurationSeconds) * time.Second
+	if duration <= 0 || duration > maxDuration {
+		return schedule, fmt.Errorf("%w: durationSeconds must be between 1 and %d", ErrInvalid, int64(maxDuration/time.Second))
+	}
+
+	if _, _, err := parse(schedule); err != nil {
+		return schedule, fmt.Errorf("%w: %s", ErrInvalid, err)
+	}
+
+	if len(schedule.DestinationIDs) == 0 && !schedule.HardStop {
+		return schedule, fmt.Errorf("%w: a schedule needs destinationIds, hardStop or both", ErrInvalid)
+	}
+
+	for _, id := range schedule.DestinationIDs {
+		found := false
+		for _, destination := range streamer.Destinations {
+			if destination.ID == id {
+				found = true
+			}
+		}
+
+		if !found {
+			return schedule, fmt.Errorf("%w: destination %d doesn't exist", ErrInvalid, id)
+		}
+	}
+
+	return schedule, nil
+}
+
+// parse returns the schedule's time zone and for recurring schedules its cron schedule
+func parse(schedule models.Schedule) (cron.Schedule, *time.Location, error) {
+	location, err := time.LoadLocation(schedule.TimeZone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unknown timeZone %q", schedule.TimeZone)
+	}
+
+	if schedule.Cron == "" {
+		return nil, location, nil
+	}
+
+	cronSchedule, err := cron.ParseStandard(schedule.Cron)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cron: %s", err)
+	}
+
+	return cronSchedule, location, nil
+}
+
+// Window returns the window that is open at now, or the next one to open.  ok is false if there won't be another
+func Window(schedule models.Schedule, now time.Time) (window models.ScheduleWindow, ok bool) {
+	duration := time.Duration(schedule.DurationSeconds) * time.Second
+
+	cronSchedule, location, err := parse(schedule)
+	if err != nil {
+		return window, false
+	}
+
+	if schedule.StartsAt != nil {
+		window.OpensAt = schedule.StartsAt.In(location)
+		window.ClosesAt = window.OpensAt.Add(duration)
+
+		return window, window.ClosesAt.After(now)
+	}
+
+	// The first start after now - duration is either still open or the next one
+	window.OpensAt = cronSchedule.Next(now.Add(-duration).In(location))
+	if window.OpensAt.IsZero() {
+		return window, false
+	}
+
+	window.ClosesAt = window.OpensAt.Add(duration)
+
+	return window, true
+}
+
+// State returns the schedule with its current or next window
+func State(schedule models.Schedule, now time.Time) models.ScheduleState {
+	state := models.ScheduleState{Schedule: schedule}
+
+	window, ok := Window(schedule, now)
+	if ok {
+		state.Window = &window
+		state.Open = !now.Before(window.OpensAt)
+	}
+
+	return state
+}
+
+func isOpen(schedule models.Schedule, now time.Time) bool {
+	window, ok := Window(schedule, now)
+
+	return ok && !now.Before(window.OpensAt)
+}
+
+// paused reports whether the destination is in at least one schedule and none of them are open
+func paused(schedules []models.Schedule, open map[int]bool, streamerID int, destinationID int) bool {
+	scheduled := false
+
+	for _, schedule := range schedules {
+		if schedule.StreamerID != streamerID {
+			continue
+		}
+
+		for _, id := range schedule.DestinationIDs {
+			if id != destinationID {
+				continue
+			}
+
+			if open[schedule.ID] {
+				return false
+			}
+
+			scheduled = true
+		}
+	}
+
+	return scheduled
+}
+
+// destinationPaused decides whether a destination added to a session starts out paused
+func destinationPaused(streamerID int, destinationID int) bool {
+	_lock.RLock()
+	defer _lock.RUnlock()
+
+	now := time.Now()
+
+	open := map[int]bool{}
+	for _, schedule := range _schedules {
+		if schedule.StreamerID == streamerID {
+			open[schedule.ID] = isOpen(schedule, now)
+		}
+	}
+
+	return paused(_schedules, open, streamerID, destinationID)
+}
+
+// apply pauses and resumes destinations of the live sessions, and ends sessions whose hard stop has passed
+func apply(now time.Time) {
+	_lock.Lock()
+
+	schedules := _schedules
+
+	open := map[int]bool{}
+	stopped := map[int]bool{}
+	for _, schedule := range schedules {
+		open[schedule.ID] = isOpen(schedule, now)
+
+		if schedule.HardStop && _open[schedule.ID] && !open[schedule.ID] {
+			stopped[schedule.StreamerID] = true
+		}
+	}
+
+	_open = open
+
+	_lock.Unlock()
+
+	for _, session := range sessions.GetSessions() {
+		if session.StreamerID == 0 {
+			continue
+		}
+
+		if stopped[session.StreamerID] && session.Snapshot().Active {
+			log.Println("Ending session for streamer", session.StreamerID, "at its scheduled hard stop")
+			session.EndSession()
+			continue
+		}
+
+		for _, destination := range session.GetDestinations() {
+			if err := session.SetDestinationPaused(destination.ID, paused(schedules, open, session.StreamerID, destination.ID)); err != nil && !errors.Is(err, sessions.ErrNotFound) {
+				log.Println("Error applying schedule:", err)
+			}
+		}
+	}
+}
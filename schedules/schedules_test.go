@@ -0,0 +1,178 @@
+package schedules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store/memstore"
+	"github.com/geekgonecrazy/prismplus/streamers"
+)
+
+func at(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+func TestWindow(t *testing.T) {
+	startsAt := at("2026-03-10T18:00:00Z")
+
+	tests := []struct {
+		name     string
+		schedule models.Schedule
+		now      string
+		open     bool
+		// opensAt is the window expected at now, empty if there shouldn't be one
+		opensAt string
+	}{
+		{"one-off before", models.Schedule{StartsAt: &startsAt, DurationSeconds: 3600, TimeZone: "UTC"}, "2026-03-10T17:59:59Z", false, "2026-03-10T18:00:00Z"},
+		{"one-off opens", models.Schedule{StartsAt: &startsAt, DurationSeconds: 3600, TimeZone: "UTC"}, "2026-03-10T18:00:00Z", true, "2026-03-10T18:00:00Z"},
+		{"one-off closes", models.Schedule{StartsAt: &startsAt, DurationSeconds: 3600, TimeZone: "UTC"}, "2026-03-10T19:00:00Z", false, ""},
+		{"one-off after", models.Schedule{StartsAt: &startsAt, DurationSeconds: 3600, TimeZone: "UTC"}, "2026-03-11T18:30:00Z", false, ""},
+
+		{"daily before", models.Schedule{Cron: "0 9 * * *", DurationSeconds: 7200, TimeZone: "UTC"}, "2026-03-10T08:59:00Z", false, "2026-03-10T09:00:00Z"},
+		{"daily opens", models.Schedule{Cron: "0 9 * * *", DurationSeconds: 7200, TimeZone: "UTC"}, "2026-03-10T09:00:00Z", true, "2026-03-10T09:00:00Z"},
+		{"daily open", models.Schedule{Cron: "0 9 * * *", DurationSeconds: 7200, TimeZone: "UTC"}, "2026-03-10T10:59:59Z", true, "2026-03-10T09:00:00Z"},
+		{"daily closes", models.Schedule{Cron: "0 9 * * *", DurationSeconds: 7200, TimeZone: "UTC"}, "2026-03-10T11:00:00Z", false, "2026-03-11T09:00:00Z"},
+
+		{"across midnight before", models.Schedule{Cron: "0 23 * * *", DurationSeconds: 7200, TimeZone: "UTC"}, "2026-03-10T22:59:00Z", false, "2026-03-10T23:00:00Z"},
+		{"across midnight late", models.Schedule{Cron: "0 23 * * *", DurationSeconds: 7200, TimeZone: "UTC"}, "2026-03-10T23:30:00Z", true, "2026-03-10T23:00:00Z"},
+		{"across midnight early", models.Schedule{Cron: "0 23 * * *", DurationSeconds: 7200, TimeZone: "UTC"}, "2026-03-11T00:30:00Z", true, "2026-03-10T23:00:00Z"},
+		{"across midnight closes", models.Schedule{Cron: "0 23 * * *", DurationSeconds: 7200, TimeZone: "UTC"}, "2026-03-11T01:00:00Z", false, "2026-03-11T23:00:00Z"},
+		{"weekdays across the weekend", models.Schedule{Cron: "0 23 * * 1-5", DurationSeconds: 7200, TimeZone: "UTC"}, "2026-03-14T00:30:00Z", true, "2026-03-13T23:00:00Z"},
+		{"weekdays skip the weekend", models.Schedule{Cron: "0 23 * * 1-5", DurationSeconds: 7200, TimeZone: "UTC"}, "2026-03-14T12:00:00Z", false, "2026-03-16T23:00:00Z"},
+
+		{"time zone open", models.Schedule{Cron: "0 9 * * *", DurationSeconds: 3600, TimeZone: "America/New_York"}, "2026-07-10T13:30:00Z", true, "2026-07-10T13:00:00Z"},
+		{"time zone isn't utc", models.Schedule{Cron: "0 9 * * *", DurationSeconds: 3600, TimeZone: "America/New_York"}, "2026-07-10T09:30:00Z", false, "2026-07-10T13:00:00Z"},
+		{"time zone standard time", models.Schedule{Cron: "0 9 * * *", DurationSeconds: 3600, TimeZone: "America/New_York"}, "2026-01-10T14:30:00Z", true, "2026-01-10T14:00:00Z"},
+		{"time zone after daylight saving starts", models.Schedule{Cron: "0 9 * * *", DurationSeconds: 3600, TimeZone: "America/New_York"}, "2026-03-09T13:30:00Z", true, "2026-03-09T13:00:00Z"},
+		{"time zone across midnight late", models.Schedule{Cron: "0 23 * * *", DurationSeconds: 7200, TimeZone: "Asia/Tokyo"}, "2026-03-10T14:30:00Z", true, "2026-03-10T14:00:00Z"},
+		{"time zone across midnight early", models.Schedule{Cron: "0 23 * * *", DurationSeconds: 7200, TimeZone: "Asia/Tokyo"}, "2026-03-10T15:30:00Z", true, "2026-03-10T14:00:00Z"},
+		{"time zone across midnight closes", models.Schedule{Cron: "0 23 * * *", DurationSeconds: 7200, TimeZone: "Asia/Tokyo"}, "2026-03-10T16:00:00Z", false, "2026-03-11T14:00:00Z"},
+
+		{"unknown time zone", models.Schedule{Cron: "0 9 * * *", DurationSeconds: 3600, TimeZone: "Mars/Olympus_Mons"}, "2026-03-10T09:30:00Z", false, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			now := at(test.now)
+
+			if open := isOpen(test.schedule, now); open != test.open {
+				t.Errorf("isOpen = %v, want %v", open, test.open)
+			}
+
+			state := State(test.schedule, now)
+			if state.Open != test.open {
+				t.Errorf("State().Open = %v, want %v", state.Open, test.open)
+			}
+
+			window, ok := Window(test.schedule, now)
+			if test.opensAt == "" {
+				if ok {
+					t.Errorf("Window = %v, want none", window)
+				}
+
+				return
+			}
+
+			if !ok {
+				t.Fatalf("Window = none, want one opening at %s", test.opensAt)
+			}
+
+			if !window.OpensAt.Equal(at(test.opensAt)) {
+				t.Errorf("OpensAt = %s, want %s", window.OpensAt.UTC().Format(time.RFC3339), test.opensAt)
+			}
+
+			if closesAt := at(test.opensAt).Add(time.Duration(test.schedule.DurationSeconds) * time.Second); !window.ClosesAt.Equal(closesAt) {
+				t.Errorf("ClosesAt = %s, want %s", window.ClosesAt.UTC().Format(time.RFC3339), closesAt.Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func TestPaused(t *testing.T) {
+	schedules := []models.Schedule{
+		{ID: 1, StreamerID: 1, DestinationIDs: []int{1, 2}},
+		{ID: 2, StreamerID: 1, DestinationIDs: []int{2}},
+		{ID: 3, StreamerID: 2, DestinationIDs: []int{3}},
+	}
+
+	tests := []struct {
+		name          string
+		open          map[int]bool
+		streamerID    int
+		destinationID int
+		paused        bool
+	}{
+		{"no windows open", map[int]bool{}, 1, 1, true},
+		{"its window open", map[int]bool{1: true}, 1, 1, false},
+		{"one of its windows open", map[int]bool{2: true}, 1, 2, false},
+		{"another destination's window open", map[int]bool{2: true}, 1, 1, true},
+		{"not in a schedule", map[int]bool{}, 1, 4, false},
+		{"another streamer's schedule", map[int]bool{}, 2, 1, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := paused(schedules, test.open, test.streamerID, test.destinationID); got != test.paused {
+				t.Errorf("paused = %v, want %v", got, test.paused)
+			}
+		})
+	}
+}
+
+func TestRemovedDestinationsArePruned(t *testing.T) {
+	dataStore := memstore.New()
+	streamers.Setup(dataStore)
+
+	if err := Setup(dataStore); err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := streamers.CreateStreamer(models.StreamerCreatePayload{Name: "scheduled", StreamKey: "scheduled-stream-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"Twitch", "YouTube"} {
+		if err := streamers.AddDestination(*created, models.Destination{Name: name, Server: "rtmp://127.0.0.1/app", Key: name}); err != nil {
+			t.Fatal(err)
+		}
+
+		if *created, err = streamers.GetStreamer(created.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	twitch, youtube := created.Destinations[0].ID, created.Destinations[1].ID
+
+	if _, err := CreateSchedule(*created, models.SchedulePayload{Cron: "0 9 * * *", DurationSeconds: 3600, DestinationIDs: []int{twitch, youtube}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := streamers.RemoveDestination(*created, twitch); err != nil {
+		t.Fatal(err)
+	}
+
+	states := GetSchedules(created.ID)
+	if len(states) != 1 {
+		t.Fatalf("got %d schedules, want 1", len(states))
+	}
+
+	if ids := states[0].DestinationIDs; len(ids) != 1 || ids[0] != youtube {
+		t.Errorf("DestinationIDs = %v, want [%d]", ids, youtube)
+	}
+
+	stored, err := dataStore.GetSchedules()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := stored[0].DestinationIDs; len(ids) != 1 || ids[0] != youtube {
+		t.Errorf("stored DestinationIDs = %v, want [%d]", ids, youtube)
+	}
+}
@@ -0,0 +1,161 @@
+package sessions
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+)
+
+// expiryInterval is how often ad-hoc sessions are checked for expiry
+const expiryInterval = time.Minute
+
+// AdHocSessionTTL is how long an ad-hoc session can go without being published to or changed before it
+// is removed.  0 keeps them until they're ended
+var AdHocSessionTTL time.Duration
+
+// CreateAdHocSession creates a session through the api.  Sessions without a streamer are saved so they
+// come back after a restart
+func CreateAdHocSession(sessionPayload models.SessionPayload) error {
+	session, err := CreateAndGetSession(sessionPayload)
+	if err != nil {
+		return err
+	}
+
+	if session.StreamerID != 0 {
+		return nil
+	}
+
+	session._lock.Lock()
+	session.AdHoc = true
+	session.createdAt = time.Now()
+	session.LastActiveAt = session.createdAt
+	err = session.save()
+	session._lock.Unlock()
+
+	if err != nil {
+		DeleteSession(session.Key)
+		return err
+	}
+
+	return nil
+}
+
+// touch records activity on an ad-hoc session and saves it.  Caller must hold the lock
+func (s *Session) touch() {
+	if !s.AdHoc || s.End {
+		return
+	}
+
+	s.LastActiveAt = time.Now()
+
+	if err := s.save(); err != nil {
+		log.Println("Error saving ad-hoc session:", err)
+	}
+}
+
+// save writes the ad-hoc session to the data store.  Caller must hold the lock
+func (s *Session) save() error {
+	stored := models.AdHocSession{
+		Key:                      s.Key,
+		Destinations:             []models.Destination{},
+		NextDestinationID:        s.NextDestinationID,
+		DuplicatePublisherPolicy: s.DuplicatePublisherPolicy,
+		CreatedAt:                s.createdAt,
+		LastActiveAt:             s.LastActiveAt,
+	}
+
+	for _, destination := range s.Destinations {
+		stored.Destinations = append(stored.Destinations, models.Destination{
+			ID:     destination.ID,
+			Name:   destination.Name,
+			Server: destination.Server,
+			Key:    destination.Key,
+		})
+	}
+
+	sort.Slice(stored.Destinations, func(i, j int) bool {
+		return stored.Destinations[i].ID < stored.Destinations[j].ID
+	})
+
+	return _dataStore.SetAdHocSession(&stored)
+}
+
+// forget removes an ad-hoc session from the data store.  Caller must hold the lock
+func (s *Session) forget() {
+	if !s.AdHoc {
+		return
+	}
+
+	if err := _dataStore.DeleteAdHocSession(s.Key); err != nil {
+		log.Println("Error removing ad-hoc session:", err)
+	}
+}
+
+// restoreAdHocSessions brings back the ad-hoc sessions saved before the last restart and starts expiring them
+func restoreAdHocSessions() error {
+	stored, err := _dataStore.GetAdHocSessions()
+	if err != nil {
+		return err
+	}
+
+	_sessionsLock.Lock()
+	for _, adHocSession := range stored {
+		session := &Session{
+			ID:                       sessionID(adHocSession.Key),
+			Key:                      adHocSession.Key,
+			Destinations:             map[int]*Destination{},
+			NextDestinationID:        adHocSession.NextDestinationID,
+			Publishers:               map[string]*Publisher{},
+			DuplicatePublisherPolicy: adHocSession.DuplicatePublisherPolicy,
+			Events:                   []SessionEvent{},
+			AdHoc:                    true,
+			LastActiveAt:             adHocSession.LastActiveAt,
+			createdAt:                adHocSession.CreatedAt,
+		}
+
+		for _, destination := range adHocSession.Destinations {
+			session.addDestination(destination)
+		}
+
+		_sessions[session.Key] = session
+	}
+	_sessionsLock.Unlock()
+
+	if len(stored) > 0 {
+		log.Println("Restored", len(stored), "ad-hoc sessions")
+	}
+
+	if AdHocSessionTTL > 0 {
+		go expireAdHocSessions()
+	}
+
+	return nil
+}
+
+func expireAdHocSessions() {
+	for {
+		removeExpiredSessions(time.Now())
+		time.Sleep(expiryInterval)
+	}
+}
+
+// removeExpiredSessions removes ad-hoc sessions that have been idle for longer than AdHocSessionTTL
+func removeExpiredSessions(now time.Time) {
+	_sessionsLock.Lock()
+	defer _sessionsLock.Unlock()
+
+	for key, session := range _sessions {
+		session._lock.Lock()
+
+		if session.AdHoc && !session.Active && now.Sub(session.LastActiveAt) > AdHocSessionTTL {
+			log.Println("Removing ad-hoc session", key, "unused since", session.LastActiveAt.Format(time.RFC3339))
+
+			session.forget()
+			delete(_sessions, key)
+		}
+
+		session._lock.Unlock()
+	}
+}
@@ -0,0 +1,101 @@
+package sessions
+
+import (
+	"log"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/events"
+	"github.com/geekgonecrazy/prismplus/models"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// broadcast tracks the stats for the session while it is live
+type broadcast struct {
+	startedAt time.Time
+	ingest    models.IngestStats
+
+	bitrate      int64
+	bitrateAt    time.Time
+	bitrateBytes int64
+
+	// Destinations removed while live still belong in the report
+	removed []models.DestinationHistory
+}
+
+func destinationHistory(destination *Destination) models.DestinationHistory {
+	stats := destination.RTMP.Stats()
+
+	return models.DestinationHistory{
+		ID:            destination.ID,
+		Name:          destination.Name,
+		Server:        destination.Server,
+		UptimeSeconds: int64(stats.Uptime / time.Second),
+		Reconnects:    stats.Reconnects,
+		BytesSent:     stats.BytesSent,
+		PacketsSent:   stats.PacketsSent,
+		Dropped:       stats.Dropped,
+	}
+}
+
+// averageBitrate is in bits per second
+func averageBitrate(bytes int64, duration time.Duration) int64 {
+	if duration <= 0 {
+		return 0
+	}
+
+	return int64(float64(bytes*8) / duration.Seconds())
+}
+
+// recordHistory stores the report for the broadcast that just ended.  Caller must hold the lock and
+// call it before the destinations are disconnected
+func (s *Session) recordHistory() {
+	if s.broadcast == nil {
+		return
+	}
+
+	endedAt := time.Now()
+	duration := endedAt.Sub(s.broadcast.startedAt)
+
+	history := models.SessionHistory{
+		StreamerID:      s.StreamerID,
+		Key:             s.publicKey(),
+		StartedAt:       s.broadcast.startedAt,
+		EndedAt:         endedAt,
+		DurationSeconds: int64(duration / time.Second),
+		Ingest:          s.broadcast.ingest,
+		Destinations:    append([]models.DestinationHistory{}, s.broadcast.removed...),
+	}
+
+	history.Ingest.AverageBitrate = averageBitrate(history.Ingest.Bytes, duration)
+
+	for _, destination := range s.Destinations {
+		history.Destinations = append(history.Destinations, destinationHistory(destination))
+	}
+
+	s.broadcast = nil
+
+	if _dataStore != nil {
+		if err := _dataStore.CreateSessionHistory(&history); err != nil {
+			log.Println("Error saving session history:", err)
+		}
+	}
+
+	events.Publish(events.SessionEnded, s.StreamerID, history)
+}
+
+// GetHistory returns a page of finished broadcasts, newest first
+func GetHistory(query models.SessionHistoryQuery) (models.SessionHistoryPage, error) {
+	if query.Limit <= 0 {
+		query.Limit = defaultHistoryLimit
+	}
+
+	if query.Limit > maxHistoryLimit {
+		query.Limit = maxHistoryLimit
+	}
+
+	return _dataStore.GetSessionHistory(query)
+}
@@ -0,0 +1,407 @@
+package sessions
+
+import (
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/events"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/rtmp-lib/av"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	PublisherPrimary = "primary"
+	PublisherBackup  = "backup"
+
+	// BackupKeySuffix is appended to the stream key by a backup encoder
+	BackupKeySuffix = "-backup"
+
+	// switchTimestampGap is roughly one frame at 30fps and keeps timestamps increasing across a switch
+	switchTimestampGap = 33 * time.Millisecond
+
+	maxSessionEvents = 100
+)
+
+var (
+	// FailoverTimeout is how long the forwarded publisher can go without sending packets before we switch
+	FailoverTimeout = 3 * time.Second
+
+	ErrPublisherExists = errors.New("publisher already connected")
+
+	droppedPackets = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prismplus_ingest_dropped_packets_total",
+		Help: "Ingest packets that weren't forwarded",
+	}, []string{"reason"})
+)
+
+type Publisher struct {
+	Role       string `json:"role"`
+	RemoteAddr string `json:"remoteAddr"`
+	// StreamKey is the name of the extra stream key the publisher used, empty for the streamer's own key
+	StreamKey    string    `json:"streamKey"`
+	ConnectedAt  time.Time `json:"connectedAt"`
+	LastPacketAt time.Time `json:"lastPacketAt"`
+
+	conn        io.Closer
+	streamKeyID int
+}
+
+type SessionEvent struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Message string    `json:"message"`
+}
+
+func otherRole(role string) string {
+	if role == PublisherPrimary {
+		return PublisherBackup
+	}
+
+	return PublisherPrimary
+}
+
+func (s *Session) recordEvent(eventType string, message string) {
+	log.Printf("Session %s: %s", s.Key, message)
+
+	s.Events = append(s.Events, SessionEvent{
+		Time:    time.Now(),
+		Type:    eventType,
+		Message: message,
+	})
+
+	if len(s.Events) > maxSessionEvents {
+		s.Events = s.Events[len(s.Events)-maxSessionEvents:]
+	}
+}
+
+// AttachPublisher registers an incoming publisher on the session.  The first publisher to attach
+// takes the session live and connects the destinations using its stream headers.  If the role is already
+// taken the session's duplicate publisher policy decides whether the newcomer is rejected or takes over.
+func (s *Session) AttachPublisher(role string, remoteAddr string, streamKey *models.StreamKey, conn io.Closer, streams []av.CodecData) (*Publisher, error) {
+	s._lock.Lock()
+	defer s._lock.Unlock()
+
+	publisher := &Publisher{
+		Role:        role,
+		RemoteAddr:  remoteAddr,
+		ConnectedAt: time.Now(),
+		conn:        conn,
+	}
+
+	if streamKey != nil {
+		publisher.StreamKey = streamKey.Name
+		publisher.streamKeyID = streamKey.ID
+	}
+
+	existing := s.Publishers[role]
+	if existing != nil {
+		if s.DuplicatePublisherPolicy != models.DuplicatePublisherTakeover {
+			s.recordEvent("publisher_rejected", "rejected duplicate "+role+" publisher from "+remoteAddr)
+			return nil, ErrPublisherExists
+		}
+
+		// Swap in the newcomer before closing the old connection so its read loop detaches as a no-op
+		s.Publishers[role] = publisher
+		s.recordEvent("publisher_takeover", role+" publisher from "+remoteAddr+" took over from "+existing.RemoteAddr)
+
+		if s.broadcast != nil {
+			s.broadcast.ingest.PublisherConnects++
+		}
+
+		if err := existing.conn.Close(); err != nil {
+			log.Println(err)
+		}
+
+		// The new encoder starts its own timeline, so resume forwarding at its first keyframe.  It may also
+		// have a different codec config, so the destinations reconnect with its headers
+		switch {
+		case s.ActivePublisher == role:
+			s.setActivePublisher("")
+			s.pendingPublisher = role
+			s.pendingFrom = ""
+			s.refreshHeaders(streams)
+		case s.pendingPublisher == role:
+			s.refreshHeaders(streams)
+		}
+
+		return publisher, nil
+	}
+
+	s.Publishers[role] = publisher
+	if streamKey != nil {
+		s.recordEvent("publisher_connected", role+" publisher connected from "+remoteAddr+" with stream key "+streamKey.Name)
+	} else {
+		s.recordEvent("publisher_connected", role+" publisher connected from "+remoteAddr)
+	}
+
+	if !s.Active {
+		s.Active = true
+		s.StreamHeaders = streams
+		s.setActivePublisher("")
+		s.pendingPublisher = ""
+		s.lastForwarded = 0
+		s.broadcast = &broadcast{startedAt: publisher.ConnectedAt, bitrateAt: publisher.ConnectedAt}
+
+		events.Publish(events.SessionStarted, s.StreamerID, models.SessionStartedEvent{
+			Session:   s.publicKey(),
+			Publisher: role,
+			StartedAt: publisher.ConnectedAt,
+		})
+
+		for _, destination := range s.Destinations {
+			// Still connecting from the last broadcast, it carries on into this one
+			if destination.Paused || destination.connecting {
+				continue
+			}
+
+			s.connect(destination)
+		}
+
+		if !s.watching {
+			s.watching = true
+			go s.watchIngest()
+		}
+
+		s.touch()
+	}
+
+	s.broadcast.ingest.PublisherConnects++
+
+	return publisher, nil
+}
+
+// refreshHeaders switches the session to a new encoder's stream headers and reconnects the destinations
+// with them.  Caller must hold the lock
+func (s *Session) refreshHeaders(streams []av.CodecData) {
+	s.StreamHeaders = streams
+
+	for _, destination := range s.Destinations {
+		if !destination.forwarding && !destination.awaitingKeyframe {
+			continue
+		}
+
+		s.disconnectDestination(destination)
+		s.connect(destination)
+	}
+}
+
+// DisconnectStreamKey closes the connections of publishers that used a stream key which has been revoked.
+// Their read loops then detach them as usual
+func (s *Session) DisconnectStreamKey(id int) {
+	s._lock.Lock()
+	defer s._lock.Unlock()
+
+	for _, publisher := range s.Publishers {
+		if publisher.streamKeyID != id {
+			continue
+		}
+
+		s.recordEvent("publisher_revoked", publisher.Role+" publisher from "+publisher.RemoteAddr+" disconnected, stream key "+publisher.StreamKey+" was revoked")
+
+		if err := publisher.conn.Close(); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// DetachPublisher removes the publisher from the session.  If it was the one being forwarded the
+// other publisher takes over at its next keyframe.  Once no publishers remain the session goes inactive.
+func (s *Session) DetachPublisher(publisher *Publisher) {
+	s._lock.Lock()
+	defer s._lock.Unlock()
+
+	if s.Publishers[publisher.Role] != publisher {
+		return
+	}
+
+	delete(s.Publishers, publisher.Role)
+	s.recordEvent("publisher_disconnected", publisher.Role+" publisher disconnected")
+
+	if s.ActivePublisher == publisher.Role {
+		s.switchTo(otherRole(publisher.Role), "disconnected")
+		s.setActivePublisher("")
+	}
+
+	if s.pendingPublisher == publisher.Role {
+		s.pendingPublisher = ""
+	}
+
+	if len(s.Publishers) > 0 {
+		return
+	}
+
+	s.Active = false
+	s.setActivePublisher("")
+	s.pendingPublisher = ""
+
+	s.recordHistory()
+
+	for _, destination := range s.Destinations {
+		s.disconnectDestination(destination)
+	}
+
+	s.touch()
+}
+
+// setActivePublisher changes which publisher is forwarded.  Caller must hold the lock
+func (s *Session) setActivePublisher(role string) {
+	s.ActivePublisher = role
+	s.ActiveRemoteAddr = ""
+
+	if publisher := s.Publishers[role]; publisher != nil {
+		s.ActiveRemoteAddr = publisher.RemoteAddr
+	}
+}
+
+// switchTo schedules a switch to the given publisher at its next keyframe.  Caller must hold the lock
+func (s *Session) switchTo(role string, reason string) {
+	if s.Publishers[role] == nil || s.pendingPublisher == role {
+		return
+	}
+
+	s.pendingPublisher = role
+	s.pendingFrom = s.ActivePublisher
+	s.recordEvent("failover_pending", "switching to "+role+" publisher at next keyframe: "+reason)
+}
+
+func (s *Session) hasVideo() bool {
+	for _, stream := range s.StreamHeaders {
+		if stream.Type().IsVideo() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WritePacket forwards a packet from the publisher to the destinations if that publisher is the one
+// currently being forwarded.  Packets from the standby publisher are dropped.
+func (s *Session) WritePacket(publisher *Publisher, packet av.Packet) {
+	s._lock.Lock()
+
+	publisher.LastPacketAt = time.Now()
+
+	switch {
+	case s.ActivePublisher == publisher.Role:
+		if publisher.Role == PublisherPrimary {
+			// Primary recovered before the backup reached a keyframe
+			s.pendingPublisher = ""
+			break
+		}
+
+		if s.Publishers[PublisherPrimary] == nil {
+			break
+		}
+
+		// Primary is back and healthy, move back to it at its next keyframe
+		if time.Since(s.Publishers[PublisherPrimary].LastPacketAt) < FailoverTimeout {
+			s.switchTo(PublisherPrimary, "primary restored")
+		}
+	case s.pendingPublisher == publisher.Role:
+		if !packet.IsKeyFrame && s.hasVideo() {
+			s._lock.Unlock()
+			droppedPackets.WithLabelValues("awaiting_keyframe").Inc()
+			return
+		}
+
+		previous := s.pendingFrom
+
+		s.setActivePublisher(publisher.Role)
+		s.pendingPublisher = ""
+		s.pendingFrom = ""
+
+		if s.lastForwarded > 0 {
+			s.timeOffset = s.lastForwarded + switchTimestampGap - packet.Time
+		} else {
+			s.timeOffset = 0
+		}
+
+		if previous != "" {
+			s.recordEvent("failover", "switched from "+previous+" to "+publisher.Role+" publisher")
+			if s.broadcast != nil {
+				s.broadcast.ingest.Failovers++
+			}
+		}
+	case s.ActivePublisher == "" && s.pendingPublisher == "":
+		s.setActivePublisher(publisher.Role)
+		s.timeOffset = 0
+	default:
+		s._lock.Unlock()
+		droppedPackets.WithLabelValues("standby").Inc()
+		return
+	}
+
+	packet.Time += s.timeOffset
+	s.lastForwarded = packet.Time
+
+	if s.broadcast != nil {
+		s.broadcast.ingest.Packets++
+		s.broadcast.ingest.Bytes += int64(len(packet.Data))
+		if packet.IsKeyFrame {
+			s.broadcast.ingest.Keyframes++
+		}
+	}
+
+	// Scheduled destinations start and stop at keyframes so they never get a partial group of pictures
+	keyframe := packet.IsKeyFrame || !s.hasVideo()
+
+	destinations := make([]*Destination, 0, len(s.Destinations))
+	for _, destination := range s.Destinations {
+		if keyframe && destination.awaitingKeyframe {
+			destination.awaitingKeyframe = false
+			destination.forwarding = true
+		}
+
+		if keyframe && destination.detachAtKeyframe {
+			s.disconnectDestination(destination)
+		}
+
+		if destination.forwarding {
+			destinations = append(destinations, destination)
+		}
+	}
+
+	s._lock.Unlock()
+
+	for _, destination := range destinations {
+		destination.RTMP.WritePacket(packet)
+	}
+}
+
+// watchIngest switches away from a stalled publisher and publishes stats while the session is active
+func (s *Session) watchIngest() {
+	ticker := time.NewTicker(FailoverTimeout / 4)
+	defer ticker.Stop()
+
+	lastStats := time.Now()
+
+	for now := range ticker.C {
+		s._lock.Lock()
+
+		if !s.Active {
+			s.watching = false
+			s._lock.Unlock()
+			return
+		}
+
+		active := s.Publishers[s.ActivePublisher]
+		if active != nil && time.Since(active.LastPacketAt) > FailoverTimeout {
+			standby := s.Publishers[otherRole(active.Role)]
+			if standby != nil && time.Since(standby.LastPacketAt) < FailoverTimeout {
+				s.switchTo(standby.Role, active.Role+" publisher stalled")
+			}
+		}
+
+		if s.broadcast != nil && now.Sub(lastStats) >= statsInterval {
+			lastStats = now
+			s.updateBitrate(now)
+			events.Publish(events.SessionStats, s.StreamerID, s.stats())
+		}
+
+		s._lock.Unlock()
+	}
+}
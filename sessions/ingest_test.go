@@ -0,0 +1,237 @@
+package sessions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/rtmp-lib/av"
+)
+
+type fakeCodec struct {
+	codecType av.CodecType
+}
+
+func (c fakeCodec) Type() av.CodecType {
+	return c.codecType
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+var videoStreams = []av.CodecData{fakeCodec{av.H264}, fakeCodec{av.AAC}}
+
+func newTestSession(t *testing.T) (*Session, *Publisher, *Publisher) {
+	t.Helper()
+
+	s := &Session{
+		Key:          "test-stream-key",
+		Destinations: map[int]*Destination{},
+		Publishers:   map[string]*Publisher{},
+		Events:       []SessionEvent{},
+	}
+
+	primary, err := s.AttachPublisher(PublisherPrimary, "127.0.0.1:1000", nil, nopCloser{}, videoStreams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := s.AttachPublisher(PublisherBackup, "127.0.0.1:2000", nil, nopCloser{}, videoStreams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		s.DetachPublisher(primary)
+		s.DetachPublisher(backup)
+	})
+
+	return s, primary, backup
+}
+
+func packet(keyframe bool, at time.Duration) av.Packet {
+	return av.Packet{IsKeyFrame: keyframe, Time: at, Data: []byte{0}}
+}
+
+func forwarded(s *Session) (active string, packets int64, last time.Duration) {
+	s._lock.Lock()
+	defer s._lock.Unlock()
+
+	return s.ActivePublisher, s.broadcast.ingest.Packets, s.lastForwarded
+}
+
+func TestFailoverWaitsForKeyframe(t *testing.T) {
+	s, primary, backup := newTestSession(t)
+
+	s.WritePacket(primary, packet(true, 0))
+	s.WritePacket(backup, packet(true, 0))
+
+	if active, packets, _ := forwarded(s); active != PublisherPrimary || packets != 1 {
+		t.Fatalf("got %s forwarding %d packets, want primary forwarding 1 and the backup dropped", active, packets)
+	}
+
+	s.DetachPublisher(primary)
+
+	s.WritePacket(backup, packet(false, 33*time.Millisecond))
+	s.WritePacket(backup, packet(false, 66*time.Millisecond))
+
+	if active, packets, _ := forwarded(s); active != "" || packets != 1 {
+		t.Fatalf("got %q forwarding %d packets, want nothing forwarded until the backup's keyframe", active, packets)
+	}
+
+	s.WritePacket(backup, packet(true, 100*time.Millisecond))
+
+	if active, packets, _ := forwarded(s); active != PublisherBackup || packets != 2 {
+		t.Fatalf("got %q forwarding %d packets, want the backup forwarded from its keyframe", active, packets)
+	}
+
+	if stats, _ := s.Stats(); stats.Ingest.Failovers != 1 {
+		t.Errorf("got %d failovers, want 1", stats.Ingest.Failovers)
+	}
+}
+
+func TestFailoverAudioOnlyDoesNotWaitForKeyframe(t *testing.T) {
+	s := &Session{
+		Destinations: map[int]*Destination{},
+		Publishers:   map[string]*Publisher{},
+		Events:       []SessionEvent{},
+	}
+
+	audio := []av.CodecData{fakeCodec{av.AAC}}
+
+	primary, err := s.AttachPublisher(PublisherPrimary, "127.0.0.1:1000", nil, nopCloser{}, audio)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := s.AttachPublisher(PublisherBackup, "127.0.0.1:2000", nil, nopCloser{}, audio)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.DetachPublisher(backup)
+
+	s.WritePacket(primary, packet(false, 0))
+	s.DetachPublisher(primary)
+	s.WritePacket(backup, packet(false, 0))
+
+	if active, packets, _ := forwarded(s); active != PublisherBackup || packets != 2 {
+		t.Fatalf("got %q forwarding %d packets, want the backup forwarded straight away", active, packets)
+	}
+}
+
+func TestFailoverTimestampOffset(t *testing.T) {
+	tests := []struct {
+		name string
+		// primaryEnd is the last timestamp forwarded from the primary, backupStart the backup's keyframe
+		primaryEnd  time.Duration
+		backupStart time.Duration
+	}{
+		{"backup behind primary", 10 * time.Second, 2 * time.Second},
+		{"backup ahead of primary", 2 * time.Second, 10 * time.Second},
+		{"backup at the same time", 5 * time.Second, 5 * time.Second},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			s, primary, backup := newTestSession(t)
+
+			s.WritePacket(primary, packet(true, 0))
+			s.WritePacket(primary, packet(false, test.primaryEnd))
+
+			s.DetachPublisher(primary)
+
+			s.WritePacket(backup, packet(true, test.backupStart))
+
+			want := test.primaryEnd + switchTimestampGap
+			if _, _, last := forwarded(s); last != want {
+				t.Fatalf("backup keyframe forwarded at %s, want %s", last, want)
+			}
+
+			s.WritePacket(backup, packet(false, test.backupStart+33*time.Millisecond))
+
+			if _, _, last := forwarded(s); last != want+33*time.Millisecond {
+				t.Errorf("next backup packet forwarded at %s, want %s", last, want+33*time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestFailoverBackToPrimary(t *testing.T) {
+	s, primary, backup := newTestSession(t)
+
+	s.WritePacket(primary, packet(true, 0))
+	s.DetachPublisher(primary)
+	s.WritePacket(backup, packet(true, time.Second))
+
+	restored, err := s.AttachPublisher(PublisherPrimary, "127.0.0.1:3000", nil, nopCloser{}, videoStreams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.DetachPublisher(restored)
+
+	// The restored primary is healthy once it sends, and the backup asks to switch back
+	s.WritePacket(restored, packet(false, 0))
+	s.WritePacket(backup, packet(false, time.Second+33*time.Millisecond))
+
+	if active, _, _ := forwarded(s); active != PublisherBackup {
+		t.Fatalf("got %q, want the backup until the primary's keyframe", active)
+	}
+
+	s.WritePacket(restored, packet(true, 66*time.Millisecond))
+
+	if active, _, last := forwarded(s); active != PublisherPrimary || last != time.Second+33*time.Millisecond+switchTimestampGap {
+		t.Fatalf("got %q at %s, want the primary at %s", active, last, time.Second+33*time.Millisecond+switchTimestampGap)
+	}
+}
+
+func TestTakeoverRefreshesHeaders(t *testing.T) {
+	s := &Session{
+		Destinations:             map[int]*Destination{},
+		Publishers:               map[string]*Publisher{},
+		Events:                   []SessionEvent{},
+		DuplicatePublisherPolicy: models.DuplicatePublisherTakeover,
+	}
+
+	old, err := s.AttachPublisher(PublisherPrimary, "127.0.0.1:1000", nil, nopCloser{}, videoStreams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.WritePacket(old, packet(true, 0))
+
+	reconnected := []av.CodecData{fakeCodec{av.H264}}
+
+	publisher, err := s.AttachPublisher(PublisherPrimary, "127.0.0.1:2000", nil, nopCloser{}, reconnected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.DetachPublisher(publisher)
+
+	s._lock.Lock()
+	headers := s.StreamHeaders
+	s._lock.Unlock()
+
+	if len(headers) != len(reconnected) || headers[0] != reconnected[0] {
+		t.Fatalf("got headers %v, want the new encoder's %v", headers, reconnected)
+	}
+
+	s.WritePacket(publisher, packet(false, 0))
+
+	if active, packets, _ := forwarded(s); active != "" || packets != 1 {
+		t.Fatalf("got %q forwarding %d packets, want nothing forwarded until the new encoder's keyframe", active, packets)
+	}
+
+	s.WritePacket(publisher, packet(true, 0))
+
+	if active, packets, _ := forwarded(s); active != PublisherPrimary || packets != 2 {
+		t.Fatalf("got %q forwarding %d packets, want the new encoder forwarded from its keyframe", active, packets)
+	}
+}
@@ -0,0 +1,113 @@
+package sessions
+
+import (
+	"log"
+
+	"github.com/geekgonecrazy/rtmp-lib/av"
+)
+
+// DestinationPaused reports whether a streamer's destination is outside its scheduled window.  It is set
+// by the schedules package, nil leaves every destination running
+var DestinationPaused func(streamerID int, destinationID int) bool
+
+// scheduledPause is whether a new destination starts out paused.  Schedules belong to streamers, so
+// destinations of ad-hoc sessions are never paused
+func (s *Session) scheduledPause(id int) bool {
+	return s.StreamerID != 0 && DestinationPaused != nil && DestinationPaused(s.StreamerID, id)
+}
+
+// SetDestinationPaused pauses or resumes a destination.  While the session is live a resumed destination
+// connects and starts at the next keyframe, a paused one stops at the next keyframe and disconnects
+func (s *Session) SetDestinationPaused(id int, paused bool) error {
+	s._lock.Lock()
+	defer s._lock.Unlock()
+
+	destination, err := s.GetDestination(id)
+	if err != nil {
+		return err
+	}
+
+	if destination.Paused == paused {
+		return nil
+	}
+
+	destination.Paused = paused
+
+	if paused {
+		s.recordEvent("destination_paused", destination.Name+" paused by its schedule")
+	} else {
+		s.recordEvent("destination_resumed", destination.Name+" resumed by its schedule")
+	}
+
+	if !s.Active {
+		return nil
+	}
+
+	if paused {
+		// Connected but not started yet, there's no keyframe to wait for
+		if !destination.forwarding {
+			s.disconnectDestination(destination)
+			return nil
+		}
+
+		destination.detachAtKeyframe = true
+
+		return nil
+	}
+
+	destination.detachAtKeyframe = false
+
+	// Still connected, it was waiting for a keyframe to stop at.  Or still connecting from the last time it
+	// was resumed, which carries on now it isn't paused
+	if destination.forwarding || destination.awaitingKeyframe || destination.connecting {
+		return nil
+	}
+
+	s.connect(destination)
+
+	return nil
+}
+
+// connect has the destination dial out in the background, so a slow or unreachable server doesn't hold up
+// the session, and start at the next keyframe.  Caller must hold the lock
+func (s *Session) connect(destination *Destination) {
+	destination.connecting = true
+
+	go s.attach(destination, s.StreamHeaders)
+}
+
+// attach connects the destination without holding the lock and has it picked up at the next keyframe
+func (s *Session) attach(destination *Destination, headers []av.CodecData) {
+	if err := destination.RTMP.WriteHeader(headers); err != nil {
+		log.Println("can't write header to destination stream:", err)
+	}
+
+	go destination.RTMP.Loop()
+
+	s._lock.Lock()
+	defer s._lock.Unlock()
+
+	destination.connecting = false
+	destination.awaitingKeyframe = true
+
+	// Paused again, the session ended or the destination was removed while we were connecting.  Nothing
+	// else disconnects a destination that is still connecting, so it's down to us
+	if destination.Paused || !s.Active || s.Destinations[destination.ID] != destination {
+		s.disconnectDestination(destination)
+	}
+}
+
+// disconnectDestination stops sending to the destination and disconnects it.  Destinations that never
+// connected, like ones paused since the session went live, are left alone so they don't report a
+// disconnect.  Caller must hold the lock, which also makes sure a connection is only disconnected once
+func (s *Session) disconnectDestination(destination *Destination) {
+	if destination.forwarding || destination.awaitingKeyframe {
+		if err := destination.RTMP.Disconnect(); err != nil {
+			log.Println(err)
+		}
+	}
+
+	destination.forwarding = false
+	destination.awaitingKeyframe = false
+	destination.detachAtKeyframe = false
+}
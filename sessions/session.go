@@ -6,26 +6,52 @@ import (
 	"log"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/geekgonecrazy/prismplus/helpers"
 	"github.com/geekgonecrazy/prismplus/models"
 	"github.com/geekgonecrazy/prismplus/rtmp"
+	"github.com/geekgonecrazy/prismplus/store"
 	"github.com/geekgonecrazy/rtmp-lib/av"
 )
 
 var (
-	_sessions   map[string]*Session
-	ErrNotFound = errors.New("not found")
+	_sessions     map[string]*Session
+	_sessionsLock sync.RWMutex
+	_dataStore    store.Store
+	ErrNotFound   = errors.New("not found")
 )
 
 type Session struct {
-	StreamerID        int                  `json:"streamerId"`
-	Key               string               `json:"key"`
-	Destinations      map[int]*Destination `json:"destinations"`
-	NextDestinationID int                  `json:"nextDestinationId"`
-	Active            bool                 `json:"active"`
-	End               bool                 `json:"end"`
-	StreamHeaders     []av.CodecData       `json:"streamHeaders"`
-	_lock             sync.Mutex           // Might need if we allow modify
+	// ID identifies the session without giving away its key, which is the stream key publishing to it
+	ID                       string                `json:"id"`
+	StreamerID               int                   `json:"streamerId"`
+	Key                      string                `json:"key"`
+	Profile                  string                `json:"profile"`
+	Destinations             map[int]*Destination  `json:"destinations"`
+	NextDestinationID        int                   `json:"nextDestinationId"`
+	Active                   bool                  `json:"active"`
+	End                      bool                  `json:"end"`
+	StreamHeaders            []av.CodecData        `json:"streamHeaders"`
+	Publishers               map[string]*Publisher `json:"publishers"`
+	ActivePublisher          string                `json:"activePublisher"`
+	ActiveRemoteAddr         string                `json:"activeRemoteAddr"`
+	DuplicatePublisherPolicy string                `json:"duplicatePublisherPolicy"`
+	Events                   []SessionEvent        `json:"events"`
+	AdHoc                    bool                  `json:"adHoc"`
+	LastActiveAt             time.Time             `json:"lastActiveAt"`
+	_lock                    sync.Mutex            // Might need if we allow modify
+
+	pendingPublisher string
+	// pendingFrom is the publisher being switched away from, empty when a publisher resumes after a takeover
+	pendingFrom   string
+	timeOffset    time.Duration
+	lastForwarded time.Duration
+	watching      bool
+	broadcast     *broadcast
+	createdAt     time.Time
+	// authorized sessions were created from a publish authorization response and last one broadcast
+	authorized bool
 }
 
 type Destination struct {
@@ -33,16 +59,20 @@ type Destination struct {
 	Name   string `json:"name"`
 	Server string `json:"server"`
 	Key    string `json:"key"`
+	// Paused destinations are outside their scheduled window and aren't sent anything
+	Paused bool `json:"paused"`
 	RTMP   *rtmp.RTMPConnection
+
+	forwarding       bool
+	awaitingKeyframe bool
+	detachAtKeyframe bool
+	// connecting is set while a resumed destination connects outside the lock
+	connecting bool
 }
 
 func (s *Session) AddDestination(destinationPayload models.Destination) error {
-
-	destinationPayload.Server = strings.TrimRight(destinationPayload.Server, "/")
-
-	url := fmt.Sprintf("%s/%s", destinationPayload.Server, destinationPayload.Key)
-
-	conn := rtmp.NewRTMPConnection(url)
+	s._lock.Lock()
+	defer s._lock.Unlock()
 
 	// If streamerID is 0 then we need to track the IDs
 	if s.StreamerID == 0 {
@@ -50,27 +80,41 @@ func (s *Session) AddDestination(destinationPayload models.Destination) error {
 		s.NextDestinationID++
 	}
 
-	s.Destinations[destinationPayload.ID] = &Destination{
+	s.addDestination(destinationPayload)
+	s.touch()
+
+	return nil
+}
+
+// addDestination connects the destination if the session is live.  Caller must hold the lock
+func (s *Session) addDestination(destinationPayload models.Destination) {
+	destinationPayload.Server = strings.TrimRight(destinationPayload.Server, "/")
+
+	url := fmt.Sprintf("%s/%s", destinationPayload.Server, destinationPayload.Key)
+
+	conn := rtmp.NewRTMPConnection(url)
+	conn.OnStateChange = s.destinationStateHandler(destinationPayload.ID, destinationPayload.Name)
+
+	destination := &Destination{
 		ID:     destinationPayload.ID,
 		Name:   destinationPayload.Name,
 		Server: destinationPayload.Server,
 		Key:    destinationPayload.Key,
+		Paused: s.scheduledPause(destinationPayload.ID),
 		RTMP:   conn,
 	}
 
-	if s.Active {
-		if err := conn.WriteHeader(s.StreamHeaders); err != nil {
-			fmt.Println("can't write header:", err)
-			// os.Exit(1)
-		}
+	s.Destinations[destinationPayload.ID] = destination
 
-		go conn.Loop()
+	if s.Active && !destination.Paused {
+		s.connect(destination)
 	}
-
-	return nil
 }
 
 func (s *Session) GetDestinations() []Destination {
+	s._lock.Lock()
+	defer s._lock.Unlock()
+
 	destinations := []Destination{}
 	for _, destination := range s.Destinations {
 		destinations = append(destinations, *destination)
@@ -88,54 +132,135 @@ func (s *Session) GetDestination(id int) (*Destination, error) {
 }
 
 func (s *Session) RemoveDestination(id int) error {
+	s._lock.Lock()
+	defer s._lock.Unlock()
+
+	if err := s.removeDestination(id); err != nil {
+		return err
+	}
+
+	s.touch()
+
+	return nil
+}
+
+// removeDestination disconnects the destination and drops it from the session.  Caller must hold the lock
+func (s *Session) removeDestination(id int) error {
 	destination, err := s.GetDestination(id)
 	if err != nil {
 		return err
 	}
 
-	if err := destination.RTMP.Disconnect(); err != nil {
-		log.Println(err)
+	if s.broadcast != nil {
+		s.broadcast.removed = append(s.broadcast.removed, destinationHistory(destination))
 	}
 
+	s.disconnectDestination(destination)
+
 	delete(s.Destinations, id)
 
 	return nil
 }
 
-func (s *Session) ChangeState(active bool) {
-	s.Active = active
+// SetProfile switches the session to a streamer's destination profile.  Destinations in both the old and
+// new profile carry on untouched, the rest are disconnected or connected
+func (s *Session) SetProfile(profile string, destinations []models.Destination) {
+	s._lock.Lock()
+	defer s._lock.Unlock()
+
+	wanted := map[int]bool{}
+	for _, destination := range destinations {
+		wanted[destination.ID] = true
+	}
+
+	for id := range s.Destinations {
+		if !wanted[id] {
+			s.removeDestination(id)
+		}
+	}
+
+	for _, destination := range destinations {
+		if s.Destinations[destination.ID] == nil {
+			s.addDestination(destination)
+		}
+	}
+
+	if profile != s.Profile {
+		s.recordEvent("profile_changed", "switched to profile "+profileName(profile))
+		s.Profile = profile
+	}
 }
 
-func (s *Session) SetHeaders(streams []av.CodecData) {
-	s.StreamHeaders = streams
+func profileName(profile string) string {
+	if profile == "" {
+		return "all destinations"
+	}
+
+	return profile
+}
+
+func (s *Session) SetDuplicatePublisherPolicy(policy string) {
+	s._lock.Lock()
+	defer s._lock.Unlock()
+
+	s.DuplicatePublisherPolicy = policy
+	s.touch()
 }
 
 func (s *Session) EndSession() {
 	s.End = true
+
+	// An ended session mustn't come back after a restart, even if nobody ever publishes to it again
+	s._lock.Lock()
+	s.forget()
+	s._lock.Unlock()
 }
 
-func InitializeSessionStore() {
+// EndSessionFor records why the session is being ended, like a limit being reached, then ends it
+func (s *Session) EndSessionFor(eventType string, reason string) {
+	s._lock.Lock()
+	s.recordEvent(eventType, reason)
+	s._lock.Unlock()
+
+	s.EndSession()
+}
+
+// Warn records a warning in the session's events
+func (s *Session) Warn(eventType string, message string) {
+	s._lock.Lock()
+	defer s._lock.Unlock()
+
+	s.recordEvent(eventType, message)
+}
+
+// InitializeSessionStore sets up the live sessions and restores ad-hoc sessions.  Broadcast history is
+// saved to the data store
+func InitializeSessionStore(dataStore store.Store) error {
 	_sessions = make(map[string]*Session)
+	_dataStore = dataStore
+
+	return restoreAdHocSessions()
 }
 
 func CreateSession(sessionPayload models.SessionPayload) error {
+	_sessionsLock.Lock()
+	defer _sessionsLock.Unlock()
 
-	existingSession, err := GetSession(sessionPayload.Key)
-	if err != nil && err != ErrNotFound {
-		return err
-	}
-
-	if existingSession != nil {
+	if _sessions[sessionPayload.Key] != nil {
 		return errors.New("Already Exists")
 	}
 
 	session := &Session{
-		StreamerID:        sessionPayload.StreamerID,
-		Key:               sessionPayload.Key,
-		Destinations:      map[int]*Destination{},
-		NextDestinationID: 0,
-		Active:            false,
-		End:               false,
+		ID:                       sessionID(sessionPayload.Key),
+		StreamerID:               sessionPayload.StreamerID,
+		Key:                      sessionPayload.Key,
+		Destinations:             map[int]*Destination{},
+		NextDestinationID:        0,
+		Active:                   false,
+		End:                      false,
+		Publishers:               map[string]*Publisher{},
+		DuplicatePublisherPolicy: sessionPayload.DuplicatePublisherPolicy,
+		Events:                   []SessionEvent{},
 	}
 
 	_sessions[sessionPayload.Key] = session
@@ -147,32 +272,135 @@ func CreateSession(sessionPayload models.SessionPayload) error {
 	return nil
 }
 
-func CreateSessionFromStreamer(streamer models.Streamer) (*Session, error) {
+// CreateSessionFromStreamer creates the streamer's session with the destinations of one of its profiles,
+// "" for all of them
+func CreateSessionFromStreamer(streamer models.Streamer, profile string) (*Session, error) {
 	log.Println("Creating session from streamer", streamer.Name)
 
+	destinations, ok := streamer.ProfileDestinations(profile)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
 	sessionPayload := models.SessionPayload{
 		StreamerID:   streamer.ID,
 		Key:          streamer.StreamKey,
-		Destinations: streamer.Destinations,
+		Destinations: destinations,
+
+		DuplicatePublisherPolicy: streamer.DuplicatePublisherPolicy,
+	}
+
+	session, err := CreateAndGetSession(sessionPayload)
+	if err != nil {
+		return nil, err
 	}
 
+	session._lock.Lock()
+	session.Profile = profile
+	session._lock.Unlock()
+
+	return session, nil
+}
+
+// CreateAndGetSession creates the session and returns it
+func CreateAndGetSession(sessionPayload models.SessionPayload) (*Session, error) {
 	if err := CreateSession(sessionPayload); err != nil {
 		return nil, err
 	}
 
-	return GetSession(streamer.StreamKey)
+	return GetSession(sessionPayload.Key)
+}
+
+// Snapshot returns a copy of the session that can be read and marshalled while it carries on changing
+func (s *Session) Snapshot() *Session {
+	s._lock.Lock()
+	defer s._lock.Unlock()
+
+	snapshot := &Session{
+		ID:                       s.ID,
+		StreamerID:               s.StreamerID,
+		Key:                      s.Key,
+		Profile:                  s.Profile,
+		Destinations:             map[int]*Destination{},
+		NextDestinationID:        s.NextDestinationID,
+		Active:                   s.Active,
+		End:                      s.End,
+		Publishers:               map[string]*Publisher{},
+		ActivePublisher:          s.ActivePublisher,
+		ActiveRemoteAddr:         s.ActiveRemoteAddr,
+		DuplicatePublisherPolicy: s.DuplicatePublisherPolicy,
+		Events:                   append([]SessionEvent{}, s.Events...),
+		AdHoc:                    s.AdHoc,
+		LastActiveAt:             s.LastActiveAt,
+	}
+
+	if s.StreamHeaders != nil {
+		snapshot.StreamHeaders = append([]av.CodecData{}, s.StreamHeaders...)
+	}
+
+	for id, destination := range s.Destinations {
+		copied := *destination
+		snapshot.Destinations[id] = &copied
+	}
+
+	for role, publisher := range s.Publishers {
+		copied := *publisher
+		snapshot.Publishers[role] = &copied
+	}
+
+	return snapshot
+}
+
+// CreateAuthorizedSession creates a session with the destinations a publish authorization response gave.
+// It only lasts for the broadcast, the next one is authorized again and can go somewhere else
+func CreateAuthorizedSession(sessionPayload models.SessionPayload) (*Session, error) {
+	session, err := CreateAndGetSession(sessionPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	session._lock.Lock()
+	session.authorized = true
+	session._lock.Unlock()
+
+	return session, nil
+}
+
+// ReleaseAuthorizedSession removes a session created by CreateAuthorizedSession once nobody is publishing to
+// it, and reports whether it did
+func ReleaseAuthorizedSession(session *Session) bool {
+	_sessionsLock.Lock()
+	defer _sessionsLock.Unlock()
+
+	session._lock.Lock()
+	defer session._lock.Unlock()
+
+	if !session.authorized || session.Active || _sessions[session.Key] != session {
+		return false
+	}
+
+	delete(_sessions, session.Key)
+
+	return true
 }
 
-func GetSessions() []Session {
-	sessions := []Session{}
+// GetSessions returns the live sessions.  Use Snapshot to read them
+func GetSessions() []*Session {
+	_sessionsLock.RLock()
+	defer _sessionsLock.RUnlock()
+
+	sessions := []*Session{}
 	for _, session := range _sessions {
-		sessions = append(sessions, *session)
+		sessions = append(sessions, session)
 	}
 
 	return sessions
 }
 
 func GetSession(key string) (*Session, error) {
+	_sessionsLock.RLock()
+	defer _sessionsLock.RUnlock()
+
 	if _sessions[key] == nil {
 		return nil, ErrNotFound
 	}
@@ -180,12 +408,42 @@ func GetSession(key string) (*Session, error) {
 	return _sessions[key], nil
 }
 
+// FindSession returns the session with keyOrID as its key or its id
+func FindSession(keyOrID string) (*Session, error) {
+	_sessionsLock.RLock()
+	defer _sessionsLock.RUnlock()
+
+	if session := _sessions[keyOrID]; session != nil {
+		return session, nil
+	}
+
+	for _, session := range _sessions {
+		if session.ID == keyOrID {
+			return session, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// sessionID is the start of the key's hash, enough to tell sessions apart
+func sessionID(key string) string {
+	return helpers.HashToken(key)[:16]
+}
+
 func DeleteSession(key string) error {
-	if _sessions[key] == nil {
+	_sessionsLock.Lock()
+	session := _sessions[key]
+	delete(_sessions, key)
+	_sessionsLock.Unlock()
+
+	if session == nil {
 		return ErrNotFound
 	}
 
-	delete(_sessions, key)
+	session._lock.Lock()
+	session.forget()
+	session._lock.Unlock()
 
 	return nil
 }
@@ -0,0 +1,80 @@
+package sessions
+
+import (
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/events"
+	"github.com/geekgonecrazy/prismplus/models"
+)
+
+// statsInterval is how often a live session's stats are published
+const statsInterval = 5 * time.Second
+
+// publicKey identifies ad-hoc sessions by their id, since their key can publish to them.  Streamer
+// sessions are identified by streamer id instead
+func (s *Session) publicKey() string {
+	if s.StreamerID != 0 {
+		return ""
+	}
+
+	return s.ID
+}
+
+// Stats returns a snapshot of the session.  ok is false if it isn't live
+func (s *Session) Stats() (stats models.SessionStats, ok bool) {
+	s._lock.Lock()
+	defer s._lock.Unlock()
+
+	if s.broadcast == nil {
+		return stats, false
+	}
+
+	return s.stats(), true
+}
+
+// stats builds the snapshot.  Caller must hold the lock and the session must be live
+func (s *Session) stats() models.SessionStats {
+	stats := models.SessionStats{
+		StreamerID:   s.StreamerID,
+		Session:      s.publicKey(),
+		StartedAt:    s.broadcast.startedAt,
+		Ingest:       s.broadcast.ingest,
+		Bitrate:      s.broadcast.bitrate,
+		Destinations: []models.DestinationStats{},
+	}
+
+	stats.Ingest.AverageBitrate = averageBitrate(stats.Ingest.Bytes, time.Since(s.broadcast.startedAt))
+
+	for _, destination := range s.Destinations {
+		stats.Destinations = append(stats.Destinations, models.DestinationStats{
+			DestinationHistory: destinationHistory(destination),
+			State:              destination.RTMP.Stats().State,
+		})
+	}
+
+	return stats
+}
+
+// updateBitrate works out the ingest bitrate since it was last called.  Caller must hold the lock
+func (s *Session) updateBitrate(now time.Time) {
+	elapsed := now.Sub(s.broadcast.bitrateAt)
+	if elapsed <= 0 {
+		return
+	}
+
+	s.broadcast.bitrate = int64(float64((s.broadcast.ingest.Bytes-s.broadcast.bitrateBytes)*8) / elapsed.Seconds())
+	s.broadcast.bitrateAt = now
+	s.broadcast.bitrateBytes = s.broadcast.ingest.Bytes
+}
+
+// destinationStateHandler publishes state changes for a destination's connection
+func (s *Session) destinationStateHandler(id int, name string) func(state string) {
+	return func(state string) {
+		events.Publish(events.DestinationEvent(state), s.StreamerID, models.DestinationStateEvent{
+			Session:       s.publicKey(),
+			DestinationID: id,
+			Name:          name,
+			State:         state,
+		})
+	}
+}
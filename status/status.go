@@ -0,0 +1,102 @@
+package status
+
+import (
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/sessions"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+const checkOK = "ok"
+
+var (
+	// Version, Commit and BuildDate are set when building, for example
+	// go build -ldflags "-X github.com/geekgonecrazy/prismplus/status.Version=1.2.0", .goreleaser.yml sets all three
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+
+	startedAt = time.Now()
+
+	_dataStore     store.Store
+	_rtmpListening int32
+
+	errRTMPNotListening = errors.New("rtmp server isn't listening")
+)
+
+// Setup gives readiness checks the store
+func Setup(dataStore store.Store) {
+	_dataStore = dataStore
+}
+
+// SetRTMPListening records whether the RTMP server is accepting connections
+func SetRTMPListening(listening bool) {
+	var value int32
+	if listening {
+		value = 1
+	}
+
+	atomic.StoreInt32(&_rtmpListening, value)
+}
+
+// Ready checks that the store can be read and that the RTMP server is accepting connections.  The api
+// is bound if it is able to ask
+func Ready() models.Readiness {
+	readiness := models.Readiness{
+		Ready: true,
+		Checks: map[string]string{
+			"api": checkOK,
+		},
+	}
+
+	check := func(name string, err error) {
+		if err != nil {
+			readiness.Ready = false
+			readiness.Checks[name] = err.Error()
+			return
+		}
+
+		readiness.Checks[name] = checkOK
+	}
+
+	check("store", _dataStore.CheckDb())
+
+	if atomic.LoadInt32(&_rtmpListening) == 1 {
+		check("rtmp", nil)
+	} else {
+		check("rtmp", errRTMPNotListening)
+	}
+
+	return readiness
+}
+
+func GetStatus() (models.Status, error) {
+	streamers, err := _dataStore.GetStreamers()
+	if err != nil {
+		return models.Status{}, err
+	}
+
+	status := models.Status{
+		Version:       Version,
+		Commit:        Commit,
+		BuildDate:     BuildDate,
+		GoVersion:     runtime.Version(),
+		StartedAt:     startedAt,
+		UptimeSeconds: int64(time.Since(startedAt) / time.Second),
+		Streamers:     len(streamers),
+	}
+
+	for _, session := range sessions.GetSessions() {
+		status.Sessions++
+
+		if session.Snapshot().Active {
+			status.LiveSessions++
+		}
+	}
+
+	return status, nil
+}
@@ -0,0 +1,47 @@
+package boltstore
+
+import (
+	"encoding/json"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *boltStore) GetAdHocSessions() ([]models.AdHocSession, error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cursor := tx.Bucket(adHocSessionsBucket).Cursor()
+
+	sessions := make([]models.AdHocSession, 0)
+	for k, data := cursor.First(); k != nil; k, data = cursor.Next() {
+		var i models.AdHocSession
+		if err := json.Unmarshal(data, &i); err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, i)
+	}
+
+	return sessions, nil
+}
+
+func (s *boltStore) SetAdHocSession(session *models.AdHocSession) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		buf, err := json.Marshal(session)
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(adHocSessionsBucket).Put([]byte(session.Key), buf)
+	})
+}
+
+func (s *boltStore) DeleteAdHocSession(key string) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(adHocSessionsBucket).Delete([]byte(key))
+	})
+}
@@ -0,0 +1,211 @@
+package boltstore
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *boltStore) GetAdmins() ([]models.Admin, error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cursor := tx.Bucket(adminsBucket).Cursor()
+
+	admins := make([]models.Admin, 0)
+	for k, data := cursor.First(); k != nil; k, data = cursor.Next() {
+		var i models.Admin
+		if err := json.Unmarshal(data, &i); err != nil {
+			return nil, err
+		}
+
+		admins = append(admins, i)
+	}
+
+	return admins, nil
+}
+
+func (s *boltStore) GetAdminByID(id int) (admin models.Admin, err error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return admin, err
+	}
+	defer tx.Rollback()
+
+	bytes := tx.Bucket(adminsBucket).Get(itob(id))
+	if bytes == nil {
+		return admin, store.ErrNotFound
+	}
+
+	if err := json.Unmarshal(bytes, &admin); err != nil {
+		return admin, err
+	}
+
+	return admin, nil
+}
+
+func (s *boltStore) CreateAdmin(admin *models.Admin) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(adminsBucket)
+
+		seq, _ := bucket.NextSequence()
+		admin.ID = int(seq)
+		admin.CreatedAt = time.Now()
+		admin.UpdatedAt = time.Now()
+
+		buf, err := json.Marshal(admin)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(admin.ID), buf)
+	})
+}
+
+func (s *boltStore) UpdateAdmin(admin *models.Admin) error {
+	if admin.ID <= 0 {
+		return errors.New("invalid admin id")
+	}
+
+	return s.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(adminsBucket)
+
+		if bucket.Get(itob(admin.ID)) == nil {
+			return store.ErrNotFound
+		}
+
+		admin.UpdatedAt = time.Now()
+
+		buf, err := json.Marshal(admin)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(admin.ID), buf)
+	})
+}
+
+func (s *boltStore) DeleteAdmin(id int) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(adminsBucket).Delete(itob(id)); err != nil {
+			return err
+		}
+
+		bucket := tx.Bucket(adminAPIKeysBucket)
+
+		ids := [][]byte{}
+
+		cursor := bucket.Cursor()
+		for k, data := cursor.First(); k != nil; k, data = cursor.Next() {
+			var i models.AdminAPIKey
+			if err := json.Unmarshal(data, &i); err != nil {
+				return err
+			}
+
+			if i.AdminID == id {
+				ids = append(ids, k)
+			}
+		}
+
+		for _, k := range ids {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *boltStore) GetAdminAPIKeys() ([]models.AdminAPIKey, error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cursor := tx.Bucket(adminAPIKeysBucket).Cursor()
+
+	keys := make([]models.AdminAPIKey, 0)
+	for k, data := cursor.First(); k != nil; k, data = cursor.Next() {
+		var i models.AdminAPIKey
+		if err := json.Unmarshal(data, &i); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, i)
+	}
+
+	return keys, nil
+}
+
+func (s *boltStore) GetAdminAPIKeyByID(id int) (key models.AdminAPIKey, err error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return key, err
+	}
+	defer tx.Rollback()
+
+	bytes := tx.Bucket(adminAPIKeysBucket).Get(itob(id))
+	if bytes == nil {
+		return key, store.ErrNotFound
+	}
+
+	if err := json.Unmarshal(bytes, &key); err != nil {
+		return key, err
+	}
+
+	return key, nil
+}
+
+func (s *boltStore) CreateAdminAPIKey(key *models.AdminAPIKey) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(adminAPIKeysBucket)
+
+		seq, _ := bucket.NextSequence()
+		key.ID = int(seq)
+		key.CreatedAt = time.Now()
+
+		buf, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(key.ID), buf)
+	})
+}
+
+func (s *boltStore) UpdateAdminAPIKey(key *models.AdminAPIKey) error {
+	if key.ID <= 0 {
+		return errors.New("invalid admin api key id")
+	}
+
+	return s.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(adminAPIKeysBucket)
+
+		// Don't resurrect a key that was deleted while it was in use
+		if bucket.Get(itob(key.ID)) == nil {
+			return store.ErrNotFound
+		}
+
+		buf, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(key.ID), buf)
+	})
+}
+
+func (s *boltStore) DeleteAdminAPIKey(id int) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(adminAPIKeysBucket).Delete(itob(id))
+	})
+}
@@ -0,0 +1,124 @@
+package boltstore
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *boltStore) GetAPITokens() ([]models.APIToken, error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cursor := tx.Bucket(apiTokensBucket).Cursor()
+
+	tokens := make([]models.APIToken, 0)
+	for k, data := cursor.First(); k != nil; k, data = cursor.Next() {
+		var i models.APIToken
+		if err := json.Unmarshal(data, &i); err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, i)
+	}
+
+	return tokens, nil
+}
+
+func (s *boltStore) GetAPITokenByID(id int) (token models.APIToken, err error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return token, err
+	}
+	defer tx.Rollback()
+
+	bytes := tx.Bucket(apiTokensBucket).Get(itob(id))
+	if bytes == nil {
+		return token, store.ErrNotFound
+	}
+
+	if err := json.Unmarshal(bytes, &token); err != nil {
+		return token, err
+	}
+
+	return token, nil
+}
+
+func (s *boltStore) CreateAPIToken(token *models.APIToken) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(apiTokensBucket)
+
+		seq, _ := bucket.NextSequence()
+		token.ID = int(seq)
+		token.CreatedAt = time.Now()
+
+		buf, err := json.Marshal(token)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(token.ID), buf)
+	})
+}
+
+func (s *boltStore) UpdateAPIToken(token *models.APIToken) error {
+	if token.ID <= 0 {
+		return errors.New("invalid api token id")
+	}
+
+	return s.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(apiTokensBucket)
+
+		// Don't resurrect a token that was deleted while it was in use
+		if bucket.Get(itob(token.ID)) == nil {
+			return store.ErrNotFound
+		}
+
+		buf, err := json.Marshal(token)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(token.ID), buf)
+	})
+}
+
+func (s *boltStore) DeleteAPIToken(id int) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(apiTokensBucket).Delete(itob(id))
+	})
+}
+
+// deleteStreamerAPITokens removes tokens bound to or created by the streamer
+func deleteStreamerAPITokens(tx *bolt.Tx, streamerID int) error {
+	bucket := tx.Bucket(apiTokensBucket)
+
+	ids := [][]byte{}
+
+	cursor := bucket.Cursor()
+	for k, data := cursor.First(); k != nil; k, data = cursor.Next() {
+		var i models.APIToken
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		if i.StreamerID == streamerID || i.CreatedByStreamerID == streamerID {
+			ids = append(ids, k)
+		}
+	}
+
+	for _, k := range ids {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
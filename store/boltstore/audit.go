@@ -0,0 +1,73 @@
+package boltstore
+
+import (
+	"encoding/json"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *boltStore) CreateAuditEntry(entry *models.AuditEntry) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(auditBucket)
+
+		seq, _ := bucket.NextSequence()
+		entry.ID = int(seq)
+
+		buf, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(entry.ID), buf)
+	})
+}
+
+// GetAuditEntries walks the audit log from newest to oldest
+func (s *boltStore) GetAuditEntries(query models.AuditQuery) (page models.AuditPage, err error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return page, err
+	}
+	defer tx.Rollback()
+
+	cursor := tx.Bucket(auditBucket).Cursor()
+
+	var k, data []byte
+	if query.Before > 0 {
+		k, data = cursor.Seek(itob(query.Before))
+		if k != nil {
+			k, data = cursor.Prev()
+		} else {
+			k, data = cursor.Last()
+		}
+	} else {
+		k, data = cursor.Last()
+	}
+
+	page.Entries = make([]models.AuditEntry, 0)
+	for ; k != nil; k, data = cursor.Prev() {
+		var i models.AuditEntry
+		if err := json.Unmarshal(data, &i); err != nil {
+			return page, err
+		}
+
+		// Entries are in time order so nothing older will match either
+		if !query.Since.IsZero() && i.Time.Before(query.Since) {
+			break
+		}
+
+		if query.StreamerID != 0 && i.StreamerID != query.StreamerID {
+			continue
+		}
+
+		if len(page.Entries) == query.Limit {
+			page.Next = page.Entries[len(page.Entries)-1].ID
+			break
+		}
+
+		page.Entries = append(page.Entries, i)
+	}
+
+	return page, nil
+}
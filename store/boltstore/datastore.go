@@ -3,9 +3,13 @@ package boltstore
 import (
 	"encoding/binary"
 	"fmt"
+	"io"
+	"sync"
 	"time"
 
 	"github.com/geekgonecrazy/prismplus/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	bolt "go.etcd.io/bbolt"
 )
 
@@ -13,13 +17,56 @@ type boltStore struct {
 	*bolt.DB
 }
 
+// OpenTimeout is how long New waits for another process to let go of the database
+var OpenTimeout = 15 * time.Second
+
+var transactionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "prismplus_bolt_transaction_duration_seconds",
+	Help:    "Time bolt transactions were open for, including the commit of writes",
+	Buckets: []float64{.0001, .0005, .001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+}, []string{"type"})
+
+var (
+	readTransactionsDesc     = prometheus.NewDesc("prismplus_bolt_read_transactions_total", "Read transactions started", nil, nil)
+	openReadTransactionsDesc = prometheus.NewDesc("prismplus_bolt_open_read_transactions", "Read transactions currently open", nil, nil)
+	writeSecondsDesc         = prometheus.NewDesc("prismplus_bolt_write_seconds_total", "Time spent writing to disk", nil, nil)
+	writesDesc               = prometheus.NewDesc("prismplus_bolt_writes_total", "Writes to disk", nil, nil)
+
+	// _collected is the most recently opened store, which the registered collector reports on
+	_collected     *boltStore
+	_collectedLock sync.Mutex
+	_registerOnce  sync.Once
+)
+
 var (
-	streamersBucket = []byte("streamers")
+	streamersBucket           = []byte("streamers")
+	streamerCredentialsBucket = []byte("streamerCredentials")
+	streamerTokensBucket      = []byte("streamerTokens")
+	adminsBucket              = []byte("admins")
+	adminAPIKeysBucket        = []byte("adminAPIKeys")
+	apiTokensBucket           = []byte("apiTokens")
+	auditBucket               = []byte("audit")
+	sessionHistoryBucket      = []byte("sessionHistory")
+	adHocSessionsBucket       = []byte("adHocSessions")
+	schedulesBucket           = []byte("schedules")
+	streamKeysBucket          = []byte("streamKeys")
+	streamerUsageBucket       = []byte("streamerUsage")
+	webhooksBucket            = []byte("webhooks")
+	webhookDeliveriesBucket   = []byte("webhookDeliveries")
 )
 
+func init() {
+	store.Register("bolt", Open)
+}
+
 //New creates a new bolt store
 func New(dataPath string) (store.Store, error) {
-	db, err := bolt.Open(fmt.Sprintf("%s%s", dataPath, "data.bbolt"), 0600, &bolt.Options{Timeout: 15 * time.Second})
+	return Open(fmt.Sprintf("%s%s", dataPath, "data.bbolt"))
+}
+
+// Open opens the bolt database at path, creating it if needed
+func Open(path string) (store.Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: OpenTimeout})
 	if err != nil {
 		return nil, err
 	}
@@ -30,15 +77,132 @@ func New(dataPath string) (store.Store, error) {
 	}
 	defer tx.Rollback()
 
-	if _, err := tx.CreateBucketIfNotExists(streamersBucket); err != nil {
-		return nil, err
+	buckets := [][]byte{
+		streamersBucket,
+		streamerCredentialsBucket,
+		streamerTokensBucket,
+		adminsBucket,
+		adminAPIKeysBucket,
+		apiTokensBucket,
+		auditBucket,
+		sessionHistoryBucket,
+		adHocSessionsBucket,
+		schedulesBucket,
+		streamKeysBucket,
+		streamerUsageBucket,
+		webhooksBucket,
+		webhookDeliveriesBucket,
+	}
+
+	for _, bucket := range buckets {
+		if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+			return nil, err
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 
-	return &boltStore{db}, nil
+	s := &boltStore{db}
+
+	_collectedLock.Lock()
+	_collected = s
+	_collectedLock.Unlock()
+
+	_registerOnce.Do(func() {
+		prometheus.MustRegister(collector{})
+	})
+
+	return s, nil
+}
+
+// timedTx records how long the transaction was open once it is committed or rolled back
+type timedTx struct {
+	*bolt.Tx
+
+	start time.Time
+	once  sync.Once
+}
+
+func (t *timedTx) observe() {
+	t.once.Do(func() {
+		transactionType := "read"
+		if t.Writable() {
+			transactionType = "write"
+		}
+
+		transactionDuration.WithLabelValues(transactionType).Observe(time.Since(t.start).Seconds())
+	})
+}
+
+func (t *timedTx) Commit() error {
+	defer t.observe()
+
+	return t.Tx.Commit()
+}
+
+func (t *timedTx) Rollback() error {
+	defer t.observe()
+
+	return t.Tx.Rollback()
+}
+
+// Begin starts a transaction that records how long it was open
+func (s *boltStore) Begin(writable bool) (*timedTx, error) {
+	start := time.Now()
+
+	tx, err := s.DB.Begin(writable)
+	if err != nil {
+		return nil, err
+	}
+
+	return &timedTx{Tx: tx, start: start}, nil
+}
+
+// View runs fn in a read transaction and records how long it took
+func (s *boltStore) View(fn func(*bolt.Tx) error) error {
+	start := time.Now()
+
+	err := s.DB.View(fn)
+
+	transactionDuration.WithLabelValues("read").Observe(time.Since(start).Seconds())
+
+	return err
+}
+
+// Update runs fn in a write transaction and records how long it took
+func (s *boltStore) Update(fn func(*bolt.Tx) error) error {
+	start := time.Now()
+
+	err := s.DB.Update(fn)
+
+	transactionDuration.WithLabelValues("write").Observe(time.Since(start).Seconds())
+
+	return err
+}
+
+// collector reports the database stats of the most recently opened store
+type collector struct{}
+
+func (collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- readTransactionsDesc
+	ch <- openReadTransactionsDesc
+	ch <- writeSecondsDesc
+	ch <- writesDesc
+}
+
+func (collector) Collect(ch chan<- prometheus.Metric) {
+	_collectedLock.Lock()
+	s := _collected
+	_collectedLock.Unlock()
+
+	stats := s.Stats()
+
+	ch <- prometheus.MustNewConstMetric(readTransactionsDesc, prometheus.CounterValue, float64(stats.TxN))
+	ch <- prometheus.MustNewConstMetric(openReadTransactionsDesc, prometheus.GaugeValue, float64(stats.OpenTxN))
+	ch <- prometheus.MustNewConstMetric(writeSecondsDesc, prometheus.CounterValue, stats.TxStats.WriteTime.Seconds())
+	ch <- prometheus.MustNewConstMetric(writesDesc, prometheus.CounterValue, float64(stats.TxStats.Write))
 }
 
 func (s *boltStore) CheckDb() error {
@@ -50,6 +214,18 @@ func (s *boltStore) CheckDb() error {
 	return tx.Rollback()
 }
 
+// Backup writes the database as it was when the read transaction started, writers aren't blocked
+func (s *boltStore) Backup(w io.Writer) error {
+	return s.View(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(w)
+		return err
+	})
+}
+
+func (s *boltStore) BackupExtension() string {
+	return "bbolt"
+}
+
 //itob returns an 8-byte big endian representation of v.
 func itob(v int) []byte {
 	b := make([]byte, 8)
@@ -0,0 +1,24 @@
+package boltstore
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/geekgonecrazy/prismplus/store"
+	"github.com/geekgonecrazy/prismplus/store/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		s, err := Open(filepath.Join(t.TempDir(), "data.bbolt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(func() {
+			s.(*boltStore).Close()
+		})
+
+		return s
+	})
+}
@@ -0,0 +1,73 @@
+package boltstore
+
+import (
+	"encoding/json"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *boltStore) CreateSessionHistory(history *models.SessionHistory) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(sessionHistoryBucket)
+
+		seq, _ := bucket.NextSequence()
+		history.ID = int(seq)
+
+		buf, err := json.Marshal(history)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(history.ID), buf)
+	})
+}
+
+// GetSessionHistory walks the history from newest to oldest
+func (s *boltStore) GetSessionHistory(query models.SessionHistoryQuery) (page models.SessionHistoryPage, err error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return page, err
+	}
+	defer tx.Rollback()
+
+	cursor := tx.Bucket(sessionHistoryBucket).Cursor()
+
+	var k, data []byte
+	if query.Before > 0 {
+		k, data = cursor.Seek(itob(query.Before))
+		if k != nil {
+			k, data = cursor.Prev()
+		} else {
+			k, data = cursor.Last()
+		}
+	} else {
+		k, data = cursor.Last()
+	}
+
+	page.Sessions = make([]models.SessionHistory, 0)
+	for ; k != nil; k, data = cursor.Prev() {
+		var i models.SessionHistory
+		if err := json.Unmarshal(data, &i); err != nil {
+			return page, err
+		}
+
+		// Records are stored as broadcasts end so nothing older will match either
+		if !query.Since.IsZero() && i.EndedAt.Before(query.Since) {
+			break
+		}
+
+		if query.StreamerID != 0 && i.StreamerID != query.StreamerID {
+			continue
+		}
+
+		if len(page.Sessions) == query.Limit {
+			page.Next = page.Sessions[len(page.Sessions)-1].ID
+			break
+		}
+
+		page.Sessions = append(page.Sessions, i)
+	}
+
+	return page, nil
+}
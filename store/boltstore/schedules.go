@@ -0,0 +1,125 @@
+package boltstore
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *boltStore) GetSchedules() ([]models.Schedule, error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cursor := tx.Bucket(schedulesBucket).Cursor()
+
+	schedules := make([]models.Schedule, 0)
+	for k, data := cursor.First(); k != nil; k, data = cursor.Next() {
+		var i models.Schedule
+		if err := json.Unmarshal(data, &i); err != nil {
+			return nil, err
+		}
+
+		schedules = append(schedules, i)
+	}
+
+	return schedules, nil
+}
+
+func (s *boltStore) GetScheduleByID(id int) (schedule models.Schedule, err error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return schedule, err
+	}
+	defer tx.Rollback()
+
+	bytes := tx.Bucket(schedulesBucket).Get(itob(id))
+	if bytes == nil {
+		return schedule, store.ErrNotFound
+	}
+
+	if err := json.Unmarshal(bytes, &schedule); err != nil {
+		return schedule, err
+	}
+
+	return schedule, nil
+}
+
+func (s *boltStore) CreateSchedule(schedule *models.Schedule) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(schedulesBucket)
+
+		seq, _ := bucket.NextSequence()
+		schedule.ID = int(seq)
+		schedule.CreatedAt = time.Now()
+		schedule.UpdatedAt = time.Now()
+
+		buf, err := json.Marshal(schedule)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(schedule.ID), buf)
+	})
+}
+
+func (s *boltStore) UpdateSchedule(schedule *models.Schedule) error {
+	if schedule.ID <= 0 {
+		return errors.New("invalid schedule id")
+	}
+
+	return s.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(schedulesBucket)
+
+		if bucket.Get(itob(schedule.ID)) == nil {
+			return store.ErrNotFound
+		}
+
+		schedule.UpdatedAt = time.Now()
+
+		buf, err := json.Marshal(schedule)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(schedule.ID), buf)
+	})
+}
+
+func (s *boltStore) DeleteSchedule(id int) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(schedulesBucket).Delete(itob(id))
+	})
+}
+
+func deleteStreamerSchedules(tx *bolt.Tx, streamerID int) error {
+	bucket := tx.Bucket(schedulesBucket)
+
+	ids := [][]byte{}
+
+	cursor := bucket.Cursor()
+	for k, data := cursor.First(); k != nil; k, data = cursor.Next() {
+		var i models.Schedule
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		if i.StreamerID == streamerID {
+			ids = append(ids, k)
+		}
+	}
+
+	for _, k := range ids {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
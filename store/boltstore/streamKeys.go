@@ -0,0 +1,126 @@
+package boltstore
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Stream keys are stored by the key itself so publishing can look them up directly
+
+func (s *boltStore) GetStreamKeys(streamerID int) ([]models.StreamKey, error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	streamKeys := make([]models.StreamKey, 0)
+	err = eachStreamKey(tx.Tx, func(k []byte, streamKey models.StreamKey) {
+		if streamKey.StreamerID == streamerID {
+			streamKeys = append(streamKeys, streamKey)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return streamKeys, nil
+}
+
+func (s *boltStore) GetStreamKey(key string) (streamKey models.StreamKey, err error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return streamKey, err
+	}
+	defer tx.Rollback()
+
+	bytes := tx.Bucket(streamKeysBucket).Get([]byte(key))
+	if bytes == nil {
+		return streamKey, store.ErrNotFound
+	}
+
+	if err := json.Unmarshal(bytes, &streamKey); err != nil {
+		return streamKey, err
+	}
+
+	return streamKey, nil
+}
+
+func (s *boltStore) CreateStreamKey(streamKey *models.StreamKey) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(streamKeysBucket)
+
+		if bucket.Get([]byte(streamKey.Key)) != nil {
+			return store.ErrExists
+		}
+
+		// Keys are unique across streamers' own keys too
+		if err := checkStreamKeyFree(tx.Bucket(streamersBucket), &models.Streamer{StreamKey: streamKey.Key}); err != nil {
+			return err
+		}
+
+		seq, _ := bucket.NextSequence()
+		streamKey.ID = int(seq)
+		streamKey.CreatedAt = time.Now()
+
+		buf, err := json.Marshal(streamKey)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(streamKey.Key), buf)
+	})
+}
+
+func (s *boltStore) DeleteStreamKey(id int) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		return deleteStreamKeys(tx, func(streamKey models.StreamKey) bool {
+			return streamKey.ID == id
+		})
+	})
+}
+
+func deleteStreamerStreamKeys(tx *bolt.Tx, streamerID int) error {
+	return deleteStreamKeys(tx, func(streamKey models.StreamKey) bool {
+		return streamKey.StreamerID == streamerID
+	})
+}
+
+func deleteStreamKeys(tx *bolt.Tx, match func(streamKey models.StreamKey) bool) error {
+	keys := [][]byte{}
+	err := eachStreamKey(tx, func(k []byte, streamKey models.StreamKey) {
+		if match(streamKey) {
+			keys = append(keys, k)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	bucket := tx.Bucket(streamKeysBucket)
+	for _, k := range keys {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func eachStreamKey(tx *bolt.Tx, fn func(k []byte, streamKey models.StreamKey)) error {
+	cursor := tx.Bucket(streamKeysBucket).Cursor()
+	for k, data := cursor.First(); k != nil; k, data = cursor.Next() {
+		var i models.StreamKey
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		fn(k, i)
+	}
+
+	return nil
+}
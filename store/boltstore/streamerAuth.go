@@ -0,0 +1,148 @@
+package boltstore
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *boltStore) GetStreamerCredentials(streamerID int) (credentials models.StreamerCredentials, err error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return credentials, err
+	}
+	defer tx.Rollback()
+
+	bytes := tx.Bucket(streamerCredentialsBucket).Get(itob(streamerID))
+	if bytes == nil {
+		return credentials, store.ErrNotFound
+	}
+
+	if err := json.Unmarshal(bytes, &credentials); err != nil {
+		return credentials, err
+	}
+
+	return credentials, nil
+}
+
+func (s *boltStore) ConsumeStreamerMagicLink(hash string) (credentials models.StreamerCredentials, err error) {
+	if hash == "" {
+		return credentials, store.ErrNotFound
+	}
+
+	err = s.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(streamerCredentialsBucket)
+
+		cursor := bucket.Cursor()
+		for k, data := cursor.First(); k != nil; k, data = cursor.Next() {
+			var i models.StreamerCredentials
+			if err := json.Unmarshal(data, &i); err != nil {
+				return err
+			}
+
+			if i.MagicLinkHash != hash {
+				continue
+			}
+
+			credentials = i
+
+			i.MagicLinkHash = ""
+			i.MagicLinkExpiresAt = time.Time{}
+
+			buf, err := json.Marshal(i)
+			if err != nil {
+				return err
+			}
+
+			return bucket.Put(k, buf)
+		}
+
+		return store.ErrNotFound
+	})
+
+	return credentials, err
+}
+
+func (s *boltStore) SetStreamerCredentials(credentials *models.StreamerCredentials) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		buf, err := json.Marshal(credentials)
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(streamerCredentialsBucket).Put(itob(credentials.StreamerID), buf)
+	})
+}
+
+func (s *boltStore) CreateStreamerToken(token *models.StreamerToken) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		token.CreatedAt = time.Now()
+
+		buf, err := json.Marshal(token)
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(streamerTokensBucket).Put([]byte(token.Hash), buf)
+	})
+}
+
+func (s *boltStore) GetStreamerToken(hash string) (token models.StreamerToken, err error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return token, err
+	}
+	defer tx.Rollback()
+
+	bytes := tx.Bucket(streamerTokensBucket).Get([]byte(hash))
+	if bytes == nil {
+		return token, store.ErrNotFound
+	}
+
+	if err := json.Unmarshal(bytes, &token); err != nil {
+		return token, err
+	}
+
+	return token, nil
+}
+
+func (s *boltStore) DeleteStreamerToken(hash string) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(streamerTokensBucket).Delete([]byte(hash))
+	})
+}
+
+func (s *boltStore) DeleteStreamerTokens(streamerID int) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		return deleteStreamerTokens(tx, streamerID)
+	})
+}
+
+func deleteStreamerTokens(tx *bolt.Tx, streamerID int) error {
+	bucket := tx.Bucket(streamerTokensBucket)
+
+	hashes := [][]byte{}
+
+	cursor := bucket.Cursor()
+	for k, data := cursor.First(); k != nil; k, data = cursor.Next() {
+		var i models.StreamerToken
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		if i.StreamerID == streamerID {
+			hashes = append(hashes, k)
+		}
+	}
+
+	for _, hash := range hashes {
+		if err := bucket.Delete(hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
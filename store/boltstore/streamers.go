@@ -84,6 +84,10 @@ func (s *boltStore) CreateStreamer(streamer *models.Streamer) error {
 
 	bucket := tx.Bucket(streamersBucket)
 
+	if err := checkStreamKeyFree(bucket, streamer); err != nil {
+		return err
+	}
+
 	seq, _ := bucket.NextSequence()
 	streamer.ID = int(seq)
 	streamer.CreatedAt = time.Now()
@@ -115,6 +119,14 @@ func (s *boltStore) UpdateStreamer(streamer *models.Streamer) error {
 
 	bucket := tx.Bucket(streamersBucket)
 
+	if bucket.Get(itob(streamer.ID)) == nil {
+		return store.ErrNotFound
+	}
+
+	if err := checkStreamKeyFree(bucket, streamer); err != nil {
+		return err
+	}
+
 	streamer.UpdatedAt = time.Now()
 
 	buf, err := json.Marshal(streamer)
@@ -131,6 +143,113 @@ func (s *boltStore) UpdateStreamer(streamer *models.Streamer) error {
 
 func (s *boltStore) DeleteStreamer(id int) error {
 	return s.Update(func(tx *bolt.Tx) error {
-		return tx.Bucket(streamersBucket).Delete(itob(id))
+		return deleteStreamer(tx, id)
+	})
+}
+
+func (s *boltStore) ImportStreamers(create []*models.Streamer, update []*models.Streamer, remove []int) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(streamersBucket)
+
+		for _, id := range remove {
+			if err := deleteStreamer(tx, id); err != nil {
+				return err
+			}
+		}
+
+		for _, streamer := range update {
+			if bucket.Get(itob(streamer.ID)) == nil {
+				return store.ErrNotFound
+			}
+
+			if err := checkStreamKeyFree(bucket, streamer); err != nil {
+				return err
+			}
+
+			streamer.UpdatedAt = time.Now()
+
+			buf, err := json.Marshal(streamer)
+			if err != nil {
+				return err
+			}
+
+			if err := bucket.Put(itob(streamer.ID), buf); err != nil {
+				return err
+			}
+		}
+
+		for _, streamer := range create {
+			if err := checkStreamKeyFree(bucket, streamer); err != nil {
+				return err
+			}
+
+			seq, _ := bucket.NextSequence()
+			streamer.ID = int(seq)
+			streamer.CreatedAt = time.Now()
+			streamer.UpdatedAt = time.Now()
+
+			buf, err := json.Marshal(streamer)
+			if err != nil {
+				return err
+			}
+
+			if err := bucket.Put(itob(streamer.ID), buf); err != nil {
+				return err
+			}
+		}
+
+		return nil
 	})
 }
+
+// checkStreamKeyFree returns store.ErrExists if another streamer already has the streamer's stream key,
+// as its own key or an extra one
+func checkStreamKeyFree(bucket *bolt.Bucket, streamer *models.Streamer) error {
+	if bucket.Tx().Bucket(streamKeysBucket).Get([]byte(streamer.StreamKey)) != nil {
+		return store.ErrExists
+	}
+
+	cursor := bucket.Cursor()
+
+	for k, data := cursor.First(); k != nil; k, data = cursor.Next() {
+		var i models.Streamer
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		if i.StreamKey == streamer.StreamKey && i.ID != streamer.ID {
+			return store.ErrExists
+		}
+	}
+
+	return nil
+}
+
+// deleteStreamer removes the streamer along with its logins, api tokens, schedules, stream keys and usage
+func deleteStreamer(tx *bolt.Tx, id int) error {
+	if err := tx.Bucket(streamersBucket).Delete(itob(id)); err != nil {
+		return err
+	}
+
+	if err := tx.Bucket(streamerCredentialsBucket).Delete(itob(id)); err != nil {
+		return err
+	}
+
+	if err := deleteStreamerTokens(tx, id); err != nil {
+		return err
+	}
+
+	if err := deleteStreamerAPITokens(tx, id); err != nil {
+		return err
+	}
+
+	if err := deleteStreamerSchedules(tx, id); err != nil {
+		return err
+	}
+
+	if err := deleteStreamerStreamKeys(tx, id); err != nil {
+		return err
+	}
+
+	return deleteStreamerUsage(tx, id)
+}
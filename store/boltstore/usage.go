@@ -0,0 +1,78 @@
+package boltstore
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Usage is keyed by streamer id then month so a streamer's months sit together
+
+func usageKey(streamerID int, month string) []byte {
+	return append(itob(streamerID), month...)
+}
+
+func (s *boltStore) GetStreamerUsage(streamerID int, month string) (usage models.StreamerUsage, err error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return usage, err
+	}
+	defer tx.Rollback()
+
+	data := tx.Bucket(streamerUsageBucket).Get(usageKey(streamerID, month))
+	if data == nil {
+		return usage, store.ErrNotFound
+	}
+
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return usage, err
+	}
+
+	return usage, nil
+}
+
+func (s *boltStore) AddStreamerUsage(streamerID int, month string, seconds int64) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(streamerUsageBucket)
+		key := usageKey(streamerID, month)
+
+		usage := models.StreamerUsage{StreamerID: streamerID, Month: month}
+		if data := bucket.Get(key); data != nil {
+			if err := json.Unmarshal(data, &usage); err != nil {
+				return err
+			}
+		}
+
+		usage.StreamedSeconds += seconds
+
+		buf, err := json.Marshal(usage)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(key, buf)
+	})
+}
+
+func deleteStreamerUsage(tx *bolt.Tx, streamerID int) error {
+	bucket := tx.Bucket(streamerUsageBucket)
+	prefix := itob(streamerID)
+
+	keys := [][]byte{}
+
+	cursor := bucket.Cursor()
+	for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+		keys = append(keys, k)
+	}
+
+	for _, k := range keys {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
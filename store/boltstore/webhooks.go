@@ -0,0 +1,202 @@
+package boltstore
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *boltStore) GetWebhooks() ([]models.Webhook, error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cursor := tx.Bucket(webhooksBucket).Cursor()
+
+	webhooks := make([]models.Webhook, 0)
+	for k, data := cursor.First(); k != nil; k, data = cursor.Next() {
+		var i models.Webhook
+		if err := json.Unmarshal(data, &i); err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, i)
+	}
+
+	return webhooks, nil
+}
+
+func (s *boltStore) GetWebhookByID(id int) (webhook models.Webhook, err error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return webhook, err
+	}
+	defer tx.Rollback()
+
+	bytes := tx.Bucket(webhooksBucket).Get(itob(id))
+	if bytes == nil {
+		return webhook, store.ErrNotFound
+	}
+
+	if err := json.Unmarshal(bytes, &webhook); err != nil {
+		return webhook, err
+	}
+
+	return webhook, nil
+}
+
+func (s *boltStore) CreateWebhook(webhook *models.Webhook) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(webhooksBucket)
+
+		seq, _ := bucket.NextSequence()
+		webhook.ID = int(seq)
+		webhook.CreatedAt = time.Now()
+		webhook.UpdatedAt = time.Now()
+
+		buf, err := json.Marshal(webhook)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(webhook.ID), buf)
+	})
+}
+
+func (s *boltStore) UpdateWebhook(webhook *models.Webhook) error {
+	if webhook.ID <= 0 {
+		return errors.New("invalid webhook id")
+	}
+
+	return s.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(webhooksBucket)
+
+		if bucket.Get(itob(webhook.ID)) == nil {
+			return store.ErrNotFound
+		}
+
+		webhook.UpdatedAt = time.Now()
+
+		buf, err := json.Marshal(webhook)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(webhook.ID), buf)
+	})
+}
+
+// DeleteWebhook removes the webhook along with its deliveries
+func (s *boltStore) DeleteWebhook(id int) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(webhooksBucket).Delete(itob(id)); err != nil {
+			return err
+		}
+
+		bucket := tx.Bucket(webhookDeliveriesBucket)
+
+		ids := [][]byte{}
+
+		cursor := bucket.Cursor()
+		for k, data := cursor.First(); k != nil; k, data = cursor.Next() {
+			var i models.WebhookDelivery
+			if err := json.Unmarshal(data, &i); err != nil {
+				return err
+			}
+
+			if i.WebhookID == id {
+				ids = append(ids, k)
+			}
+		}
+
+		for _, k := range ids {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *boltStore) CreateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(webhookDeliveriesBucket)
+
+		seq, _ := bucket.NextSequence()
+		delivery.ID = int(seq)
+
+		buf, err := json.Marshal(delivery)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(delivery.ID), buf)
+	})
+}
+
+func (s *boltStore) PruneWebhookDeliveries(keep int) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(webhookDeliveriesBucket)
+
+		ids := [][]byte{}
+		kept := map[int]int{}
+
+		// Keys are sequential so walking back from the last is newest first
+		cursor := bucket.Cursor()
+		for k, data := cursor.Last(); k != nil; k, data = cursor.Prev() {
+			var i models.WebhookDelivery
+			if err := json.Unmarshal(data, &i); err != nil {
+				return err
+			}
+
+			if kept[i.WebhookID] < keep {
+				kept[i.WebhookID]++
+				continue
+			}
+
+			ids = append(ids, k)
+		}
+
+		for _, k := range ids {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetWebhookDeliveries returns the newest deliveries for the webhook first
+func (s *boltStore) GetWebhookDeliveries(webhookID int, limit int) ([]models.WebhookDelivery, error) {
+	tx, err := s.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cursor := tx.Bucket(webhookDeliveriesBucket).Cursor()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for k, data := cursor.Last(); k != nil && len(deliveries) < limit; k, data = cursor.Prev() {
+		var i models.WebhookDelivery
+		if err := json.Unmarshal(data, &i); err != nil {
+			return nil, err
+		}
+
+		if i.WebhookID != webhookID {
+			continue
+		}
+
+		deliveries = append(deliveries, i)
+	}
+
+	return deliveries, nil
+}
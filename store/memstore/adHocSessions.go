@@ -0,0 +1,55 @@
+package memstore
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/geekgonecrazy/prismplus/models"
+)
+
+func (s *memStore) GetAdHocSessions() ([]models.AdHocSession, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	keys := make([]string, 0, len(s.adHocSessions))
+	for key := range s.adHocSessions {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	sessions := make([]models.AdHocSession, 0)
+	for _, key := range keys {
+		var i models.AdHocSession
+		if err := json.Unmarshal(s.adHocSessions[key], &i); err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, i)
+	}
+
+	return sessions, nil
+}
+
+func (s *memStore) SetAdHocSession(session *models.AdHocSession) error {
+	s.Lock()
+	defer s.Unlock()
+
+	buf, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	s.adHocSessions[session.Key] = buf
+
+	return nil
+}
+
+func (s *memStore) DeleteAdHocSession(key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.adHocSessions, key)
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package memstore
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+func (s *memStore) GetAdmins() ([]models.Admin, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	admins := make([]models.Admin, 0)
+	err := s.admins.each(func(id int, data []byte) error {
+		var i models.Admin
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		admins = append(admins, i)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return admins, nil
+}
+
+func (s *memStore) GetAdminByID(id int) (admin models.Admin, err error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	err = s.admins.get(id, &admin)
+
+	return admin, err
+}
+
+func (s *memStore) CreateAdmin(admin *models.Admin) error {
+	s.Lock()
+	defer s.Unlock()
+
+	admin.ID = s.admins.nextSequence()
+	admin.CreatedAt = time.Now()
+	admin.UpdatedAt = time.Now()
+
+	return s.admins.put(admin.ID, admin)
+}
+
+func (s *memStore) UpdateAdmin(admin *models.Admin) error {
+	if admin.ID <= 0 {
+		return errors.New("invalid admin id")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if !s.admins.exists(admin.ID) {
+		return store.ErrNotFound
+	}
+
+	admin.UpdatedAt = time.Now()
+
+	return s.admins.put(admin.ID, admin)
+}
+
+func (s *memStore) DeleteAdmin(id int) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.admins.delete(id)
+
+	ids := []int{}
+	err := s.adminAPIKeys.each(func(k int, data []byte) error {
+		var i models.AdminAPIKey
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		if i.AdminID == id {
+			ids = append(ids, k)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, k := range ids {
+		s.adminAPIKeys.delete(k)
+	}
+
+	return nil
+}
+
+func (s *memStore) GetAdminAPIKeys() ([]models.AdminAPIKey, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	keys := make([]models.AdminAPIKey, 0)
+	err := s.adminAPIKeys.each(func(id int, data []byte) error {
+		var i models.AdminAPIKey
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		keys = append(keys, i)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (s *memStore) GetAdminAPIKeyByID(id int) (key models.AdminAPIKey, err error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	err = s.adminAPIKeys.get(id, &key)
+
+	return key, err
+}
+
+func (s *memStore) CreateAdminAPIKey(key *models.AdminAPIKey) error {
+	s.Lock()
+	defer s.Unlock()
+
+	key.ID = s.adminAPIKeys.nextSequence()
+	key.CreatedAt = time.Now()
+
+	return s.adminAPIKeys.put(key.ID, key)
+}
+
+func (s *memStore) UpdateAdminAPIKey(key *models.AdminAPIKey) error {
+	if key.ID <= 0 {
+		return errors.New("invalid admin api key id")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	// Don't resurrect a key that was deleted while it was in use
+	if !s.adminAPIKeys.exists(key.ID) {
+		return store.ErrNotFound
+	}
+
+	return s.adminAPIKeys.put(key.ID, key)
+}
+
+func (s *memStore) DeleteAdminAPIKey(id int) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.adminAPIKeys.delete(id)
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package memstore
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+func (s *memStore) GetAPITokens() ([]models.APIToken, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	tokens := make([]models.APIToken, 0)
+	err := s.apiTokens.each(func(id int, data []byte) error {
+		var i models.APIToken
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		tokens = append(tokens, i)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (s *memStore) GetAPITokenByID(id int) (token models.APIToken, err error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	err = s.apiTokens.get(id, &token)
+
+	return token, err
+}
+
+func (s *memStore) CreateAPIToken(token *models.APIToken) error {
+	s.Lock()
+	defer s.Unlock()
+
+	token.ID = s.apiTokens.nextSequence()
+	token.CreatedAt = time.Now()
+
+	return s.apiTokens.put(token.ID, token)
+}
+
+func (s *memStore) UpdateAPIToken(token *models.APIToken) error {
+	if token.ID <= 0 {
+		return errors.New("invalid api token id")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	// Don't resurrect a token that was deleted while it was in use
+	if !s.apiTokens.exists(token.ID) {
+		return store.ErrNotFound
+	}
+
+	return s.apiTokens.put(token.ID, token)
+}
+
+func (s *memStore) DeleteAPIToken(id int) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.apiTokens.delete(id)
+
+	return nil
+}
+
+// deleteStreamerAPITokens removes tokens bound to or created by the streamer
+func (s *memStore) deleteStreamerAPITokens(streamerID int) error {
+	ids := []int{}
+	err := s.apiTokens.each(func(id int, data []byte) error {
+		var i models.APIToken
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		if i.StreamerID == streamerID || i.CreatedByStreamerID == streamerID {
+			ids = append(ids, id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		s.apiTokens.delete(id)
+	}
+
+	return nil
+}
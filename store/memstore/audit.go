@@ -0,0 +1,46 @@
+package memstore
+
+import (
+	"github.com/geekgonecrazy/prismplus/models"
+)
+
+func (s *memStore) CreateAuditEntry(entry *models.AuditEntry) error {
+	s.Lock()
+	defer s.Unlock()
+
+	entry.ID = s.audit.nextSequence()
+
+	return s.audit.put(entry.ID, entry)
+}
+
+// GetAuditEntries walks the audit log from newest to oldest
+func (s *memStore) GetAuditEntries(query models.AuditQuery) (page models.AuditPage, err error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	page.Entries = make([]models.AuditEntry, 0)
+	for _, id := range s.audit.newestFirst(query.Before) {
+		var i models.AuditEntry
+		if err := s.audit.get(id, &i); err != nil {
+			return page, err
+		}
+
+		// Entries are in time order so nothing older will match either
+		if !query.Since.IsZero() && i.Time.Before(query.Since) {
+			break
+		}
+
+		if query.StreamerID != 0 && i.StreamerID != query.StreamerID {
+			continue
+		}
+
+		if len(page.Entries) == query.Limit {
+			page.Next = page.Entries[len(page.Entries)-1].ID
+			break
+		}
+
+		page.Entries = append(page.Entries, i)
+	}
+
+	return page, nil
+}
@@ -0,0 +1,185 @@
+package memstore
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+// memStore keeps everything in memory and loses it on restart.  Records are kept json encoded, the same
+// as bolt, so callers never share memory with the store
+type memStore struct {
+	sync.RWMutex
+
+	streamers           *bucket
+	streamerCredentials *bucket
+	streamerTokens      map[string][]byte
+	admins              *bucket
+	adminAPIKeys        *bucket
+	apiTokens           *bucket
+	audit               *bucket
+	sessionHistory      *bucket
+	adHocSessions       map[string][]byte
+	schedules           *bucket
+	streamKeys          *bucket
+	streamKeyIndex      map[string]int
+	streamerUsage       map[string][]byte
+	webhooks            *bucket
+	webhookDeliveries   *bucket
+}
+
+func init() {
+	store.Register("memory", func(location string) (store.Store, error) {
+		return New(), nil
+	})
+}
+
+// New creates an empty memory store
+func New() store.Store {
+	return &memStore{
+		streamers:           newBucket(),
+		streamerCredentials: newBucket(),
+		streamerTokens:      map[string][]byte{},
+		admins:              newBucket(),
+		adminAPIKeys:        newBucket(),
+		apiTokens:           newBucket(),
+		audit:               newBucket(),
+		sessionHistory:      newBucket(),
+		adHocSessions:       map[string][]byte{},
+		schedules:           newBucket(),
+		streamKeys:          newBucket(),
+		streamKeyIndex:      map[string]int{},
+		streamerUsage:       map[string][]byte{},
+		webhooks:            newBucket(),
+		webhookDeliveries:   newBucket(),
+	}
+}
+
+func (s *memStore) CheckDb() error {
+	return nil
+}
+
+// Backup writes every bucket as json
+func (s *memStore) Backup(w io.Writer) error {
+	s.RLock()
+	defer s.RUnlock()
+
+	dump := map[string]interface{}{
+		"streamers":           s.streamers.dump(),
+		"streamerCredentials": s.streamerCredentials.dump(),
+		"streamerTokens":      s.streamerTokens,
+		"admins":              s.admins.dump(),
+		"adminAPIKeys":        s.adminAPIKeys.dump(),
+		"apiTokens":           s.apiTokens.dump(),
+		"audit":               s.audit.dump(),
+		"sessionHistory":      s.sessionHistory.dump(),
+		"adHocSessions":       s.adHocSessions,
+		"schedules":           s.schedules.dump(),
+		"streamKeys":          s.streamKeys.dump(),
+		"streamerUsage":       s.streamerUsage,
+		"webhooks":            s.webhooks.dump(),
+		"webhookDeliveries":   s.webhookDeliveries.dump(),
+	}
+
+	return json.NewEncoder(w).Encode(dump)
+}
+
+func (s *memStore) BackupExtension() string {
+	return "json"
+}
+
+// bucket holds json encoded records by id
+type bucket struct {
+	sequence int
+	records  map[int][]byte
+}
+
+func newBucket() *bucket {
+	return &bucket{records: map[int][]byte{}}
+}
+
+func (b *bucket) nextSequence() int {
+	b.sequence++
+	return b.sequence
+}
+
+// get decodes the record into v
+func (b *bucket) get(id int, v interface{}) error {
+	data, ok := b.records[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func (b *bucket) exists(id int) bool {
+	_, ok := b.records[id]
+	return ok
+}
+
+func (b *bucket) put(id int, v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	b.records[id] = buf
+
+	return nil
+}
+
+func (b *bucket) delete(id int) {
+	delete(b.records, id)
+}
+
+// ids returns the ids oldest first, the same order as the keys in a bolt bucket
+func (b *bucket) ids() []int {
+	ids := make([]int, 0, len(b.records))
+	for id := range b.records {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	return ids
+}
+
+// each decodes every record in id order with decode, stopping at the first error
+func (b *bucket) each(decode func(id int, data []byte) error) error {
+	for _, id := range b.ids() {
+		if err := decode(id, b.records[id]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *bucket) dump() []json.RawMessage {
+	records := make([]json.RawMessage, 0, len(b.records))
+	for _, id := range b.ids() {
+		records = append(records, b.records[id])
+	}
+
+	return records
+}
+
+// newestFirst returns the ids below before, newest first.  0 returns them all
+func (b *bucket) newestFirst(before int) []int {
+	ids := b.ids()
+
+	newest := make([]int, 0, len(ids))
+	for n := len(ids) - 1; n >= 0; n-- {
+		if before > 0 && ids[n] >= before {
+			continue
+		}
+
+		newest = append(newest, ids[n])
+	}
+
+	return newest
+}
@@ -0,0 +1,46 @@
+package memstore
+
+import (
+	"github.com/geekgonecrazy/prismplus/models"
+)
+
+func (s *memStore) CreateSessionHistory(history *models.SessionHistory) error {
+	s.Lock()
+	defer s.Unlock()
+
+	history.ID = s.sessionHistory.nextSequence()
+
+	return s.sessionHistory.put(history.ID, history)
+}
+
+// GetSessionHistory walks the history from newest to oldest
+func (s *memStore) GetSessionHistory(query models.SessionHistoryQuery) (page models.SessionHistoryPage, err error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	page.Sessions = make([]models.SessionHistory, 0)
+	for _, id := range s.sessionHistory.newestFirst(query.Before) {
+		var i models.SessionHistory
+		if err := s.sessionHistory.get(id, &i); err != nil {
+			return page, err
+		}
+
+		// Records are stored as broadcasts end so nothing older will match either
+		if !query.Since.IsZero() && i.EndedAt.Before(query.Since) {
+			break
+		}
+
+		if query.StreamerID != 0 && i.StreamerID != query.StreamerID {
+			continue
+		}
+
+		if len(page.Sessions) == query.Limit {
+			page.Next = page.Sessions[len(page.Sessions)-1].ID
+			break
+		}
+
+		page.Sessions = append(page.Sessions, i)
+	}
+
+	return page, nil
+}
@@ -0,0 +1,103 @@
+package memstore
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+func (s *memStore) GetSchedules() ([]models.Schedule, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	schedules := make([]models.Schedule, 0)
+	err := s.schedules.each(func(id int, data []byte) error {
+		var i models.Schedule
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		schedules = append(schedules, i)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+func (s *memStore) GetScheduleByID(id int) (schedule models.Schedule, err error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	err = s.schedules.get(id, &schedule)
+
+	return schedule, err
+}
+
+func (s *memStore) CreateSchedule(schedule *models.Schedule) error {
+	s.Lock()
+	defer s.Unlock()
+
+	schedule.ID = s.schedules.nextSequence()
+	schedule.CreatedAt = time.Now()
+	schedule.UpdatedAt = time.Now()
+
+	return s.schedules.put(schedule.ID, schedule)
+}
+
+func (s *memStore) UpdateSchedule(schedule *models.Schedule) error {
+	if schedule.ID <= 0 {
+		return errors.New("invalid schedule id")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if !s.schedules.exists(schedule.ID) {
+		return store.ErrNotFound
+	}
+
+	schedule.UpdatedAt = time.Now()
+
+	return s.schedules.put(schedule.ID, schedule)
+}
+
+func (s *memStore) DeleteSchedule(id int) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.schedules.delete(id)
+
+	return nil
+}
+
+func (s *memStore) deleteStreamerSchedules(streamerID int) error {
+	ids := []int{}
+	err := s.schedules.each(func(id int, data []byte) error {
+		var i models.Schedule
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		if i.StreamerID == streamerID {
+			ids = append(ids, id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		s.schedules.delete(id)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package memstore
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+func (s *memStore) GetStreamKeys(streamerID int) ([]models.StreamKey, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	streamKeys := make([]models.StreamKey, 0)
+	err := s.streamKeys.each(func(id int, data []byte) error {
+		var i models.StreamKey
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		if i.StreamerID == streamerID {
+			streamKeys = append(streamKeys, i)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return streamKeys, nil
+}
+
+func (s *memStore) GetStreamKey(key string) (streamKey models.StreamKey, err error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	id, ok := s.streamKeyIndex[key]
+	if !ok {
+		return streamKey, store.ErrNotFound
+	}
+
+	err = s.streamKeys.get(id, &streamKey)
+
+	return streamKey, err
+}
+
+func (s *memStore) CreateStreamKey(streamKey *models.StreamKey) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.streamKeyIndex[streamKey.Key]; ok {
+		return store.ErrExists
+	}
+
+	// Keys are unique across streamers' own keys too
+	if err := s.checkStreamKeyFree(&models.Streamer{StreamKey: streamKey.Key}); err != nil {
+		return err
+	}
+
+	streamKey.ID = s.streamKeys.nextSequence()
+	streamKey.CreatedAt = time.Now()
+
+	if err := s.streamKeys.put(streamKey.ID, streamKey); err != nil {
+		return err
+	}
+
+	s.streamKeyIndex[streamKey.Key] = streamKey.ID
+
+	return nil
+}
+
+func (s *memStore) DeleteStreamKey(id int) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.deleteStreamKeys(func(streamKey models.StreamKey) bool {
+		return streamKey.ID == id
+	})
+}
+
+func (s *memStore) deleteStreamerStreamKeys(streamerID int) error {
+	return s.deleteStreamKeys(func(streamKey models.StreamKey) bool {
+		return streamKey.StreamerID == streamerID
+	})
+}
+
+func (s *memStore) deleteStreamKeys(match func(streamKey models.StreamKey) bool) error {
+	removed := []models.StreamKey{}
+	err := s.streamKeys.each(func(id int, data []byte) error {
+		var i models.StreamKey
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		if match(i) {
+			removed = append(removed, i)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, streamKey := range removed {
+		s.streamKeys.delete(streamKey.ID)
+		delete(s.streamKeyIndex, streamKey.Key)
+	}
+
+	return nil
+}
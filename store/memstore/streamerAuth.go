@@ -0,0 +1,117 @@
+package memstore
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+func (s *memStore) GetStreamerCredentials(streamerID int) (credentials models.StreamerCredentials, err error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	err = s.streamerCredentials.get(streamerID, &credentials)
+
+	return credentials, err
+}
+
+func (s *memStore) ConsumeStreamerMagicLink(hash string) (credentials models.StreamerCredentials, err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if hash == "" {
+		return credentials, store.ErrNotFound
+	}
+
+	for _, id := range s.streamerCredentials.ids() {
+		var i models.StreamerCredentials
+		if err := s.streamerCredentials.get(id, &i); err != nil {
+			return credentials, err
+		}
+
+		if i.MagicLinkHash != hash {
+			continue
+		}
+
+		credentials = i
+
+		i.MagicLinkHash = ""
+		i.MagicLinkExpiresAt = time.Time{}
+
+		return credentials, s.streamerCredentials.put(id, &i)
+	}
+
+	return credentials, store.ErrNotFound
+}
+
+func (s *memStore) SetStreamerCredentials(credentials *models.StreamerCredentials) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.streamerCredentials.put(credentials.StreamerID, credentials)
+}
+
+func (s *memStore) CreateStreamerToken(token *models.StreamerToken) error {
+	s.Lock()
+	defer s.Unlock()
+
+	token.CreatedAt = time.Now()
+
+	buf, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	s.streamerTokens[token.Hash] = buf
+
+	return nil
+}
+
+func (s *memStore) GetStreamerToken(hash string) (token models.StreamerToken, err error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	data, ok := s.streamerTokens[hash]
+	if !ok {
+		return token, store.ErrNotFound
+	}
+
+	if err := json.Unmarshal(data, &token); err != nil {
+		return token, err
+	}
+
+	return token, nil
+}
+
+func (s *memStore) DeleteStreamerToken(hash string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.streamerTokens, hash)
+
+	return nil
+}
+
+func (s *memStore) DeleteStreamerTokens(streamerID int) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.deleteStreamerTokens(streamerID)
+}
+
+func (s *memStore) deleteStreamerTokens(streamerID int) error {
+	for hash, data := range s.streamerTokens {
+		var i models.StreamerToken
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		if i.StreamerID == streamerID {
+			delete(s.streamerTokens, hash)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package memstore
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+func (s *memStore) GetStreamers() ([]models.Streamer, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	streamers := make([]models.Streamer, 0)
+	err := s.streamers.each(func(id int, data []byte) error {
+		var i models.Streamer
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		streamers = append(streamers, i)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return streamers, nil
+}
+
+func (s *memStore) GetStreamerByID(id int) (streamer models.Streamer, err error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	err = s.streamers.get(id, &streamer)
+
+	return streamer, err
+}
+
+func (s *memStore) GetStreamerByStreamKey(key string) (streamer models.Streamer, err error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.streamerByStreamKey(key)
+}
+
+func (s *memStore) streamerByStreamKey(key string) (streamer models.Streamer, err error) {
+	for _, id := range s.streamers.ids() {
+		var i models.Streamer
+		if err := s.streamers.get(id, &i); err != nil {
+			return streamer, err
+		}
+
+		if i.StreamKey == key {
+			return i, nil
+		}
+	}
+
+	return streamer, store.ErrNotFound
+}
+
+func (s *memStore) CreateStreamer(streamer *models.Streamer) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.checkStreamKeyFree(streamer); err != nil {
+		return err
+	}
+
+	streamer.ID = s.streamers.nextSequence()
+	streamer.CreatedAt = time.Now()
+	streamer.UpdatedAt = time.Now()
+
+	return s.streamers.put(streamer.ID, streamer)
+}
+
+func (s *memStore) UpdateStreamer(streamer *models.Streamer) error {
+	if streamer.ID <= 0 {
+		return errors.New("invalid service id")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if !s.streamers.exists(streamer.ID) {
+		return store.ErrNotFound
+	}
+
+	if err := s.checkStreamKeyFree(streamer); err != nil {
+		return err
+	}
+
+	streamer.UpdatedAt = time.Now()
+
+	return s.streamers.put(streamer.ID, streamer)
+}
+
+func (s *memStore) DeleteStreamer(id int) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.deleteStreamer(id)
+}
+
+func (s *memStore) ImportStreamers(create []*models.Streamer, update []*models.Streamer, remove []int) error {
+	s.Lock()
+	defer s.Unlock()
+
+	// Check everything first so a failed import leaves nothing half done, like a rolled back transaction
+	removed := map[int]bool{}
+	for _, id := range remove {
+		removed[id] = true
+	}
+
+	for _, streamer := range update {
+		if !s.streamers.exists(streamer.ID) || removed[streamer.ID] {
+			return store.ErrNotFound
+		}
+	}
+
+	// Stream keys are checked in the same order bolt and sqlite write them in
+	keys := map[string]int{}
+	current := map[int]string{}
+	for _, id := range s.streamers.ids() {
+		if removed[id] {
+			continue
+		}
+
+		var i models.Streamer
+		if err := s.streamers.get(id, &i); err != nil {
+			return err
+		}
+
+		keys[i.StreamKey] = id
+		current[id] = i.StreamKey
+	}
+
+	// Extra stream keys can't be taken either, unless their streamer is being removed
+	for key, id := range s.streamKeyIndex {
+		var i models.StreamKey
+		if err := s.streamKeys.get(id, &i); err != nil {
+			return err
+		}
+
+		if !removed[i.StreamerID] {
+			keys[key] = -1
+		}
+	}
+
+	for _, streamer := range update {
+		if id, ok := keys[streamer.StreamKey]; ok && id != streamer.ID {
+			return store.ErrExists
+		}
+
+		delete(keys, current[streamer.ID])
+		keys[streamer.StreamKey] = streamer.ID
+	}
+
+	for _, streamer := range create {
+		if _, ok := keys[streamer.StreamKey]; ok {
+			return store.ErrExists
+		}
+
+		keys[streamer.StreamKey] = 0
+	}
+
+	for _, id := range remove {
+		if err := s.deleteStreamer(id); err != nil {
+			return err
+		}
+	}
+
+	for _, streamer := range update {
+		streamer.UpdatedAt = time.Now()
+
+		if err := s.streamers.put(streamer.ID, streamer); err != nil {
+			return err
+		}
+	}
+
+	for _, streamer := range create {
+		streamer.ID = s.streamers.nextSequence()
+		streamer.CreatedAt = time.Now()
+		streamer.UpdatedAt = time.Now()
+
+		if err := s.streamers.put(streamer.ID, streamer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkStreamKeyFree returns store.ErrExists if another streamer already has the streamer's stream key,
+// as its own key or an extra one
+func (s *memStore) checkStreamKeyFree(streamer *models.Streamer) error {
+	if _, ok := s.streamKeyIndex[streamer.StreamKey]; ok {
+		return store.ErrExists
+	}
+
+	existing, err := s.streamerByStreamKey(streamer.StreamKey)
+	if err == nil && existing.ID != streamer.ID {
+		return store.ErrExists
+	}
+
+	if err != nil && err != store.ErrNotFound {
+		return err
+	}
+
+	return nil
+}
+
+// deleteStreamer removes the streamer along with its logins, api tokens, schedules, stream keys and usage
+func (s *memStore) deleteStreamer(id int) error {
+	s.streamers.delete(id)
+	s.streamerCredentials.delete(id)
+
+	if err := s.deleteStreamerTokens(id); err != nil {
+		return err
+	}
+
+	if err := s.deleteStreamerAPITokens(id); err != nil {
+		return err
+	}
+
+	if err := s.deleteStreamerSchedules(id); err != nil {
+		return err
+	}
+
+	if err := s.deleteStreamerStreamKeys(id); err != nil {
+		return err
+	}
+
+	s.deleteStreamerUsage(id)
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package memstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+func usageKey(streamerID int, month string) string {
+	return fmt.Sprintf("%d/%s", streamerID, month)
+}
+
+func (s *memStore) GetStreamerUsage(streamerID int, month string) (usage models.StreamerUsage, err error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	data, ok := s.streamerUsage[usageKey(streamerID, month)]
+	if !ok {
+		return usage, store.ErrNotFound
+	}
+
+	err = json.Unmarshal(data, &usage)
+
+	return usage, err
+}
+
+func (s *memStore) AddStreamerUsage(streamerID int, month string, seconds int64) error {
+	s.Lock()
+	defer s.Unlock()
+
+	key := usageKey(streamerID, month)
+
+	usage := models.StreamerUsage{StreamerID: streamerID, Month: month}
+	if data, ok := s.streamerUsage[key]; ok {
+		if err := json.Unmarshal(data, &usage); err != nil {
+			return err
+		}
+	}
+
+	usage.StreamedSeconds += seconds
+
+	buf, err := json.Marshal(usage)
+	if err != nil {
+		return err
+	}
+
+	s.streamerUsage[key] = buf
+
+	return nil
+}
+
+func (s *memStore) deleteStreamerUsage(streamerID int) {
+	prefix := fmt.Sprintf("%d/", streamerID)
+
+	for key := range s.streamerUsage {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.streamerUsage, key)
+		}
+	}
+}
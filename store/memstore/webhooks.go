@@ -0,0 +1,157 @@
+package memstore
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+func (s *memStore) GetWebhooks() ([]models.Webhook, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	webhooks := make([]models.Webhook, 0)
+	err := s.webhooks.each(func(id int, data []byte) error {
+		var i models.Webhook
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		webhooks = append(webhooks, i)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (s *memStore) GetWebhookByID(id int) (webhook models.Webhook, err error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	err = s.webhooks.get(id, &webhook)
+
+	return webhook, err
+}
+
+func (s *memStore) CreateWebhook(webhook *models.Webhook) error {
+	s.Lock()
+	defer s.Unlock()
+
+	webhook.ID = s.webhooks.nextSequence()
+	webhook.CreatedAt = time.Now()
+	webhook.UpdatedAt = time.Now()
+
+	return s.webhooks.put(webhook.ID, webhook)
+}
+
+func (s *memStore) UpdateWebhook(webhook *models.Webhook) error {
+	if webhook.ID <= 0 {
+		return errors.New("invalid webhook id")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if !s.webhooks.exists(webhook.ID) {
+		return store.ErrNotFound
+	}
+
+	webhook.UpdatedAt = time.Now()
+
+	return s.webhooks.put(webhook.ID, webhook)
+}
+
+// DeleteWebhook removes the webhook along with its deliveries
+func (s *memStore) DeleteWebhook(id int) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.webhooks.delete(id)
+
+	ids := []int{}
+	err := s.webhookDeliveries.each(func(k int, data []byte) error {
+		var i models.WebhookDelivery
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		if i.WebhookID == id {
+			ids = append(ids, k)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, k := range ids {
+		s.webhookDeliveries.delete(k)
+	}
+
+	return nil
+}
+
+func (s *memStore) CreateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delivery.ID = s.webhookDeliveries.nextSequence()
+
+	return s.webhookDeliveries.put(delivery.ID, delivery)
+}
+
+func (s *memStore) PruneWebhookDeliveries(keep int) error {
+	s.Lock()
+	defer s.Unlock()
+
+	kept := map[int]int{}
+	for _, id := range s.webhookDeliveries.newestFirst(0) {
+		var i models.WebhookDelivery
+		if err := s.webhookDeliveries.get(id, &i); err != nil {
+			return err
+		}
+
+		if kept[i.WebhookID] < keep {
+			kept[i.WebhookID]++
+			continue
+		}
+
+		s.webhookDeliveries.delete(id)
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries returns the newest deliveries for the webhook first
+func (s *memStore) GetWebhookDeliveries(webhookID int, limit int) ([]models.WebhookDelivery, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for _, id := range s.webhookDeliveries.newestFirst(0) {
+		if len(deliveries) >= limit {
+			break
+		}
+
+		var i models.WebhookDelivery
+		if err := s.webhookDeliveries.get(id, &i); err != nil {
+			return nil, err
+		}
+
+		if i.WebhookID != webhookID {
+			continue
+		}
+
+		deliveries = append(deliveries, i)
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Opener opens a store at location, the part of the store url after scheme://
+type Opener func(location string) (Store, error)
+
+var (
+	driversLock sync.Mutex
+	drivers     = map[string]Opener{}
+)
+
+// Register makes a store implementation available to Open under scheme.  Drivers call it from init
+func Register(scheme string, opener Opener) {
+	driversLock.Lock()
+	defer driversLock.Unlock()
+
+	if _, exists := drivers[scheme]; exists {
+		panic("store: driver registered twice for " + scheme)
+	}
+
+	drivers[scheme] = opener
+}
+
+// Drivers returns the registered schemes in order
+func Drivers() []string {
+	driversLock.Lock()
+	defer driversLock.Unlock()
+
+	schemes := make([]string, 0, len(drivers))
+	for scheme := range drivers {
+		schemes = append(schemes, scheme)
+	}
+
+	sort.Strings(schemes)
+
+	return schemes
+}
+
+// Open opens the store for a url like bolt://./data.bbolt, sqlite:///var/lib/prismplus/data.sqlite or memory://
+func Open(url string) (Store, error) {
+	parts := strings.SplitN(url, "://", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("store url %q must look like scheme://location", url)
+	}
+
+	scheme, location := parts[0], parts[1]
+
+	driversLock.Lock()
+	opener, ok := drivers[scheme]
+	driversLock.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown store %q, expected one of %s", scheme, strings.Join(Drivers(), ", "))
+	}
+
+	return opener(location)
+}
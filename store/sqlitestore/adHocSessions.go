@@ -0,0 +1,46 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/geekgonecrazy/prismplus/models"
+)
+
+func (s *sqliteStore) GetAdHocSessions() ([]models.AdHocSession, error) {
+	sessions := make([]models.AdHocSession, 0)
+	err := each(s, func(data []byte) error {
+		var i models.AdHocSession
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		sessions = append(sessions, i)
+
+		return nil
+	}, "SELECT data FROM ad_hoc_sessions ORDER BY key")
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (s *sqliteStore) SetAdHocSession(session *models.AdHocSession) error {
+	return s.update(func(tx *sql.Tx) error {
+		buf, err := json.Marshal(session)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("INSERT OR REPLACE INTO ad_hoc_sessions (key, data) VALUES (?, ?)", session.Key, buf)
+
+		return err
+	})
+}
+
+func (s *sqliteStore) DeleteAdHocSession(key string) error {
+	_, err := s.Exec("DELETE FROM ad_hoc_sessions WHERE key = ?", key)
+
+	return err
+}
@@ -0,0 +1,160 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+)
+
+func (s *sqliteStore) GetAdmins() ([]models.Admin, error) {
+	admins := make([]models.Admin, 0)
+	err := each(s, func(data []byte) error {
+		var i models.Admin
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		admins = append(admins, i)
+
+		return nil
+	}, "SELECT data FROM admins ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	return admins, nil
+}
+
+func (s *sqliteStore) GetAdminByID(id int) (admin models.Admin, err error) {
+	err = get(s, &admin, "SELECT data FROM admins WHERE id = ?", id)
+
+	return admin, err
+}
+
+func (s *sqliteStore) CreateAdmin(admin *models.Admin) error {
+	return s.update(func(tx *sql.Tx) error {
+		id, err := nextSequence(tx, "admins")
+		if err != nil {
+			return err
+		}
+
+		admin.ID = id
+		admin.CreatedAt = time.Now()
+		admin.UpdatedAt = time.Now()
+
+		return putAdmin(tx, admin)
+	})
+}
+
+func (s *sqliteStore) UpdateAdmin(admin *models.Admin) error {
+	if admin.ID <= 0 {
+		return errors.New("invalid admin id")
+	}
+
+	return s.update(func(tx *sql.Tx) error {
+		if err := mustExist(tx, "admins", admin.ID); err != nil {
+			return err
+		}
+
+		admin.UpdatedAt = time.Now()
+
+		return putAdmin(tx, admin)
+	})
+}
+
+func putAdmin(tx *sql.Tx, admin *models.Admin) error {
+	buf, err := json.Marshal(admin)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT OR REPLACE INTO admins (id, data) VALUES (?, ?)", admin.ID, buf)
+
+	return err
+}
+
+func (s *sqliteStore) DeleteAdmin(id int) error {
+	return s.update(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM admins WHERE id = ?", id); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec("DELETE FROM admin_api_keys WHERE admin_id = ?", id)
+
+		return err
+	})
+}
+
+func (s *sqliteStore) GetAdminAPIKeys() ([]models.AdminAPIKey, error) {
+	keys := make([]models.AdminAPIKey, 0)
+	err := each(s, func(data []byte) error {
+		var i models.AdminAPIKey
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		keys = append(keys, i)
+
+		return nil
+	}, "SELECT data FROM admin_api_keys ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (s *sqliteStore) GetAdminAPIKeyByID(id int) (key models.AdminAPIKey, err error) {
+	err = get(s, &key, "SELECT data FROM admin_api_keys WHERE id = ?", id)
+
+	return key, err
+}
+
+func (s *sqliteStore) CreateAdminAPIKey(key *models.AdminAPIKey) error {
+	return s.update(func(tx *sql.Tx) error {
+		id, err := nextSequence(tx, "admin_api_keys")
+		if err != nil {
+			return err
+		}
+
+		key.ID = id
+		key.CreatedAt = time.Now()
+
+		return putAdminAPIKey(tx, key)
+	})
+}
+
+func (s *sqliteStore) UpdateAdminAPIKey(key *models.AdminAPIKey) error {
+	if key.ID <= 0 {
+		return errors.New("invalid admin api key id")
+	}
+
+	return s.update(func(tx *sql.Tx) error {
+		// Don't resurrect a key that was deleted while it was in use
+		if err := mustExist(tx, "admin_api_keys", key.ID); err != nil {
+			return err
+		}
+
+		return putAdminAPIKey(tx, key)
+	})
+}
+
+func putAdminAPIKey(tx *sql.Tx, key *models.AdminAPIKey) error {
+	buf, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT OR REPLACE INTO admin_api_keys (id, admin_id, data) VALUES (?, ?, ?)", key.ID, key.AdminID, buf)
+
+	return err
+}
+
+func (s *sqliteStore) DeleteAdminAPIKey(id int) error {
+	_, err := s.Exec("DELETE FROM admin_api_keys WHERE id = ?", id)
+
+	return err
+}
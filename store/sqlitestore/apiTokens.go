@@ -0,0 +1,82 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+)
+
+func (s *sqliteStore) GetAPITokens() ([]models.APIToken, error) {
+	tokens := make([]models.APIToken, 0)
+	err := each(s, func(data []byte) error {
+		var i models.APIToken
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		tokens = append(tokens, i)
+
+		return nil
+	}, "SELECT data FROM api_tokens ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (s *sqliteStore) GetAPITokenByID(id int) (token models.APIToken, err error) {
+	err = get(s, &token, "SELECT data FROM api_tokens WHERE id = ?", id)
+
+	return token, err
+}
+
+func (s *sqliteStore) CreateAPIToken(token *models.APIToken) error {
+	return s.update(func(tx *sql.Tx) error {
+		id, err := nextSequence(tx, "api_tokens")
+		if err != nil {
+			return err
+		}
+
+		token.ID = id
+		token.CreatedAt = time.Now()
+
+		return putAPIToken(tx, token)
+	})
+}
+
+func (s *sqliteStore) UpdateAPIToken(token *models.APIToken) error {
+	if token.ID <= 0 {
+		return errors.New("invalid api token id")
+	}
+
+	return s.update(func(tx *sql.Tx) error {
+		// Don't resurrect a token that was deleted while it was in use
+		if err := mustExist(tx, "api_tokens", token.ID); err != nil {
+			return err
+		}
+
+		return putAPIToken(tx, token)
+	})
+}
+
+func putAPIToken(tx *sql.Tx, token *models.APIToken) error {
+	buf, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT OR REPLACE INTO api_tokens (id, streamer_id, created_by_streamer_id, data) VALUES (?, ?, ?, ?)",
+		token.ID, token.StreamerID, token.CreatedByStreamerID, buf)
+
+	return err
+}
+
+func (s *sqliteStore) DeleteAPIToken(id int) error {
+	_, err := s.Exec("DELETE FROM api_tokens WHERE id = ?", id)
+
+	return err
+}
@@ -0,0 +1,55 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/geekgonecrazy/prismplus/models"
+)
+
+func (s *sqliteStore) CreateAuditEntry(entry *models.AuditEntry) error {
+	return s.update(func(tx *sql.Tx) error {
+		id, err := nextSequence(tx, "audit")
+		if err != nil {
+			return err
+		}
+
+		entry.ID = id
+
+		buf, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("INSERT INTO audit (id, streamer_id, data) VALUES (?, ?, ?)", entry.ID, entry.StreamerID, buf)
+
+		return err
+	})
+}
+
+// GetAuditEntries walks the audit log from newest to oldest
+func (s *sqliteStore) GetAuditEntries(query models.AuditQuery) (page models.AuditPage, err error) {
+	page.Entries = make([]models.AuditEntry, 0)
+	err = each(s, func(data []byte) error {
+		var i models.AuditEntry
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		// Entries are in time order so nothing older will match either
+		if !query.Since.IsZero() && i.Time.Before(query.Since) {
+			return errStop
+		}
+
+		if len(page.Entries) == query.Limit {
+			page.Next = page.Entries[len(page.Entries)-1].ID
+			return errStop
+		}
+
+		page.Entries = append(page.Entries, i)
+
+		return nil
+	}, "SELECT data FROM audit WHERE (?1 = 0 OR id < ?1) AND (?2 = 0 OR streamer_id = ?2) ORDER BY id DESC", query.Before, query.StreamerID)
+
+	return page, err
+}
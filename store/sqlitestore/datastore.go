@@ -0,0 +1,202 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/store"
+	_ "modernc.org/sqlite"
+)
+
+type sqliteStore struct {
+	*sql.DB
+}
+
+// Records are kept as json like in bolt, with the columns needed for lookups alongside.  sequences hands
+// out ids the same way bolt's NextSequence does, so deleted ids are never reused.  Stream keys used to have
+// a plain index, it is swapped for a unique one
+const schema = `
+CREATE TABLE IF NOT EXISTS sequences (name TEXT PRIMARY KEY, value INTEGER NOT NULL);
+CREATE TABLE IF NOT EXISTS streamers (id INTEGER PRIMARY KEY, stream_key TEXT NOT NULL, data BLOB NOT NULL);
+DROP INDEX IF EXISTS streamers_stream_key;
+CREATE UNIQUE INDEX IF NOT EXISTS streamers_stream_key_unique ON streamers (stream_key);
+CREATE TABLE IF NOT EXISTS streamer_credentials (streamer_id INTEGER PRIMARY KEY, magic_link_hash TEXT NOT NULL, data BLOB NOT NULL);
+CREATE INDEX IF NOT EXISTS streamer_credentials_magic_link_hash ON streamer_credentials (magic_link_hash);
+CREATE TABLE IF NOT EXISTS streamer_tokens (hash TEXT PRIMARY KEY, streamer_id INTEGER NOT NULL, data BLOB NOT NULL);
+CREATE INDEX IF NOT EXISTS streamer_tokens_streamer_id ON streamer_tokens (streamer_id);
+CREATE TABLE IF NOT EXISTS admins (id INTEGER PRIMARY KEY, data BLOB NOT NULL);
+CREATE TABLE IF NOT EXISTS admin_api_keys (id INTEGER PRIMARY KEY, admin_id INTEGER NOT NULL, data BLOB NOT NULL);
+CREATE TABLE IF NOT EXISTS api_tokens (id INTEGER PRIMARY KEY, streamer_id INTEGER NOT NULL, created_by_streamer_id INTEGER NOT NULL, data BLOB NOT NULL);
+CREATE TABLE IF NOT EXISTS audit (id INTEGER PRIMARY KEY, streamer_id INTEGER NOT NULL, data BLOB NOT NULL);
+CREATE TABLE IF NOT EXISTS session_history (id INTEGER PRIMARY KEY, streamer_id INTEGER NOT NULL, data BLOB NOT NULL);
+CREATE TABLE IF NOT EXISTS schedules (id INTEGER PRIMARY KEY, streamer_id INTEGER NOT NULL, data BLOB NOT NULL);
+CREATE TABLE IF NOT EXISTS stream_keys (id INTEGER PRIMARY KEY, streamer_id INTEGER NOT NULL, key TEXT NOT NULL UNIQUE, data BLOB NOT NULL);
+CREATE TABLE IF NOT EXISTS streamer_usage (streamer_id INTEGER NOT NULL, month TEXT NOT NULL, data BLOB NOT NULL, PRIMARY KEY (streamer_id, month));
+CREATE TABLE IF NOT EXISTS ad_hoc_sessions (key TEXT PRIMARY KEY, data BLOB NOT NULL);
+CREATE TABLE IF NOT EXISTS webhooks (id INTEGER PRIMARY KEY, data BLOB NOT NULL);
+CREATE TABLE IF NOT EXISTS webhook_deliveries (id INTEGER PRIMARY KEY, webhook_id INTEGER NOT NULL, data BLOB NOT NULL);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+`
+
+// BusyTimeout is how long Open waits for another process to let go of the database
+var BusyTimeout = 15 * time.Second
+
+// ErrLocked is returned by Open when another process has the database open
+var ErrLocked = errors.New("database is locked by another process")
+
+func init() {
+	store.Register("sqlite", Open)
+}
+
+// Open opens the sqlite database at path, creating it if needed.  The database is locked for as long as
+// it's open, like bolt, so nothing else can write to it behind the server's back
+func Open(path string) (store.Store, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(%d)&_pragma=locking_mode(EXCLUSIVE)&_pragma=journal_mode(WAL)", path, BusyTimeout.Milliseconds())
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// One connection serializes writers inside the process, sqlite only allows one at a time anyway
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+
+		if strings.Contains(err.Error(), "database is locked") {
+			return nil, ErrLocked
+		}
+
+		return nil, err
+	}
+
+	return &sqliteStore{db}, nil
+}
+
+// update runs fn in a transaction, committing if it returns nil
+func (s *sqliteStore) update(fn func(tx *sql.Tx) error) error {
+	tx, err := s.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// nextSequence returns the next id for table
+func nextSequence(tx *sql.Tx, table string) (int, error) {
+	var id int
+	err := tx.QueryRow(`INSERT INTO sequences (name, value) VALUES (?, 1)
+		ON CONFLICT (name) DO UPDATE SET value = value + 1 RETURNING value`, table).Scan(&id)
+
+	return id, err
+}
+
+// queryer is either the database or a transaction
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// get decodes the data column of the single row query returns into v
+func get(q queryer, v interface{}, query string, args ...interface{}) error {
+	var data []byte
+	if err := q.QueryRow(query, args...).Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return store.ErrNotFound
+		}
+
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// each calls decode with the data column of every row query returns, stopping early if it returns errStop
+func each(q queryer, decode func(data []byte) error, query string, args ...interface{}) error {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+
+		if err := decode(data); err != nil {
+			if errors.Is(err, errStop) {
+				break
+			}
+
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+var errStop = errors.New("stop")
+
+// mustExist returns store.ErrNotFound if table has no row for id
+func mustExist(tx *sql.Tx, table string, id int) error {
+	var found int
+	err := tx.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE id = ?", id).Scan(&found)
+	if err != nil {
+		return err
+	}
+
+	if found == 0 {
+		return store.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *sqliteStore) CheckDb() error {
+	return s.Ping()
+}
+
+// Backup writes a compacted copy of the database made with VACUUM INTO
+func (s *sqliteStore) Backup(w io.Writer) error {
+	dir, err := os.MkdirTemp("", "prismplus-backup")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "backup.sqlite")
+
+	if _, err := s.Exec("VACUUM INTO ?", path); err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+
+	return err
+}
+
+func (s *sqliteStore) BackupExtension() string {
+	return "sqlite"
+}
@@ -0,0 +1,55 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/geekgonecrazy/prismplus/models"
+)
+
+func (s *sqliteStore) CreateSessionHistory(history *models.SessionHistory) error {
+	return s.update(func(tx *sql.Tx) error {
+		id, err := nextSequence(tx, "session_history")
+		if err != nil {
+			return err
+		}
+
+		history.ID = id
+
+		buf, err := json.Marshal(history)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("INSERT INTO session_history (id, streamer_id, data) VALUES (?, ?, ?)", history.ID, history.StreamerID, buf)
+
+		return err
+	})
+}
+
+// GetSessionHistory walks the history from newest to oldest
+func (s *sqliteStore) GetSessionHistory(query models.SessionHistoryQuery) (page models.SessionHistoryPage, err error) {
+	page.Sessions = make([]models.SessionHistory, 0)
+	err = each(s, func(data []byte) error {
+		var i models.SessionHistory
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		// Records are stored as broadcasts end so nothing older will match either
+		if !query.Since.IsZero() && i.EndedAt.Before(query.Since) {
+			return errStop
+		}
+
+		if len(page.Sessions) == query.Limit {
+			page.Next = page.Sessions[len(page.Sessions)-1].ID
+			return errStop
+		}
+
+		page.Sessions = append(page.Sessions, i)
+
+		return nil
+	}, "SELECT data FROM session_history WHERE (?1 = 0 OR id < ?1) AND (?2 = 0 OR streamer_id = ?2) ORDER BY id DESC", query.Before, query.StreamerID)
+
+	return page, err
+}
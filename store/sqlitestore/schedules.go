@@ -0,0 +1,83 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+)
+
+func (s *sqliteStore) GetSchedules() ([]models.Schedule, error) {
+	schedules := make([]models.Schedule, 0)
+	err := each(s, func(data []byte) error {
+		var i models.Schedule
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		schedules = append(schedules, i)
+
+		return nil
+	}, "SELECT data FROM schedules ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+func (s *sqliteStore) GetScheduleByID(id int) (schedule models.Schedule, err error) {
+	err = get(s, &schedule, "SELECT data FROM schedules WHERE id = ?", id)
+
+	return schedule, err
+}
+
+func (s *sqliteStore) CreateSchedule(schedule *models.Schedule) error {
+	return s.update(func(tx *sql.Tx) error {
+		id, err := nextSequence(tx, "schedules")
+		if err != nil {
+			return err
+		}
+
+		schedule.ID = id
+		schedule.CreatedAt = time.Now()
+		schedule.UpdatedAt = time.Now()
+
+		return putSchedule(tx, schedule)
+	})
+}
+
+func (s *sqliteStore) UpdateSchedule(schedule *models.Schedule) error {
+	if schedule.ID <= 0 {
+		return errors.New("invalid schedule id")
+	}
+
+	return s.update(func(tx *sql.Tx) error {
+		if err := mustExist(tx, "schedules", schedule.ID); err != nil {
+			return err
+		}
+
+		schedule.UpdatedAt = time.Now()
+
+		return putSchedule(tx, schedule)
+	})
+}
+
+func putSchedule(tx *sql.Tx, schedule *models.Schedule) error {
+	buf, err := json.Marshal(schedule)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT OR REPLACE INTO schedules (id, streamer_id, data) VALUES (?, ?, ?)", schedule.ID, schedule.StreamerID, buf)
+
+	return err
+}
+
+func (s *sqliteStore) DeleteSchedule(id int) error {
+	_, err := s.Exec("DELETE FROM schedules WHERE id = ?", id)
+
+	return err
+}
@@ -0,0 +1,76 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+func (s *sqliteStore) GetStreamKeys(streamerID int) ([]models.StreamKey, error) {
+	streamKeys := make([]models.StreamKey, 0)
+	err := each(s, func(data []byte) error {
+		var i models.StreamKey
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		streamKeys = append(streamKeys, i)
+
+		return nil
+	}, "SELECT data FROM stream_keys WHERE streamer_id = ? ORDER BY id", streamerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return streamKeys, nil
+}
+
+func (s *sqliteStore) GetStreamKey(key string) (streamKey models.StreamKey, err error) {
+	err = get(s, &streamKey, "SELECT data FROM stream_keys WHERE key = ?", key)
+
+	return streamKey, err
+}
+
+func (s *sqliteStore) CreateStreamKey(streamKey *models.StreamKey) error {
+	return s.update(func(tx *sql.Tx) error {
+		var taken int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM stream_keys WHERE key = ?", streamKey.Key).Scan(&taken); err != nil {
+			return err
+		}
+
+		if taken > 0 {
+			return store.ErrExists
+		}
+
+		// Keys are unique across streamers' own keys too
+		if err := checkStreamKeyFree(tx, &models.Streamer{StreamKey: streamKey.Key}); err != nil {
+			return err
+		}
+
+		id, err := nextSequence(tx, "stream_keys")
+		if err != nil {
+			return err
+		}
+
+		streamKey.ID = id
+		streamKey.CreatedAt = time.Now()
+
+		buf, err := json.Marshal(streamKey)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("INSERT INTO stream_keys (id, streamer_id, key, data) VALUES (?, ?, ?, ?)", streamKey.ID, streamKey.StreamerID, streamKey.Key, buf)
+
+		return err
+	})
+}
+
+func (s *sqliteStore) DeleteStreamKey(id int) error {
+	_, err := s.Exec("DELETE FROM stream_keys WHERE id = ?", id)
+
+	return err
+}
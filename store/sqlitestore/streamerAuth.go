@@ -0,0 +1,86 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+func (s *sqliteStore) GetStreamerCredentials(streamerID int) (credentials models.StreamerCredentials, err error) {
+	err = get(s, &credentials, "SELECT data FROM streamer_credentials WHERE streamer_id = ?", streamerID)
+
+	return credentials, err
+}
+
+func (s *sqliteStore) ConsumeStreamerMagicLink(hash string) (credentials models.StreamerCredentials, err error) {
+	if hash == "" {
+		return credentials, store.ErrNotFound
+	}
+
+	err = s.update(func(tx *sql.Tx) error {
+		if err := get(tx, &credentials, "SELECT data FROM streamer_credentials WHERE magic_link_hash = ? ORDER BY streamer_id LIMIT 1", hash); err != nil {
+			return err
+		}
+
+		consumed := credentials
+		consumed.MagicLinkHash = ""
+		consumed.MagicLinkExpiresAt = time.Time{}
+
+		return putStreamerCredentials(tx, &consumed)
+	})
+
+	return credentials, err
+}
+
+func (s *sqliteStore) SetStreamerCredentials(credentials *models.StreamerCredentials) error {
+	return s.update(func(tx *sql.Tx) error {
+		return putStreamerCredentials(tx, credentials)
+	})
+}
+
+func putStreamerCredentials(tx *sql.Tx, credentials *models.StreamerCredentials) error {
+	buf, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT OR REPLACE INTO streamer_credentials (streamer_id, magic_link_hash, data) VALUES (?, ?, ?)", credentials.StreamerID, credentials.MagicLinkHash, buf)
+
+	return err
+}
+
+func (s *sqliteStore) CreateStreamerToken(token *models.StreamerToken) error {
+	return s.update(func(tx *sql.Tx) error {
+		token.CreatedAt = time.Now()
+
+		buf, err := json.Marshal(token)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("INSERT OR REPLACE INTO streamer_tokens (hash, streamer_id, data) VALUES (?, ?, ?)", token.Hash, token.StreamerID, buf)
+
+		return err
+	})
+}
+
+func (s *sqliteStore) GetStreamerToken(hash string) (token models.StreamerToken, err error) {
+	err = get(s, &token, "SELECT data FROM streamer_tokens WHERE hash = ?", hash)
+
+	return token, err
+}
+
+func (s *sqliteStore) DeleteStreamerToken(hash string) error {
+	_, err := s.Exec("DELETE FROM streamer_tokens WHERE hash = ?", hash)
+
+	return err
+}
+
+func (s *sqliteStore) DeleteStreamerTokens(streamerID int) error {
+	_, err := s.Exec("DELETE FROM streamer_tokens WHERE streamer_id = ?", streamerID)
+
+	return err
+}
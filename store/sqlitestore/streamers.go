@@ -0,0 +1,174 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+func (s *sqliteStore) GetStreamers() ([]models.Streamer, error) {
+	streamers := make([]models.Streamer, 0)
+	err := each(s, func(data []byte) error {
+		var i models.Streamer
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		streamers = append(streamers, i)
+
+		return nil
+	}, "SELECT data FROM streamers ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	return streamers, nil
+}
+
+func (s *sqliteStore) GetStreamerByID(id int) (streamer models.Streamer, err error) {
+	err = get(s, &streamer, "SELECT data FROM streamers WHERE id = ?", id)
+
+	return streamer, err
+}
+
+func (s *sqliteStore) GetStreamerByStreamKey(key string) (streamer models.Streamer, err error) {
+	err = get(s, &streamer, "SELECT data FROM streamers WHERE stream_key = ? ORDER BY id LIMIT 1", key)
+
+	return streamer, err
+}
+
+func (s *sqliteStore) CreateStreamer(streamer *models.Streamer) error {
+	return s.update(func(tx *sql.Tx) error {
+		return createStreamer(tx, streamer)
+	})
+}
+
+func (s *sqliteStore) UpdateStreamer(streamer *models.Streamer) error {
+	if streamer.ID <= 0 {
+		return errors.New("invalid service id")
+	}
+
+	return s.update(func(tx *sql.Tx) error {
+		streamer.UpdatedAt = time.Now()
+
+		return updateStreamer(tx, streamer)
+	})
+}
+
+func (s *sqliteStore) DeleteStreamer(id int) error {
+	return s.update(func(tx *sql.Tx) error {
+		return deleteStreamer(tx, id)
+	})
+}
+
+func (s *sqliteStore) ImportStreamers(create []*models.Streamer, update []*models.Streamer, remove []int) error {
+	return s.update(func(tx *sql.Tx) error {
+		for _, id := range remove {
+			if err := deleteStreamer(tx, id); err != nil {
+				return err
+			}
+		}
+
+		for _, streamer := range update {
+			streamer.UpdatedAt = time.Now()
+
+			if err := updateStreamer(tx, streamer); err != nil {
+				return err
+			}
+		}
+
+		for _, streamer := range create {
+			if err := createStreamer(tx, streamer); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func createStreamer(tx *sql.Tx, streamer *models.Streamer) error {
+	if err := checkStreamKeyFree(tx, streamer); err != nil {
+		return err
+	}
+
+	id, err := nextSequence(tx, "streamers")
+	if err != nil {
+		return err
+	}
+
+	streamer.ID = id
+	streamer.CreatedAt = time.Now()
+	streamer.UpdatedAt = time.Now()
+
+	buf, err := json.Marshal(streamer)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO streamers (id, stream_key, data) VALUES (?, ?, ?)", streamer.ID, streamer.StreamKey, buf)
+
+	return err
+}
+
+// updateStreamer returns store.ErrNotFound rather than creating a streamer that isn't there
+func updateStreamer(tx *sql.Tx, streamer *models.Streamer) error {
+	if err := mustExist(tx, "streamers", streamer.ID); err != nil {
+		return err
+	}
+
+	if err := checkStreamKeyFree(tx, streamer); err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(streamer)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE streamers SET stream_key = ?, data = ? WHERE id = ?", streamer.StreamKey, buf, streamer.ID)
+
+	return err
+}
+
+// checkStreamKeyFree returns store.ErrExists if another streamer already has the streamer's stream key,
+// as its own key or an extra one
+func checkStreamKeyFree(tx *sql.Tx, streamer *models.Streamer) error {
+	var taken int
+	err := tx.QueryRow(`SELECT (SELECT COUNT(*) FROM streamers WHERE stream_key = ?1 AND id != ?2)
+		+ (SELECT COUNT(*) FROM stream_keys WHERE key = ?1)`, streamer.StreamKey, streamer.ID).Scan(&taken)
+	if err != nil {
+		return err
+	}
+
+	if taken > 0 {
+		return store.ErrExists
+	}
+
+	return nil
+}
+
+// deleteStreamer removes the streamer along with its logins, api tokens, schedules, stream keys and usage
+func deleteStreamer(tx *sql.Tx, id int) error {
+	statements := []string{
+		"DELETE FROM streamers WHERE id = ?",
+		"DELETE FROM streamer_credentials WHERE streamer_id = ?",
+		"DELETE FROM streamer_tokens WHERE streamer_id = ?",
+		"DELETE FROM api_tokens WHERE streamer_id = ?1 OR created_by_streamer_id = ?1",
+		"DELETE FROM schedules WHERE streamer_id = ?",
+		"DELETE FROM stream_keys WHERE streamer_id = ?",
+		"DELETE FROM streamer_usage WHERE streamer_id = ?",
+	}
+
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+func (s *sqliteStore) GetStreamerUsage(streamerID int, month string) (usage models.StreamerUsage, err error) {
+	err = get(s, &usage, "SELECT data FROM streamer_usage WHERE streamer_id = ? AND month = ?", streamerID, month)
+
+	return usage, err
+}
+
+func (s *sqliteStore) AddStreamerUsage(streamerID int, month string, seconds int64) error {
+	return s.update(func(tx *sql.Tx) error {
+		usage := models.StreamerUsage{StreamerID: streamerID, Month: month}
+
+		err := get(tx, &usage, "SELECT data FROM streamer_usage WHERE streamer_id = ? AND month = ?", streamerID, month)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+
+		usage.StreamedSeconds += seconds
+
+		buf, err := json.Marshal(usage)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("INSERT OR REPLACE INTO streamer_usage (streamer_id, month, data) VALUES (?, ?, ?)", streamerID, month, buf)
+
+		return err
+	})
+}
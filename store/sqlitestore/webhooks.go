@@ -0,0 +1,141 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+)
+
+func (s *sqliteStore) GetWebhooks() ([]models.Webhook, error) {
+	webhooks := make([]models.Webhook, 0)
+	err := each(s, func(data []byte) error {
+		var i models.Webhook
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		webhooks = append(webhooks, i)
+
+		return nil
+	}, "SELECT data FROM webhooks ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (s *sqliteStore) GetWebhookByID(id int) (webhook models.Webhook, err error) {
+	err = get(s, &webhook, "SELECT data FROM webhooks WHERE id = ?", id)
+
+	return webhook, err
+}
+
+func (s *sqliteStore) CreateWebhook(webhook *models.Webhook) error {
+	return s.update(func(tx *sql.Tx) error {
+		id, err := nextSequence(tx, "webhooks")
+		if err != nil {
+			return err
+		}
+
+		webhook.ID = id
+		webhook.CreatedAt = time.Now()
+		webhook.UpdatedAt = time.Now()
+
+		return putWebhook(tx, webhook)
+	})
+}
+
+func (s *sqliteStore) UpdateWebhook(webhook *models.Webhook) error {
+	if webhook.ID <= 0 {
+		return errors.New("invalid webhook id")
+	}
+
+	return s.update(func(tx *sql.Tx) error {
+		if err := mustExist(tx, "webhooks", webhook.ID); err != nil {
+			return err
+		}
+
+		webhook.UpdatedAt = time.Now()
+
+		return putWebhook(tx, webhook)
+	})
+}
+
+func putWebhook(tx *sql.Tx, webhook *models.Webhook) error {
+	buf, err := json.Marshal(webhook)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT OR REPLACE INTO webhooks (id, data) VALUES (?, ?)", webhook.ID, buf)
+
+	return err
+}
+
+// DeleteWebhook removes the webhook along with its deliveries
+func (s *sqliteStore) DeleteWebhook(id int) error {
+	return s.update(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM webhooks WHERE id = ?", id); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec("DELETE FROM webhook_deliveries WHERE webhook_id = ?", id)
+
+		return err
+	})
+}
+
+func (s *sqliteStore) CreateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	return s.update(func(tx *sql.Tx) error {
+		id, err := nextSequence(tx, "webhook_deliveries")
+		if err != nil {
+			return err
+		}
+
+		delivery.ID = id
+
+		buf, err := json.Marshal(delivery)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("INSERT INTO webhook_deliveries (id, webhook_id, data) VALUES (?, ?, ?)", delivery.ID, delivery.WebhookID, buf)
+
+		return err
+	})
+}
+
+func (s *sqliteStore) PruneWebhookDeliveries(keep int) error {
+	return s.update(func(tx *sql.Tx) error {
+		// Ids are sequential so the highest are the newest
+		_, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE id IN (
+			SELECT id FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY webhook_id ORDER BY id DESC) AS n FROM webhook_deliveries)
+			WHERE n > ?)`, keep)
+
+		return err
+	})
+}
+
+// GetWebhookDeliveries returns the newest deliveries for the webhook first
+func (s *sqliteStore) GetWebhookDeliveries(webhookID int, limit int) ([]models.WebhookDelivery, error) {
+	deliveries := make([]models.WebhookDelivery, 0)
+	err := each(s, func(data []byte) error {
+		var i models.WebhookDelivery
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+
+		deliveries = append(deliveries, i)
+
+		return nil
+	}, "SELECT data FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ?", webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
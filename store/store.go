@@ -2,6 +2,7 @@ package store
 
 import (
 	"errors"
+	"io"
 
 	"github.com/geekgonecrazy/prismplus/models"
 )
@@ -14,8 +15,82 @@ type Store interface {
 	GetStreamerByStreamKey(key string) (models.Streamer, error)
 	UpdateStreamer(streamer *models.Streamer) error
 	DeleteStreamer(id int) error
+	// ImportStreamers creates, updates and deletes streamers all at once
+	ImportStreamers(create []*models.Streamer, update []*models.Streamer, remove []int) error
+
+	GetStreamerCredentials(streamerID int) (models.StreamerCredentials, error)
+	// ConsumeStreamerMagicLink clears the magic link with hash and returns the credentials as they were
+	// before, all in one go so a link can only be used once
+	ConsumeStreamerMagicLink(hash string) (models.StreamerCredentials, error)
+	SetStreamerCredentials(credentials *models.StreamerCredentials) error
+
+	CreateStreamerToken(token *models.StreamerToken) error
+	GetStreamerToken(hash string) (models.StreamerToken, error)
+	DeleteStreamerToken(hash string) error
+	DeleteStreamerTokens(streamerID int) error
+
+	GetAdmins() ([]models.Admin, error)
+	GetAdminByID(id int) (models.Admin, error)
+	CreateAdmin(admin *models.Admin) error
+	UpdateAdmin(admin *models.Admin) error
+	DeleteAdmin(id int) error
+
+	GetAdminAPIKeys() ([]models.AdminAPIKey, error)
+	GetAdminAPIKeyByID(id int) (models.AdminAPIKey, error)
+	CreateAdminAPIKey(key *models.AdminAPIKey) error
+	UpdateAdminAPIKey(key *models.AdminAPIKey) error
+	DeleteAdminAPIKey(id int) error
+
+	GetAPITokens() ([]models.APIToken, error)
+	GetAPITokenByID(id int) (models.APIToken, error)
+	CreateAPIToken(token *models.APIToken) error
+	UpdateAPIToken(token *models.APIToken) error
+	DeleteAPIToken(id int) error
+
+	CreateAuditEntry(entry *models.AuditEntry) error
+	GetAuditEntries(query models.AuditQuery) (models.AuditPage, error)
+
+	CreateSessionHistory(history *models.SessionHistory) error
+	GetSessionHistory(query models.SessionHistoryQuery) (models.SessionHistoryPage, error)
+
+	// GetStreamerUsage returns how long the streamer was live in month, formatted 2006-01
+	GetStreamerUsage(streamerID int, month string) (models.StreamerUsage, error)
+	AddStreamerUsage(streamerID int, month string, seconds int64) error
+
+	GetStreamKeys(streamerID int) ([]models.StreamKey, error)
+	// GetStreamKey looks up an extra stream key by the key itself
+	GetStreamKey(key string) (models.StreamKey, error)
+	CreateStreamKey(streamKey *models.StreamKey) error
+	DeleteStreamKey(id int) error
+
+	GetSchedules() ([]models.Schedule, error)
+	GetScheduleByID(id int) (models.Schedule, error)
+	CreateSchedule(schedule *models.Schedule) error
+	UpdateSchedule(schedule *models.Schedule) error
+	DeleteSchedule(id int) error
+
+	GetAdHocSessions() ([]models.AdHocSession, error)
+	SetAdHocSession(session *models.AdHocSession) error
+	DeleteAdHocSession(key string) error
+
+	GetWebhooks() ([]models.Webhook, error)
+	GetWebhookByID(id int) (models.Webhook, error)
+	CreateWebhook(webhook *models.Webhook) error
+	UpdateWebhook(webhook *models.Webhook) error
+	DeleteWebhook(id int) error
+	CreateWebhookDelivery(delivery *models.WebhookDelivery) error
+	GetWebhookDeliveries(webhookID int, limit int) ([]models.WebhookDelivery, error)
+	// PruneWebhookDeliveries removes all but the newest keep deliveries of each webhook
+	PruneWebhookDeliveries(keep int) error
 
 	CheckDb() error
+	// Backup writes a consistent copy of the whole database to w
+	Backup(w io.Writer) error
+	// BackupExtension is the file extension for what Backup writes
+	BackupExtension() string
 }
 
 var ErrNotFound = errors.New("record not found")
+
+// ErrExists is returned when creating a record with a unique key that is already taken
+var ErrExists = errors.New("record already exists")
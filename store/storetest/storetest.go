@@ -0,0 +1,662 @@
+// Package storetest checks that a store.Store behaves the same as the others.  Every backend runs it from
+// its own tests, so a difference between them shows up as a failure in that backend
+package storetest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+// Run runs every conformance test against stores made by open.  Each test gets a new, empty store
+func Run(t *testing.T, open func(t *testing.T) store.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.Store)
+	}{
+		{"Streamers", testStreamers},
+		{"StreamerNotFound", testStreamerNotFound},
+		{"StreamerStreamKeyUnique", testStreamerStreamKeyUnique},
+		{"ImportStreamers", testImportStreamers},
+		{"ImportStreamersFailsWhole", testImportStreamersFailsWhole},
+		{"DeleteStreamerCascades", testDeleteStreamerCascades},
+		{"StreamKeys", testStreamKeys},
+		{"StreamKeysUniqueWithStreamers", testStreamKeysUniqueWithStreamers},
+		{"StreamerCredentials", testStreamerCredentials},
+		{"StreamerTokens", testStreamerTokens},
+		{"Admins", testAdmins},
+		{"AdminAPIKeys", testAdminAPIKeys},
+		{"APITokens", testAPITokens},
+		{"Schedules", testSchedules},
+		{"Webhooks", testWebhooks},
+		{"AdHocSessions", testAdHocSessions},
+		{"Usage", testUsage},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			test.fn(t, open(t))
+		})
+	}
+}
+
+func isNotFound(t *testing.T, what string, err error) {
+	t.Helper()
+
+	if !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("%s returned %v, want %v", what, err, store.ErrNotFound)
+	}
+}
+
+func isExists(t *testing.T, what string, err error) {
+	t.Helper()
+
+	if !errors.Is(err, store.ErrExists) {
+		t.Errorf("%s returned %v, want %v", what, err, store.ErrExists)
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func createStreamer(t *testing.T, s store.Store, name string, streamKey string) *models.Streamer {
+	t.Helper()
+
+	streamer := &models.Streamer{
+		Name:              name,
+		StreamKey:         streamKey,
+		Destinations:      []models.Destination{},
+		Profiles:          []models.Profile{},
+		NextDestinationID: 1,
+	}
+
+	must(t, s.CreateStreamer(streamer))
+
+	return streamer
+}
+
+func testStreamers(t *testing.T, s store.Store) {
+	first := createStreamer(t, s, "first", "first-key")
+	second := createStreamer(t, s, "second", "second-key")
+
+	if first.ID <= 0 || second.ID <= first.ID {
+		t.Errorf("created ids %d and %d, want increasing ids above 0", first.ID, second.ID)
+	}
+
+	if first.CreatedAt.IsZero() || first.UpdatedAt.IsZero() {
+		t.Error("created streamer has no timestamps")
+	}
+
+	got, err := s.GetStreamerByID(first.ID)
+	must(t, err)
+
+	if got.Name != "first" || got.StreamKey != "first-key" {
+		t.Errorf("got %s with key %s, want first with key first-key", got.Name, got.StreamKey)
+	}
+
+	got, err = s.GetStreamerByStreamKey("second-key")
+	must(t, err)
+
+	if got.ID != second.ID {
+		t.Errorf("stream key lookup returned %d, want %d", got.ID, second.ID)
+	}
+
+	first.Destinations = append(first.Destinations, models.Destination{ID: 1, Name: "Twitch", Server: "rtmp://live.twitch.tv/app", Key: "live_1"})
+	first.NextDestinationID = 2
+	must(t, s.UpdateStreamer(first))
+
+	got, err = s.GetStreamerByID(first.ID)
+	must(t, err)
+
+	if len(got.Destinations) != 1 || got.Destinations[0].Key != "live_1" {
+		t.Errorf("update wasn't saved, got destinations %v", got.Destinations)
+	}
+
+	all, err := s.GetStreamers()
+	must(t, err)
+
+	if len(all) != 2 || all[0].ID != first.ID || all[1].ID != second.ID {
+		t.Errorf("got %d streamers, want first and second in id order", len(all))
+	}
+
+	must(t, s.DeleteStreamer(first.ID))
+
+	_, err = s.GetStreamerByID(first.ID)
+	isNotFound(t, "GetStreamerByID after delete", err)
+
+	// Ids aren't reused
+	third := createStreamer(t, s, "third", "third-key")
+	if third.ID <= second.ID {
+		t.Errorf("created id %d after %d was deleted", third.ID, first.ID)
+	}
+}
+
+func testStreamerNotFound(t *testing.T, s store.Store) {
+	_, err := s.GetStreamerByID(42)
+	isNotFound(t, "GetStreamerByID", err)
+
+	_, err = s.GetStreamerByStreamKey("missing")
+	isNotFound(t, "GetStreamerByStreamKey", err)
+
+	missing := &models.Streamer{ID: 42, Name: "missing", StreamKey: "missing"}
+	isNotFound(t, "UpdateStreamer", s.UpdateStreamer(missing))
+
+	// A failed update mustn't create the streamer
+	_, err = s.GetStreamerByID(42)
+	isNotFound(t, "GetStreamerByID after UpdateStreamer", err)
+
+	all, err := s.GetStreamers()
+	must(t, err)
+
+	if len(all) != 0 {
+		t.Errorf("got %d streamers, want none", len(all))
+	}
+}
+
+func testStreamerStreamKeyUnique(t *testing.T, s store.Store) {
+	first := createStreamer(t, s, "first", "shared-key")
+
+	isExists(t, "CreateStreamer with a taken stream key", s.CreateStreamer(&models.Streamer{Name: "second", StreamKey: "shared-key"}))
+
+	second := createStreamer(t, s, "second", "second-key")
+	second.StreamKey = "shared-key"
+	isExists(t, "UpdateStreamer to a taken stream key", s.UpdateStreamer(second))
+
+	got, err := s.GetStreamerByID(second.ID)
+	must(t, err)
+
+	if got.StreamKey != "second-key" {
+		t.Errorf("failed update changed the stream key to %s", got.StreamKey)
+	}
+
+	// Keeping its own key isn't a conflict
+	first.Name = "renamed"
+	must(t, s.UpdateStreamer(first))
+
+	// A key is free again once its streamer moves off it
+	first.StreamKey = "first-key"
+	must(t, s.UpdateStreamer(first))
+
+	second.StreamKey = "shared-key"
+	must(t, s.UpdateStreamer(second))
+}
+
+func testImportStreamers(t *testing.T, s store.Store) {
+	kept := createStreamer(t, s, "kept", "kept-key")
+	removed := createStreamer(t, s, "removed", "removed-key")
+
+	kept.StreamKey = "new-kept-key"
+	created := &models.Streamer{Name: "created", StreamKey: "removed-key"}
+
+	// The removed streamer's key can be reused in the same import
+	must(t, s.ImportStreamers([]*models.Streamer{created}, []*models.Streamer{kept}, []int{removed.ID}))
+
+	if created.ID <= removed.ID {
+		t.Errorf("created id %d, want a new id above %d", created.ID, removed.ID)
+	}
+
+	got, err := s.GetStreamerByStreamKey("new-kept-key")
+	must(t, err)
+
+	if got.ID != kept.ID {
+		t.Errorf("updated key belongs to %d, want %d", got.ID, kept.ID)
+	}
+
+	got, err = s.GetStreamerByStreamKey("removed-key")
+	must(t, err)
+
+	if got.ID != created.ID {
+		t.Errorf("reused key belongs to %d, want %d", got.ID, created.ID)
+	}
+
+	_, err = s.GetStreamerByID(removed.ID)
+	isNotFound(t, "GetStreamerByID of a removed streamer", err)
+}
+
+func testImportStreamersFailsWhole(t *testing.T, s store.Store) {
+	existing := createStreamer(t, s, "existing", "existing-key")
+	other := createStreamer(t, s, "other", "other-key")
+
+	missing := &models.Streamer{ID: 42, Name: "missing", StreamKey: "missing-key"}
+	created := &models.Streamer{Name: "created", StreamKey: "created-key"}
+	isNotFound(t, "ImportStreamers updating a missing streamer", s.ImportStreamers([]*models.Streamer{created}, []*models.Streamer{missing}, []int{other.ID}))
+
+	duplicate := &models.Streamer{Name: "duplicate", StreamKey: "existing-key"}
+	isExists(t, "ImportStreamers creating a taken stream key", s.ImportStreamers([]*models.Streamer{duplicate}, nil, []int{other.ID}))
+
+	twice := []*models.Streamer{{Name: "one", StreamKey: "twice"}, {Name: "two", StreamKey: "twice"}}
+	isExists(t, "ImportStreamers creating the same stream key twice", s.ImportStreamers(twice, nil, nil))
+
+	all, err := s.GetStreamers()
+	must(t, err)
+
+	if len(all) != 2 || all[0].ID != existing.ID || all[1].ID != other.ID {
+		t.Errorf("failed imports changed the streamers, got %d of them", len(all))
+	}
+}
+
+func testDeleteStreamerCascades(t *testing.T, s store.Store) {
+	streamer := createStreamer(t, s, "streamer", "streamer-key")
+	other := createStreamer(t, s, "other", "other-key")
+
+	for _, id := range []int{streamer.ID, other.ID} {
+		must(t, s.SetStreamerCredentials(&models.StreamerCredentials{StreamerID: id, PasswordHash: []byte("hash")}))
+		must(t, s.CreateStreamerToken(&models.StreamerToken{Hash: fmt.Sprint("token-", id), StreamerID: id, ExpiresAt: time.Now().Add(time.Hour)}))
+		must(t, s.CreateStreamKey(&models.StreamKey{StreamerID: id, Name: "encoder", Key: fmt.Sprint("extra-", id)}))
+		must(t, s.CreateSchedule(&models.Schedule{StreamerID: id, Name: "weekly", Cron: "0 20 * * 5", DurationSeconds: 3600}))
+		must(t, s.AddStreamerUsage(id, "2024-01", 60))
+	}
+
+	must(t, s.DeleteStreamer(streamer.ID))
+
+	_, err := s.GetStreamerCredentials(streamer.ID)
+	isNotFound(t, "GetStreamerCredentials of a deleted streamer", err)
+
+	streamKeys, err := s.GetStreamKeys(streamer.ID)
+	must(t, err)
+
+	if len(streamKeys) != 0 {
+		t.Errorf("deleted streamer still has %d stream keys", len(streamKeys))
+	}
+
+	schedules, err := s.GetSchedules()
+	must(t, err)
+
+	for _, schedule := range schedules {
+		if schedule.StreamerID == streamer.ID {
+			t.Error("deleted streamer still has a schedule")
+		}
+	}
+
+	_, err = s.GetStreamerUsage(streamer.ID, "2024-01")
+	isNotFound(t, "GetStreamerUsage of a deleted streamer", err)
+
+	// Nothing of the other streamer's goes with it
+	if _, err := s.GetStreamerCredentials(other.ID); err != nil {
+		t.Errorf("other streamer lost its credentials: %v", err)
+	}
+
+	streamKeys, err = s.GetStreamKeys(other.ID)
+	must(t, err)
+
+	if len(streamKeys) != 1 {
+		t.Errorf("other streamer has %d stream keys, want 1", len(streamKeys))
+	}
+
+	usage, err := s.GetStreamerUsage(other.ID, "2024-01")
+	must(t, err)
+
+	if usage.StreamedSeconds != 60 {
+		t.Errorf("other streamer has %d seconds of usage, want 60", usage.StreamedSeconds)
+	}
+}
+
+func testStreamKeys(t *testing.T, s store.Store) {
+	streamer := createStreamer(t, s, "streamer", "streamer-key")
+
+	first := &models.StreamKey{StreamerID: streamer.ID, Name: "desk", Key: "desk-key"}
+	must(t, s.CreateStreamKey(first))
+
+	second := &models.StreamKey{StreamerID: streamer.ID, Name: "laptop", Key: "laptop-key", Profile: "travel"}
+	must(t, s.CreateStreamKey(second))
+
+	if first.ID <= 0 || second.ID <= first.ID {
+		t.Errorf("created ids %d and %d, want increasing ids above 0", first.ID, second.ID)
+	}
+
+	got, err := s.GetStreamKey("laptop-key")
+	must(t, err)
+
+	if got.ID != second.ID || got.StreamerID != streamer.ID || got.Profile != "travel" {
+		t.Errorf("got %+v, want %+v", got, *second)
+	}
+
+	isExists(t, "CreateStreamKey with a taken key", s.CreateStreamKey(&models.StreamKey{StreamerID: streamer.ID, Name: "again", Key: "desk-key"}))
+
+	all, err := s.GetStreamKeys(streamer.ID)
+	must(t, err)
+
+	if len(all) != 2 || all[0].ID != first.ID || all[1].ID != second.ID {
+		t.Errorf("got %d stream keys, want desk and laptop in id order", len(all))
+	}
+
+	_, err = s.GetStreamKey("missing")
+	isNotFound(t, "GetStreamKey", err)
+
+	must(t, s.DeleteStreamKey(first.ID))
+
+	_, err = s.GetStreamKey("desk-key")
+	isNotFound(t, "GetStreamKey after delete", err)
+
+	// The key is free again
+	must(t, s.CreateStreamKey(&models.StreamKey{StreamerID: streamer.ID, Name: "desk", Key: "desk-key"}))
+}
+
+// Streamers' own keys and their extra keys are one keyspace, so a key always resolves to one streamer
+func testStreamKeysUniqueWithStreamers(t *testing.T, s store.Store) {
+	streamer := createStreamer(t, s, "streamer", "streamer-key")
+	other := createStreamer(t, s, "other", "other-key")
+
+	must(t, s.CreateStreamKey(&models.StreamKey{StreamerID: streamer.ID, Name: "desk", Key: "desk-key"}))
+
+	isExists(t, "CreateStreamKey with a streamer's own key", s.CreateStreamKey(&models.StreamKey{StreamerID: other.ID, Name: "taken", Key: "streamer-key"}))
+	isExists(t, "CreateStreamer with an extra key", s.CreateStreamer(&models.Streamer{Name: "third", StreamKey: "desk-key"}))
+
+	other.StreamKey = "desk-key"
+	isExists(t, "UpdateStreamer to an extra key", s.UpdateStreamer(other))
+
+	other.StreamKey = "other-key"
+	created := &models.Streamer{Name: "created", StreamKey: "desk-key"}
+	isExists(t, "ImportStreamers creating an extra key", s.ImportStreamers([]*models.Streamer{created}, nil, nil))
+
+	other.StreamKey = "desk-key"
+	isExists(t, "ImportStreamers updating to an extra key", s.ImportStreamers(nil, []*models.Streamer{other}, nil))
+
+	// Removing the streamer frees its extra keys
+	must(t, s.ImportStreamers([]*models.Streamer{created}, nil, []int{streamer.ID}))
+}
+
+func testStreamerCredentials(t *testing.T, s store.Store) {
+	streamer := createStreamer(t, s, "streamer", "streamer-key")
+
+	_, err := s.GetStreamerCredentials(streamer.ID)
+	isNotFound(t, "GetStreamerCredentials", err)
+
+	credentials := &models.StreamerCredentials{
+		StreamerID:         streamer.ID,
+		PasswordHash:       []byte("hash"),
+		MagicLinkHash:      "magic",
+		MagicLinkExpiresAt: time.Now().Add(time.Hour),
+	}
+	must(t, s.SetStreamerCredentials(credentials))
+
+	got, err := s.GetStreamerCredentials(streamer.ID)
+	must(t, err)
+
+	if string(got.PasswordHash) != "hash" || got.MagicLinkHash != "magic" {
+		t.Errorf("got %+v, want %+v", got, *credentials)
+	}
+
+	consumed, err := s.ConsumeStreamerMagicLink("magic")
+	must(t, err)
+
+	if consumed.StreamerID != streamer.ID || consumed.MagicLinkHash != "magic" {
+		t.Errorf("consumed %+v, want the credentials before the link was cleared", consumed)
+	}
+
+	_, err = s.ConsumeStreamerMagicLink("magic")
+	isNotFound(t, "ConsumeStreamerMagicLink a second time", err)
+
+	got, err = s.GetStreamerCredentials(streamer.ID)
+	must(t, err)
+
+	if got.MagicLinkHash != "" || string(got.PasswordHash) != "hash" {
+		t.Errorf("after consuming the link got %+v, want only the link cleared", got)
+	}
+}
+
+func testStreamerTokens(t *testing.T, s store.Store) {
+	streamer := createStreamer(t, s, "streamer", "streamer-key")
+
+	for _, hash := range []string{"one", "two"} {
+		must(t, s.CreateStreamerToken(&models.StreamerToken{Hash: hash, StreamerID: streamer.ID, ExpiresAt: time.Now().Add(time.Hour)}))
+	}
+
+	got, err := s.GetStreamerToken("one")
+	must(t, err)
+
+	if got.StreamerID != streamer.ID {
+		t.Errorf("token belongs to %d, want %d", got.StreamerID, streamer.ID)
+	}
+
+	_, err = s.GetStreamerToken("missing")
+	isNotFound(t, "GetStreamerToken", err)
+
+	must(t, s.DeleteStreamerToken("one"))
+
+	_, err = s.GetStreamerToken("one")
+	isNotFound(t, "GetStreamerToken after delete", err)
+
+	must(t, s.DeleteStreamerTokens(streamer.ID))
+
+	_, err = s.GetStreamerToken("two")
+	isNotFound(t, "GetStreamerToken after deleting the streamer's tokens", err)
+}
+
+func testAdmins(t *testing.T, s store.Store) {
+	admin := &models.Admin{Name: "admin", Role: models.RoleAdmin}
+	must(t, s.CreateAdmin(admin))
+
+	key := &models.AdminAPIKey{AdminID: admin.ID, Name: "cli", Prefix: "abc", Hash: "hash"}
+	must(t, s.CreateAdminAPIKey(key))
+
+	admin.Role = models.RoleViewer
+	must(t, s.UpdateAdmin(admin))
+
+	got, err := s.GetAdminByID(admin.ID)
+	must(t, err)
+
+	if got.Role != models.RoleViewer {
+		t.Errorf("got role %s, want %s", got.Role, models.RoleViewer)
+	}
+
+	missing := &models.Admin{ID: 42, Name: "missing", Role: models.RoleAdmin}
+	isNotFound(t, "UpdateAdmin", s.UpdateAdmin(missing))
+
+	_, err = s.GetAdminByID(42)
+	isNotFound(t, "GetAdminByID", err)
+
+	// An admin's keys go with them
+	must(t, s.DeleteAdmin(admin.ID))
+
+	_, err = s.GetAdminByID(admin.ID)
+	isNotFound(t, "GetAdminByID after delete", err)
+
+	_, err = s.GetAdminAPIKeyByID(key.ID)
+	isNotFound(t, "GetAdminAPIKeyByID after deleting the admin", err)
+
+	all, err := s.GetAdmins()
+	must(t, err)
+
+	if len(all) != 0 {
+		t.Errorf("got %d admins, want none", len(all))
+	}
+}
+
+func testAdminAPIKeys(t *testing.T, s store.Store) {
+	admin := &models.Admin{Name: "admin", Role: models.RoleAdmin}
+	must(t, s.CreateAdmin(admin))
+
+	key := &models.AdminAPIKey{AdminID: admin.ID, Name: "cli", Prefix: "abc", Hash: "hash"}
+	must(t, s.CreateAdminAPIKey(key))
+
+	now := time.Now().UTC().Truncate(time.Second)
+	key.LastUsedAt = &now
+	must(t, s.UpdateAdminAPIKey(key))
+
+	got, err := s.GetAdminAPIKeyByID(key.ID)
+	must(t, err)
+
+	if got.LastUsedAt == nil || !got.LastUsedAt.Equal(now) {
+		t.Errorf("got lastUsedAt %v, want %v", got.LastUsedAt, now)
+	}
+
+	isNotFound(t, "UpdateAdminAPIKey", s.UpdateAdminAPIKey(&models.AdminAPIKey{ID: 42, AdminID: admin.ID}))
+
+	_, err = s.GetAdminAPIKeyByID(42)
+	isNotFound(t, "GetAdminAPIKeyByID", err)
+
+	must(t, s.DeleteAdminAPIKey(key.ID))
+
+	all, err := s.GetAdminAPIKeys()
+	must(t, err)
+
+	if len(all) != 0 {
+		t.Errorf("got %d keys, want none", len(all))
+	}
+}
+
+func testAPITokens(t *testing.T, s store.Store) {
+	streamer := createStreamer(t, s, "streamer", "streamer-key")
+
+	token := &models.APIToken{Name: "bot", Prefix: "abc", Hash: "hash", Scopes: []string{"sessions:read"}, StreamerID: streamer.ID}
+	must(t, s.CreateAPIToken(token))
+
+	token.Name = "renamed"
+	must(t, s.UpdateAPIToken(token))
+
+	got, err := s.GetAPITokenByID(token.ID)
+	must(t, err)
+
+	if got.Name != "renamed" || len(got.Scopes) != 1 {
+		t.Errorf("got %+v, want %+v", got, *token)
+	}
+
+	isNotFound(t, "UpdateAPIToken", s.UpdateAPIToken(&models.APIToken{ID: 42, Name: "missing"}))
+
+	_, err = s.GetAPITokenByID(42)
+	isNotFound(t, "GetAPITokenByID", err)
+
+	must(t, s.DeleteAPIToken(token.ID))
+
+	_, err = s.GetAPITokenByID(token.ID)
+	isNotFound(t, "GetAPITokenByID after delete", err)
+}
+
+func testSchedules(t *testing.T, s store.Store) {
+	streamer := createStreamer(t, s, "streamer", "streamer-key")
+
+	schedule := &models.Schedule{StreamerID: streamer.ID, Name: "weekly", Cron: "0 20 * * 5", DurationSeconds: 3600, DestinationIDs: []int{1}}
+	must(t, s.CreateSchedule(schedule))
+
+	schedule.HardStop = true
+	must(t, s.UpdateSchedule(schedule))
+
+	got, err := s.GetScheduleByID(schedule.ID)
+	must(t, err)
+
+	if !got.HardStop || got.Cron != "0 20 * * 5" {
+		t.Errorf("got %+v, want %+v", got, *schedule)
+	}
+
+	isNotFound(t, "UpdateSchedule", s.UpdateSchedule(&models.Schedule{ID: 42, StreamerID: streamer.ID}))
+
+	_, err = s.GetScheduleByID(42)
+	isNotFound(t, "GetScheduleByID", err)
+
+	must(t, s.DeleteSchedule(schedule.ID))
+
+	all, err := s.GetSchedules()
+	must(t, err)
+
+	if len(all) != 0 {
+		t.Errorf("got %d schedules, want none", len(all))
+	}
+}
+
+func testWebhooks(t *testing.T, s store.Store) {
+	webhook := &models.Webhook{URL: "https://example.com/hook", Events: []string{"*"}, Secret: "secret"}
+	must(t, s.CreateWebhook(webhook))
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		must(t, s.CreateWebhookDelivery(&models.WebhookDelivery{WebhookID: webhook.ID, Attempt: attempt, Time: time.Now()}))
+	}
+
+	webhook.Disabled = true
+	must(t, s.UpdateWebhook(webhook))
+
+	got, err := s.GetWebhookByID(webhook.ID)
+	must(t, err)
+
+	if !got.Disabled || got.Secret != "secret" {
+		t.Errorf("got %+v, want %+v", got, *webhook)
+	}
+
+	isNotFound(t, "UpdateWebhook", s.UpdateWebhook(&models.Webhook{ID: 42, URL: "https://example.com"}))
+
+	_, err = s.GetWebhookByID(42)
+	isNotFound(t, "GetWebhookByID", err)
+
+	deliveries, err := s.GetWebhookDeliveries(webhook.ID, 2)
+	must(t, err)
+
+	if len(deliveries) != 2 || deliveries[0].Attempt != 3 || deliveries[1].Attempt != 2 {
+		t.Errorf("got %d deliveries, want the newest two newest first", len(deliveries))
+	}
+
+	must(t, s.PruneWebhookDeliveries(1))
+
+	deliveries, err = s.GetWebhookDeliveries(webhook.ID, 10)
+	must(t, err)
+
+	if len(deliveries) != 1 || deliveries[0].Attempt != 3 {
+		t.Errorf("pruning left %d deliveries, want only the newest", len(deliveries))
+	}
+
+	// A webhook's deliveries go with it
+	must(t, s.DeleteWebhook(webhook.ID))
+
+	_, err = s.GetWebhookByID(webhook.ID)
+	isNotFound(t, "GetWebhookByID after delete", err)
+
+	deliveries, err = s.GetWebhookDeliveries(webhook.ID, 10)
+	must(t, err)
+
+	if len(deliveries) != 0 {
+		t.Errorf("deleted webhook still has %d deliveries", len(deliveries))
+	}
+}
+
+func testAdHocSessions(t *testing.T, s store.Store) {
+	session := &models.AdHocSession{Key: "adhoc", Destinations: []models.Destination{{ID: 1, Name: "Twitch", Server: "rtmp://live.twitch.tv/app"}}, NextDestinationID: 2}
+	must(t, s.SetAdHocSession(session))
+
+	// Setting it again replaces it
+	session.Destinations = []models.Destination{}
+	must(t, s.SetAdHocSession(session))
+
+	all, err := s.GetAdHocSessions()
+	must(t, err)
+
+	if len(all) != 1 || all[0].Key != "adhoc" || len(all[0].Destinations) != 0 {
+		t.Errorf("got %+v, want the one session without destinations", all)
+	}
+
+	must(t, s.DeleteAdHocSession("adhoc"))
+
+	all, err = s.GetAdHocSessions()
+	must(t, err)
+
+	if len(all) != 0 {
+		t.Errorf("got %d sessions, want none", len(all))
+	}
+}
+
+func testUsage(t *testing.T, s store.Store) {
+	streamer := createStreamer(t, s, "streamer", "streamer-key")
+
+	_, err := s.GetStreamerUsage(streamer.ID, "2024-01")
+	isNotFound(t, "GetStreamerUsage of a month without any", err)
+
+	must(t, s.AddStreamerUsage(streamer.ID, "2024-01", 60))
+	must(t, s.AddStreamerUsage(streamer.ID, "2024-01", 30))
+	must(t, s.AddStreamerUsage(streamer.ID, "2024-02", 5))
+
+	usage, err := s.GetStreamerUsage(streamer.ID, "2024-01")
+	must(t, err)
+
+	if usage.StreamedSeconds != 90 || usage.StreamerID != streamer.ID || usage.Month != "2024-01" {
+		t.Errorf("got %+v, want 90 seconds in 2024-01", usage)
+	}
+}
@@ -0,0 +1,246 @@
+package streamers
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/helpers"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// TokenTTL is how long a streamer stays logged in
+	TokenTTL = 12 * time.Hour
+	// MagicLinkTTL is how long a magic link can be used for
+	MagicLinkTTL = 15 * time.Minute
+
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrPasswordTooShort   = errors.New("password must be at least 8 characters")
+	ErrWrongPassword      = errors.New("current password is incorrect")
+
+	// _noPasswordHash is compared against when there is no password to check, so a failed login takes
+	// as long whether or not the name exists
+	_noPasswordHash     []byte
+	_noPasswordHashOnce sync.Once
+)
+
+func noPasswordHash() []byte {
+	_noPasswordHashOnce.Do(func() {
+		_noPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("no password"), bcrypt.DefaultCost)
+	})
+
+	return _noPasswordHash
+}
+
+// ChangePassword sets the streamer's password if currentPassword is right.  Streamers who have only ever
+// logged in with a magic link don't have a password to give yet
+func ChangePassword(streamer models.Streamer, currentPassword string, password string) error {
+	credentials, err := getCredentials(streamer.ID)
+	if err != nil {
+		return err
+	}
+
+	if len(credentials.PasswordHash) > 0 && bcrypt.CompareHashAndPassword(credentials.PasswordHash, []byte(currentPassword)) != nil {
+		return ErrWrongPassword
+	}
+
+	return SetPassword(streamer, password)
+}
+
+func SetPassword(streamer models.Streamer, password string) error {
+	if len(password) < 8 {
+		return ErrPasswordTooShort
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	credentials, err := getCredentials(streamer.ID)
+	if err != nil {
+		return err
+	}
+
+	credentials.PasswordHash = hash
+
+	if err := _dataStore.SetStreamerCredentials(&credentials); err != nil {
+		return err
+	}
+
+	// Changing the password logs out everywhere
+	return _dataStore.DeleteStreamerTokens(streamer.ID)
+}
+
+// CreateMagicLink creates a one-time login token for the streamer, replacing any previous one
+func CreateMagicLink(streamer models.Streamer) (*models.StreamerMagicLink, error) {
+	token, err := helpers.NewToken()
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := getCredentials(streamer.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials.MagicLinkHash = helpers.HashToken(token)
+	credentials.MagicLinkExpiresAt = time.Now().Add(MagicLinkTTL)
+
+	if err := _dataStore.SetStreamerCredentials(&credentials); err != nil {
+		return nil, err
+	}
+
+	return &models.StreamerMagicLink{
+		Token:     token,
+		URL:       "/?magic=" + token,
+		ExpiresAt: credentials.MagicLinkExpiresAt,
+	}, nil
+}
+
+// Login checks the password of the streamer with the given name and issues a session token
+func Login(name string, password string) (*models.StreamerLogin, error) {
+	streamer, err := getStreamerByName(name)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+
+	found := err == nil
+	hash := noPasswordHash()
+
+	if found {
+		credentials, err := _dataStore.GetStreamerCredentials(streamer.ID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return nil, err
+		}
+
+		found = len(credentials.PasswordHash) > 0
+		if found {
+			hash = credentials.PasswordHash
+		}
+	}
+
+	// Always compare so the time taken doesn't give away which names exist
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil || !found {
+		return nil, ErrInvalidCredentials
+	}
+
+	return issueToken(streamer.ID)
+}
+
+// getStreamerByName finds the streamer with name, ignoring case.  Names are unique but streamers from
+// before they were might share one, and then none of them match
+func getStreamerByName(name string) (models.Streamer, error) {
+	s, err := _dataStore.GetStreamers()
+	if err != nil {
+		return models.Streamer{}, err
+	}
+
+	matches := []models.Streamer{}
+	for _, streamer := range s {
+		if strings.EqualFold(streamer.Name, name) {
+			matches = append(matches, streamer)
+		}
+	}
+
+	if len(matches) != 1 {
+		return models.Streamer{}, store.ErrNotFound
+	}
+
+	return matches[0], nil
+}
+
+// nameTaken reports whether a streamer other than id already has name
+func nameTaken(name string, id int) (bool, error) {
+	s, err := _dataStore.GetStreamers()
+	if err != nil {
+		return false, err
+	}
+
+	for _, streamer := range s {
+		if streamer.ID != id && strings.EqualFold(streamer.Name, name) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// LoginWithMagicLink consumes a magic link token and issues a session token
+func LoginWithMagicLink(token string) (*models.StreamerLogin, error) {
+	credentials, err := _dataStore.ConsumeStreamerMagicLink(helpers.HashToken(token))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+
+		return nil, err
+	}
+
+	if time.Now().After(credentials.MagicLinkExpiresAt) {
+		return nil, ErrInvalidCredentials
+	}
+
+	return issueToken(credentials.StreamerID)
+}
+
+// GetStreamerByToken returns the streamer a session token was issued to
+func GetStreamerByToken(token string) (models.Streamer, error) {
+	hash := helpers.HashToken(token)
+
+	streamerToken, err := _dataStore.GetStreamerToken(hash)
+	if err != nil {
+		return models.Streamer{}, err
+	}
+
+	if time.Now().After(streamerToken.ExpiresAt) {
+		if err := _dataStore.DeleteStreamerToken(hash); err != nil {
+			return models.Streamer{}, err
+		}
+
+		return models.Streamer{}, store.ErrNotFound
+	}
+
+	return _dataStore.GetStreamerByID(streamerToken.StreamerID)
+}
+
+func Logout(token string) error {
+	return _dataStore.DeleteStreamerToken(helpers.HashToken(token))
+}
+
+func issueToken(streamerID int) (*models.StreamerLogin, error) {
+	token, err := helpers.NewToken()
+	if err != nil {
+		return nil, err
+	}
+
+	streamerToken := models.StreamerToken{
+		Hash:       helpers.HashToken(token),
+		StreamerID: streamerID,
+		ExpiresAt:  time.Now().Add(TokenTTL),
+	}
+
+	if err := _dataStore.CreateStreamerToken(&streamerToken); err != nil {
+		return nil, err
+	}
+
+	return &models.StreamerLogin{
+		Token:     token,
+		ExpiresAt: streamerToken.ExpiresAt,
+	}, nil
+}
+
+func getCredentials(streamerID int) (models.StreamerCredentials, error) {
+	credentials, err := _dataStore.GetStreamerCredentials(streamerID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return credentials, err
+	}
+
+	credentials.StreamerID = streamerID
+
+	return credentials, nil
+}
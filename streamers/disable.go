@@ -0,0 +1,53 @@
+package streamers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/sessions"
+)
+
+var ErrInvalidUntil = errors.New("until must be in the future")
+
+// Disable stops the streamer from publishing or changing anything through the streamer api, until they are
+// enabled again or until passes.  A live session is ended
+func Disable(streamer models.Streamer, disablePayload models.StreamerDisablePayload) (*models.Streamer, error) {
+	now := time.Now()
+
+	if disablePayload.Until != nil && !disablePayload.Until.After(now) {
+		return nil, ErrInvalidUntil
+	}
+
+	streamer.Disabled = &models.StreamerDisabled{
+		Reason:     disablePayload.Reason,
+		Until:      disablePayload.Until,
+		DisabledAt: now,
+	}
+
+	if err := _dataStore.UpdateStreamer(&streamer); err != nil {
+		return nil, err
+	}
+
+	session, _ := sessions.GetSession(streamer.StreamKey)
+	if session == nil {
+		return &streamer, nil
+	}
+
+	if err := endSession(session, "streamer_disabled", disablePayload.Reason); err != nil {
+		return nil, err
+	}
+
+	return &streamer, nil
+}
+
+// Enable lets a disabled streamer publish again
+func Enable(streamer models.Streamer) (*models.Streamer, error) {
+	streamer.Disabled = nil
+
+	if err := _dataStore.UpdateStreamer(&streamer); err != nil {
+		return nil, err
+	}
+
+	return &streamer, nil
+}
@@ -0,0 +1,150 @@
+package streamers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/sessions"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+var (
+	ErrInvalidProfile = errors.New("invalid profile")
+	ErrUnknownProfile = errors.New("unknown profile")
+)
+
+// SetProfile creates or replaces one of the streamer's profiles.  A live session on it switches to the new
+// set of destinations
+func SetProfile(streamer models.Streamer, name string, profilePayload models.ProfilePayload) (*models.Profile, error) {
+	if name == "" || strings.ContainsAny(name, "/ ") {
+		return nil, fmt.Errorf("%w: names can't be empty or contain spaces or slashes", ErrInvalidProfile)
+	}
+
+	profile := models.Profile{
+		Name:           name,
+		DestinationIDs: []int{},
+	}
+
+	for _, id := range profilePayload.DestinationIDs {
+		found := false
+		for _, destination := range streamer.Destinations {
+			if destination.ID == id {
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("%w: destination %d doesn't exist", ErrInvalidProfile, id)
+		}
+
+		profile.DestinationIDs = append(profile.DestinationIDs, id)
+	}
+
+	profiles := []models.Profile{}
+	for _, existing := range streamer.Profiles {
+		if existing.Name != name {
+			profiles = append(profiles, existing)
+		}
+	}
+
+	streamer.Profiles = append(profiles, profile)
+
+	if err := _dataStore.UpdateStreamer(&streamer); err != nil {
+		return nil, err
+	}
+
+	session, _ := sessions.GetSession(streamer.StreamKey)
+	if session != nil && session.Profile == name {
+		destinations, _ := streamer.ProfileDestinations(name)
+		session.SetProfile(name, destinations)
+	}
+
+	return &profile, nil
+}
+
+// DeleteProfile removes one of the streamer's profiles.  The active profile, or one a live session or
+// stream key is on, can't be removed
+func DeleteProfile(streamer models.Streamer, name string) error {
+	if _, ok := streamer.GetProfile(name); !ok {
+		return store.ErrNotFound
+	}
+
+	if streamer.ActiveProfile == name {
+		return fmt.Errorf("%w: %s is the active profile", ErrInvalidProfile, name)
+	}
+
+	session, _ := sessions.GetSession(streamer.StreamKey)
+	if session != nil && session.Profile == name {
+		return fmt.Errorf("%w: the session is using %s", ErrInvalidProfile, name)
+	}
+
+	streamKeys, err := _dataStore.GetStreamKeys(streamer.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, streamKey := range streamKeys {
+		if streamKey.Profile == name {
+			return fmt.Errorf("%w: stream key %s uses %s", ErrInvalidProfile, streamKey.Name, name)
+		}
+	}
+
+	profiles := []models.Profile{}
+	for _, profile := range streamer.Profiles {
+		if profile.Name != name {
+			profiles = append(profiles, profile)
+		}
+	}
+
+	streamer.Profiles = profiles
+
+	return _dataStore.UpdateStreamer(&streamer)
+}
+
+// SetActiveProfile picks the profile the streamer broadcasts to, "" for all destinations.  A live session
+// switches over straight away
+func SetActiveProfile(streamer models.Streamer, name string) (*models.Streamer, error) {
+	destinations, ok := streamer.ProfileDestinations(name)
+	if !ok {
+		return nil, ErrUnknownProfile
+	}
+
+	streamer.ActiveProfile = name
+
+	if err := _dataStore.UpdateStreamer(&streamer); err != nil {
+		return nil, err
+	}
+
+	session, _ := sessions.GetSession(streamer.StreamKey)
+	if session != nil {
+		session.SetProfile(name, destinations)
+	}
+
+	return &streamer, nil
+}
+
+// PrepareSession gets the streamer's session ready for a publish.  profile is the one picked in the rtmp
+// url, if none was the active profile is used.  A session that is already live stays on its profile
+func PrepareSession(streamer models.Streamer, profile string) (*sessions.Session, error) {
+	if profile == "" {
+		profile = streamer.ActiveProfile
+	}
+
+	destinations, ok := streamer.ProfileDestinations(profile)
+	if !ok {
+		return nil, ErrUnknownProfile
+	}
+
+	session, err := sessions.GetSession(streamer.StreamKey)
+	if err != nil {
+		return sessions.CreateSessionFromStreamer(streamer, profile)
+	}
+
+	if !session.Active {
+		session.SetProfile(profile, destinations)
+	}
+
+	return session, nil
+}
@@ -0,0 +1,123 @@
+package streamers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/geekgonecrazy/prismplus/helpers"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/sessions"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+var (
+	ErrInvalidStreamKey = errors.New("invalid stream key")
+	ErrStreamKeyTaken   = errors.New("stream key already in use")
+)
+
+// checkStreamKey refuses keys that would be read as part of the url or as a backup encoder's key.  It
+// applies to a streamer's own key as well as the extra ones
+func checkStreamKey(key string) error {
+	if strings.Contains(key, "/") || strings.HasSuffix(key, sessions.BackupKeySuffix) {
+		return fmt.Errorf("%w: keys can't contain slashes or end in %s", ErrInvalidStreamKey, sessions.BackupKeySuffix)
+	}
+
+	return nil
+}
+
+func GetStreamKeys(streamer models.Streamer) ([]models.StreamKey, error) {
+	return _dataStore.GetStreamKeys(streamer.ID)
+}
+
+// CreateStreamKey gives the streamer an extra stream key, generating the key if none is given
+func CreateStreamKey(streamer models.Streamer, streamKeyPayload models.StreamKeyPayload) (*models.StreamKey, error) {
+	if streamKeyPayload.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidStreamKey)
+	}
+
+	if streamKeyPayload.Key == "" {
+		uuid, err := helpers.NewUUID()
+		if err != nil {
+			return nil, err
+		}
+
+		streamKeyPayload.Key = uuid
+	}
+
+	if err := checkStreamKey(streamKeyPayload.Key); err != nil {
+		return nil, err
+	}
+
+	if streamKeyPayload.Profile != "" {
+		if _, ok := streamer.GetProfile(streamKeyPayload.Profile); !ok {
+			return nil, ErrUnknownProfile
+		}
+	}
+
+	streamKey := models.StreamKey{
+		StreamerID: streamer.ID,
+		Name:       streamKeyPayload.Name,
+		Key:        streamKeyPayload.Key,
+		Profile:    streamKeyPayload.Profile,
+	}
+
+	// The store also refuses a key another streamer publishes with as its own
+	if err := _dataStore.CreateStreamKey(&streamKey); err != nil {
+		if errors.Is(err, store.ErrExists) {
+			return nil, ErrStreamKeyTaken
+		}
+
+		return nil, err
+	}
+
+	return &streamKey, nil
+}
+
+// RevokeStreamKey deletes one of the streamer's extra stream keys and disconnects any encoder using it
+func RevokeStreamKey(streamer models.Streamer, id int) (*models.StreamKey, error) {
+	streamKeys, err := _dataStore.GetStreamKeys(streamer.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, streamKey := range streamKeys {
+		if streamKey.ID != id {
+			continue
+		}
+
+		if err := _dataStore.DeleteStreamKey(id); err != nil {
+			return nil, err
+		}
+
+		session, _ := sessions.GetSession(streamer.StreamKey)
+		if session != nil {
+			session.DisconnectStreamKey(id)
+		}
+
+		return &streamKey, nil
+	}
+
+	return nil, store.ErrNotFound
+}
+
+// ResolveStreamKey finds the streamer a stream key belongs to.  streamKey is nil for the streamer's own key.
+// The store keeps streamers' own keys and extra keys unique between them, so at most one can match
+func ResolveStreamKey(key string) (streamer models.Streamer, streamKey *models.StreamKey, err error) {
+	streamer, err = _dataStore.GetStreamerByStreamKey(key)
+	if err == nil || !errors.Is(err, store.ErrNotFound) {
+		return streamer, nil, err
+	}
+
+	extra, err := _dataStore.GetStreamKey(key)
+	if err != nil {
+		return streamer, nil, err
+	}
+
+	streamer, err = _dataStore.GetStreamerByID(extra.StreamerID)
+	if err != nil {
+		return streamer, nil, err
+	}
+
+	return streamer, &extra, nil
+}
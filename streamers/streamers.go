@@ -1,29 +1,30 @@
 package streamers
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"time"
 
+	"github.com/geekgonecrazy/prismplus/events"
 	"github.com/geekgonecrazy/prismplus/helpers"
 	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/quotas"
 	"github.com/geekgonecrazy/prismplus/sessions"
 	"github.com/geekgonecrazy/prismplus/store"
-	"github.com/geekgonecrazy/prismplus/store/boltstore"
 )
 
-var _dataStore store.Store
+var (
+	_dataStore store.Store
 
-func Setup(dataPath string) {
-	if dataPath == "" {
-		dataPath = "./"
-	}
+	// SchedulesChanged is called after streamer changes rewrote schedules, so they can be reloaded.  It is set
+	// by the schedules package, and stays nil when schedules aren't running like in the offline cli
+	SchedulesChanged func()
 
-	store, err := boltstore.New(dataPath)
-	if err != nil {
-		log.Fatalln(err)
-	}
+	ErrNameTaken = errors.New("another streamer already has that name")
+)
 
-	_dataStore = store
+func Setup(dataStore store.Store) {
+	_dataStore = dataStore
 }
 
 func GetStreamers() ([]models.Streamer, error) {
@@ -36,6 +37,16 @@ func GetStreamers() ([]models.Streamer, error) {
 }
 
 func CreateStreamer(streamerPayload models.StreamerCreatePayload) (*models.Streamer, error) {
+	// The name is what streamers log in with
+	taken, err := nameTaken(streamerPayload.Name, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if taken {
+		return nil, ErrNameTaken
+	}
+
 	// If no StreamKey is provided generate one
 	if streamerPayload.StreamKey == "" {
 		uuid, err := helpers.NewUUID()
@@ -47,18 +58,31 @@ func CreateStreamer(streamerPayload models.StreamerCreatePayload) (*models.Strea
 		streamerPayload.StreamKey = uuid
 	}
 
+	if err := checkStreamKey(streamerPayload.StreamKey); err != nil {
+		return nil, err
+	}
+
 	streamer := models.Streamer{
 		Name:         streamerPayload.Name,
 		StreamKey:    streamerPayload.StreamKey,
 		Destinations: []models.Destination{},
+		Profiles:     []models.Profile{},
+
+		DuplicatePublisherPolicy: streamerPayload.DuplicatePublisherPolicy,
 
 		NextDestinationID: 1,
 	}
 
 	if err := _dataStore.CreateStreamer(&streamer); err != nil {
+		if errors.Is(err, store.ErrExists) {
+			return nil, ErrStreamKeyTaken
+		}
+
 		return nil, err
 	}
 
+	events.Publish(events.StreamerCreated, streamer.ID, models.StreamerEvent{ID: streamer.ID, Name: streamer.Name})
+
 	return &streamer, nil
 }
 
@@ -73,9 +97,43 @@ func GetStreamer(id int) (models.Streamer, error) {
 	return streamer, nil
 }
 
-func UpdateStreamer(streamer models.Streamer) error {
+func UpdateStreamer(streamer models.Streamer, streamerPayload models.StreamerUpdatePayload) (*models.Streamer, error) {
+	if streamerPayload.Name != "" {
+		taken, err := nameTaken(streamerPayload.Name, streamer.ID)
+		if err != nil {
+			return nil, err
+		}
 
-	return nil
+		if taken {
+			return nil, ErrNameTaken
+		}
+
+		streamer.Name = streamerPayload.Name
+	}
+
+	if streamerPayload.DuplicatePublisherPolicy != "" {
+		streamer.DuplicatePublisherPolicy = streamerPayload.DuplicatePublisherPolicy
+	}
+
+	if streamerPayload.Limits != nil {
+		streamer.Limits = *streamerPayload.Limits
+	}
+
+	// Streamers from before keys were checked have to rotate theirs first
+	if err := checkStreamKey(streamer.StreamKey); err != nil {
+		return nil, err
+	}
+
+	if err := _dataStore.UpdateStreamer(&streamer); err != nil {
+		return nil, err
+	}
+
+	session, _ := sessions.GetSession(streamer.StreamKey)
+	if session != nil {
+		session.SetDuplicatePublisherPolicy(streamer.DuplicatePublisherPolicy)
+	}
+
+	return &streamer, nil
 }
 
 func DeleteStreamer(id int) error {
@@ -88,16 +146,50 @@ func DeleteStreamer(id int) error {
 		return err
 	}
 
+	events.Publish(events.StreamerDeleted, streamer.ID, models.StreamerEvent{ID: streamer.ID, Name: streamer.Name})
+
 	session, _ := sessions.GetSession(streamer.StreamKey)
 	if session == nil {
 		return nil
 	}
 
-	if err := sessions.DeleteSession(session.Key); err != nil {
-		return err
+	return endSession(session, "streamer_deleted", "streamer was deleted")
+}
+
+// RotateStreamKey gives the streamer a new stream key.  A live session on the old key is ended so it
+// stops working straight away
+func RotateStreamKey(streamer models.Streamer) (*models.Streamer, error) {
+	oldKey := streamer.StreamKey
+
+	uuid, err := helpers.NewUUID()
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	streamer.StreamKey = uuid
+
+	if err := checkStreamKey(streamer.StreamKey); err != nil {
+		return nil, err
+	}
+
+	if err := _dataStore.UpdateStreamer(&streamer); err != nil {
+		if errors.Is(err, store.ErrExists) {
+			return nil, ErrStreamKeyTaken
+		}
+
+		return nil, err
+	}
+
+	session, _ := sessions.GetSession(oldKey)
+	if session == nil {
+		return &streamer, nil
+	}
+
+	if err := endSession(session, "stream_key_rotated", "stream key was rotated"); err != nil {
+		return nil, err
+	}
+
+	return &streamer, nil
 }
 
 func GetStreamerByStreamKey(streamKey string) (models.Streamer, error) {
@@ -110,6 +202,9 @@ func GetStreamerByStreamKey(streamKey string) (models.Streamer, error) {
 }
 
 func AddDestination(streamer models.Streamer, destination models.Destination) error {
+	if err := quotas.CheckDestinations(streamer); err != nil {
+		return err
+	}
 
 	destination.ID = streamer.NextDestinationID
 	streamer.NextDestinationID++
@@ -120,8 +215,9 @@ func AddDestination(streamer models.Streamer, destination models.Destination) er
 		return err
 	}
 
+	// New destinations aren't in any profile yet
 	session, _ := sessions.GetSession(streamer.StreamKey)
-	if session == nil {
+	if session == nil || session.Profile != "" {
 		return nil
 	}
 
@@ -148,16 +244,156 @@ func RemoveDestination(streamer models.Streamer, id int) error {
 
 	streamer.Destinations = newDestinations
 
+	profiles := []models.Profile{}
+	for _, profile := range streamer.Profiles {
+		destinationIDs := []int{}
+		for _, destinationID := range profile.DestinationIDs {
+			if destinationID != id {
+				destinationIDs = append(destinationIDs, destinationID)
+			}
+		}
+
+		profile.DestinationIDs = destinationIDs
+		profiles = append(profiles, profile)
+	}
+
+	streamer.Profiles = profiles
+
 	if err := _dataStore.UpdateStreamer(&streamer); err != nil {
 		return err
 	}
 
+	if err := pruneSchedules(streamer.ID, id); err != nil {
+		return err
+	}
+
+	session, _ := sessions.GetSession(streamer.StreamKey)
+	if session == nil {
+		return nil
+	}
+
+	// It may not be in the session's profile
+	if err := session.RemoveDestination(id); err != nil && !errors.Is(err, sessions.ErrNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// pruneSchedules takes a removed destination out of the streamer's schedules
+func pruneSchedules(streamerID int, destinationID int) error {
+	schedules, err := _dataStore.GetSchedules()
+	if err != nil {
+		return err
+	}
+
+	pruned := false
+
+	for _, schedule := range schedules {
+		if schedule.StreamerID != streamerID {
+			continue
+		}
+
+		destinationIDs := []int{}
+		for _, id := range schedule.DestinationIDs {
+			if id != destinationID {
+				destinationIDs = append(destinationIDs, id)
+			}
+		}
+
+		if len(destinationIDs) == len(schedule.DestinationIDs) {
+			continue
+		}
+
+		schedule.DestinationIDs = destinationIDs
+
+		if err := _dataStore.UpdateSchedule(&schedule); err != nil {
+			return err
+		}
+
+		pruned = true
+	}
+
+	if pruned && SchedulesChanged != nil {
+		SchedulesChanged()
+	}
+
+	return nil
+}
+
+// SyncSession brings the streamer's live session in line with changes made to the streamer all at once,
+// like an import.  A changed stream key ends the session
+func SyncSession(before models.Streamer, after models.Streamer) error {
+	session, _ := sessions.GetSession(before.StreamKey)
+	if session == nil {
+		return nil
+	}
+
+	if after.StreamKey != before.StreamKey {
+		return endSession(session, "stream_key_changed", "stream key was changed")
+	}
+
+	// The same check publishing uses, so a timed disable that has run out counts as enabled
+	now := time.Now()
+	if after.IsDisabled(now) && !before.IsDisabled(now) {
+		return endSession(session, "streamer_disabled", after.Disabled.Reason)
+	}
+
+	session.SetDuplicatePublisherPolicy(after.DuplicatePublisherPolicy)
+
+	// Only the destinations in the session's profile are live
+	beforeDestinations, _ := before.ProfileDestinations(session.Profile)
+	afterDestinations, _ := after.ProfileDestinations(session.Profile)
+
+	beforeByID := map[int]models.Destination{}
+	for _, destination := range beforeDestinations {
+		beforeByID[destination.ID] = destination
+	}
+
+	afterByID := map[int]models.Destination{}
+	for _, destination := range afterDestinations {
+		afterByID[destination.ID] = destination
+	}
+
+	// Changed destinations are removed and added again to reconnect them
+	for id, destination := range beforeByID {
+		if updated, ok := afterByID[id]; !ok || updated != destination {
+			if err := session.RemoveDestination(id); err != nil && !errors.Is(err, sessions.ErrNotFound) {
+				return err
+			}
+		}
+	}
+
+	for id, destination := range afterByID {
+		if previous, ok := beforeByID[id]; !ok || previous != destination {
+			if err := session.AddDestination(destination); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// EndSession ends the streamer's live session if it has one
+func EndSession(streamer models.Streamer) error {
 	session, _ := sessions.GetSession(streamer.StreamKey)
 	if session == nil {
 		return nil
 	}
 
-	if err := session.RemoveDestination(id); err != nil {
+	return endSession(session, "session_ended", "session was ended")
+}
+
+// endSession ends an active session, the publish loop cleans it up once it sees it has ended.  A session
+// nobody is publishing to is removed straight away
+func endSession(session *sessions.Session, eventType string, reason string) error {
+	if session.Active {
+		session.EndSessionFor(eventType, reason)
+		return nil
+	}
+
+	if err := sessions.DeleteSession(session.Key); err != nil && !errors.Is(err, sessions.ErrNotFound) {
 		return err
 	}
 
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// startAPIServer serves the router over HTTPS if TLS is configured, otherwise plain HTTP
+func startAPIServer(router *echo.Echo) error {
+	server := &http.Server{
+		Addr:              cfg.API.Bind,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	t := cfg.API.TLS
+	if !t.Enabled() {
+		return router.StartServer(server)
+	}
+
+	var challengeHandler func(http.Handler) http.Handler
+
+	if t.ACME.Enabled() {
+		manager, err := acmeManager()
+		if err != nil {
+			return err
+		}
+
+		server.TLSConfig = manager.TLSConfig()
+		challengeHandler = manager.HTTPHandler
+	} else {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return err
+		}
+
+		server.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+		}
+	}
+
+	server.TLSConfig.MinVersion = tls.VersionTLS12
+
+	if t.RedirectBind != "" {
+		go redirectServer(challengeHandler)
+	}
+
+	return router.StartServer(server)
+}
+
+// hstsMiddleware sends Strict-Transport-Security on HTTPS responses
+func hstsMiddleware() echo.MiddlewareFunc {
+	return middleware.SecureWithConfig(middleware.SecureConfig{
+		HSTSMaxAge:            int(time.Duration(cfg.API.TLS.HSTSMaxAge).Seconds()),
+		HSTSExcludeSubdomains: !cfg.API.TLS.HSTSIncludeSubdomains,
+	})
+}
+
+func acmeManager() (*autocert.Manager, error) {
+	a := cfg.API.TLS.ACME
+
+	cacheDir := a.CacheDir
+	if cacheDir == "" {
+		cacheDir = filepath.Join(cfg.DataPath, "certs")
+	}
+
+	client := &acme.Client{DirectoryURL: a.DirectoryURL}
+
+	if a.CAFile != "" {
+		pem, err := ioutil.ReadFile(a.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in " + a.CAFile)
+		}
+
+		client.HTTPClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{RootCAs: pool},
+			},
+		}
+	}
+
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cacheDir),
+		HostPolicy: autocert.HostWhitelist(a.Domains...),
+		Email:      a.Email,
+		Client:     client,
+	}, nil
+}
+
+// redirectServer sends plain HTTP requests to the HTTPS api.  With ACME challengeHandler answers
+// http-01 challenges first
+func redirectServer(challengeHandler func(http.Handler) http.Handler) {
+	var handler http.Handler = http.HandlerFunc(redirectToHTTPS)
+	if challengeHandler != nil {
+		handler = challengeHandler(handler)
+	}
+
+	server := &http.Server{
+		Addr:              cfg.API.TLS.RedirectBind,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Println("Redirecting HTTP to HTTPS on", cfg.API.TLS.RedirectBind)
+	log.Fatalln(server.ListenAndServe())
+}
+
+func redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+
+	if _, port, err := net.SplitHostPort(cfg.API.Bind); err == nil && port != "443" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	// 301 would turn a POST into a GET
+	code := http.StatusMovedPermanently
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		code = http.StatusPermanentRedirect
+	}
+
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), code)
+}
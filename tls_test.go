@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/geekgonecrazy/prismplus/config"
+)
+
+// acmeTestServer serves an ACME directory over TLS with its own self-signed certificate, which is written
+// to the returned CA file
+func acmeTestServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/directory" {
+			http.NotFound(w, r)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{
+			"newNonce":   server.URL + "/new-nonce",
+			"newAccount": server.URL + "/new-account",
+			"newOrder":   server.URL + "/new-order",
+			"revokeCert": server.URL + "/revoke-cert",
+			"keyChange":  server.URL + "/key-change",
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, ca, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return server, caFile
+}
+
+func withACMEConfig(t *testing.T, directoryURL string, caFile string) {
+	t.Helper()
+
+	previous := cfg
+	t.Cleanup(func() {
+		cfg = previous
+	})
+
+	cfg = config.Default()
+	cfg.DataPath = t.TempDir() + "/"
+	cfg.API.TLS.ACME.Domains = []string{"prism.example.com"}
+	cfg.API.TLS.ACME.DirectoryURL = directoryURL
+	cfg.API.TLS.ACME.CAFile = caFile
+}
+
+func TestACMEManagerUsesDirectoryAndCA(t *testing.T) {
+	server, caFile := acmeTestServer(t)
+	withACMEConfig(t, server.URL+"/directory", caFile)
+
+	manager, err := acmeManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if manager.Client.DirectoryURL != server.URL+"/directory" {
+		t.Errorf("directory url is %q, want %q", manager.Client.DirectoryURL, server.URL+"/directory")
+	}
+
+	// The directory is only trusted through the CA file
+	directory, err := manager.Client.Discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if directory.OrderURL != server.URL+"/new-order" {
+		t.Errorf("order url is %q, want %q", directory.OrderURL, server.URL+"/new-order")
+	}
+}
+
+func TestACMEManagerWithoutCA(t *testing.T) {
+	server, _ := acmeTestServer(t)
+	withACMEConfig(t, server.URL+"/directory", "")
+
+	manager, err := acmeManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := manager.Client.Discover(context.Background()); err == nil {
+		t.Error("discovered a directory with an untrusted certificate")
+	}
+}
+
+func TestACMEManagerInvalidCA(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := ioutil.WriteFile(caFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	withACMEConfig(t, "https://acme.example.com/directory", caFile)
+
+	if _, err := acmeManager(); err == nil {
+		t.Error("accepted a CA file without certificates")
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		bind     string
+		method   string
+		host     string
+		target   string
+		code     int
+		location string
+	}{
+		{":443", http.MethodGet, "prism.example.com", "/streamers?a=1", http.StatusMovedPermanently, "https://prism.example.com/streamers?a=1"},
+		{":443", http.MethodGet, "prism.example.com:80", "/", http.StatusMovedPermanently, "https://prism.example.com/"},
+		{":8443", http.MethodGet, "prism.example.com:8080", "/", http.StatusMovedPermanently, "https://prism.example.com:8443/"},
+		{":443", http.MethodPost, "prism.example.com", "/api/v1/streamer/login", http.StatusPermanentRedirect, "https://prism.example.com/api/v1/streamer/login"},
+		{":443", http.MethodGet, "[::1]:80", "/", http.StatusMovedPermanently, "https://[::1]/"},
+		{":8443", http.MethodGet, "[::1]", "/", http.StatusMovedPermanently, "https://[::1]:8443/"},
+	}
+
+	previous := cfg
+	defer func() {
+		cfg = previous
+	}()
+
+	for _, test := range tests {
+		cfg = config.Default()
+		cfg.API.Bind = test.bind
+
+		req := httptest.NewRequest(test.method, test.target, nil)
+		req.Host = test.host
+
+		rec := httptest.NewRecorder()
+		redirectToHTTPS(rec, req)
+
+		if rec.Code != test.code || rec.Header().Get("Location") != test.location {
+			t.Errorf("%s %s%s on %s redirected %d to %q, want %d to %q", test.method, test.host, test.target, test.bind, rec.Code, rec.Header().Get("Location"), test.code, test.location)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package tokens
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/helpers"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+const (
+	// Prefix marks scoped api tokens so they can be told apart from admin keys and streamer logins
+	Prefix = "ppt_"
+
+	// lastUsedInterval limits how often we write last used timestamps back to the store
+	lastUsedInterval = time.Minute
+)
+
+var (
+	_dataStore store.Store
+
+	_lastUsedLock sync.Mutex
+
+	ErrInvalidToken = errors.New("invalid api token")
+)
+
+func Setup(dataStore store.Store) {
+	_dataStore = dataStore
+}
+
+// Authenticate finds the api token matching token.  Every stored token is compared in constant time
+func Authenticate(token string) (models.APIToken, error) {
+	apiTokens, err := _dataStore.GetAPITokens()
+	if err != nil {
+		return models.APIToken{}, err
+	}
+
+	hash := []byte(helpers.HashToken(token))
+
+	var match *models.APIToken
+	for i := range apiTokens {
+		if subtle.ConstantTimeCompare(hash, []byte(apiTokens[i].Hash)) == 1 {
+			match = &apiTokens[i]
+		}
+	}
+
+	if match == nil {
+		return models.APIToken{}, ErrInvalidToken
+	}
+
+	now := time.Now()
+
+	if match.ExpiresAt != nil && now.After(*match.ExpiresAt) {
+		return models.APIToken{}, ErrInvalidToken
+	}
+
+	touchToken(*match, now)
+
+	match.Hash = ""
+
+	return *match, nil
+}
+
+func touchToken(token models.APIToken, now time.Time) {
+	_lastUsedLock.Lock()
+	defer _lastUsedLock.Unlock()
+
+	if token.LastUsedAt != nil && now.Sub(*token.LastUsedAt) < lastUsedInterval {
+		return
+	}
+
+	token.LastUsedAt = &now
+
+	if err := _dataStore.UpdateAPIToken(&token); err != nil {
+		log.Println("Error:", err)
+	}
+}
+
+// GetTokens returns all tokens, or only those belonging to the streamer if streamerID isn't 0
+func GetTokens(streamerID int) ([]models.APIToken, error) {
+	apiTokens, err := _dataStore.GetAPITokens()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := []models.APIToken{}
+	for _, token := range apiTokens {
+		if streamerID != 0 && token.StreamerID != streamerID {
+			continue
+		}
+
+		token.Hash = ""
+		filtered = append(filtered, token)
+	}
+
+	return filtered, nil
+}
+
+func GetToken(id int) (models.APIToken, error) {
+	token, err := _dataStore.GetAPITokenByID(id)
+	if err != nil {
+		return token, err
+	}
+
+	token.Hash = ""
+
+	return token, nil
+}
+
+// CreateToken stores a new token.  Exactly one of createdByAdminID or createdByStreamerID should be set
+func CreateToken(tokenPayload models.APITokenCreatePayload, createdByAdminID int, createdByStreamerID int) (*models.APITokenCreated, error) {
+	token, err := helpers.NewToken()
+	if err != nil {
+		return nil, err
+	}
+
+	token = Prefix + token
+
+	apiToken := models.APIToken{
+		Name:       tokenPayload.Name,
+		Prefix:     token[:12],
+		Hash:       helpers.HashToken(token),
+		Scopes:     tokenPayload.Scopes,
+		StreamerID: tokenPayload.StreamerID,
+		ExpiresAt:  tokenPayload.ExpiresAt,
+
+		CreatedByAdminID:    createdByAdminID,
+		CreatedByStreamerID: createdByStreamerID,
+	}
+
+	if err := _dataStore.CreateAPIToken(&apiToken); err != nil {
+		return nil, err
+	}
+
+	apiToken.Hash = ""
+
+	return &models.APITokenCreated{
+		APIToken: apiToken,
+		Token:    token,
+	}, nil
+}
+
+func DeleteToken(id int) error {
+	if _, err := _dataStore.GetAPITokenByID(id); err != nil {
+		return err
+	}
+
+	return _dataStore.DeleteAPIToken(id)
+}
@@ -0,0 +1,355 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/geekgonecrazy/prismplus/events"
+	"github.com/geekgonecrazy/prismplus/helpers"
+	"github.com/geekgonecrazy/prismplus/models"
+	"github.com/geekgonecrazy/prismplus/store"
+)
+
+const (
+	// SignatureHeader holds sha256=<hex hmac of "<timestamp>.<body>" using the webhook secret>
+	SignatureHeader = "X-Prismplus-Signature"
+	// TimestampHeader holds the unix time the delivery was signed at, so receivers can refuse replays
+	TimestampHeader = "X-Prismplus-Timestamp"
+	EventHeader     = "X-Prismplus-Event"
+	DeliveryHeader  = "X-Prismplus-Delivery"
+
+	// PingEvent is only sent to a webhook when it is tested
+	PingEvent = "ping"
+
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 500
+
+	// keepDeliveries is how many deliveries are kept for each webhook, older ones are pruned every pruneInterval
+	keepDeliveries = maxDeliveriesLimit
+	pruneInterval  = 10 * time.Minute
+)
+
+var (
+	_dataStore store.Store
+
+	_client = &http.Client{Timeout: 10 * time.Second}
+
+	// _webhooks caches the stored webhooks for dispatch, nil until loaded and after any change
+	_webhooks     []models.Webhook
+	_webhooksLock sync.Mutex
+
+	// MaxAttempts is how many times a delivery is tried before giving up
+	MaxAttempts = 5
+	// RetryBackoff is the wait before the first retry, it doubles after each attempt
+	RetryBackoff = 2 * time.Second
+
+	ErrInvalidURL    = errors.New("webhook url must be http or https")
+	ErrInvalidEvents = errors.New("webhook events must be known event types or *")
+)
+
+// Setup starts delivering published events to the stored webhooks
+func Setup(dataStore store.Store) {
+	_dataStore = dataStore
+
+	subscription := events.Subscribe(0)
+
+	go func() {
+		for event := range subscription.Events {
+			dispatch(event)
+		}
+	}()
+
+	go func() {
+		for {
+			if err := _dataStore.PruneWebhookDeliveries(keepDeliveries); err != nil {
+				log.Println("Error pruning webhook deliveries:", err)
+			}
+
+			time.Sleep(pruneInterval)
+		}
+	}()
+}
+
+// cachedWebhooks returns the stored webhooks, only going to the store after they've changed
+func cachedWebhooks() ([]models.Webhook, error) {
+	_webhooksLock.Lock()
+	defer _webhooksLock.Unlock()
+
+	if _webhooks != nil {
+		return _webhooks, nil
+	}
+
+	webhooks, err := _dataStore.GetWebhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	_webhooks = webhooks
+
+	return _webhooks, nil
+}
+
+func invalidateCache() {
+	_webhooksLock.Lock()
+	_webhooks = nil
+	_webhooksLock.Unlock()
+}
+
+func dispatch(event events.Event) {
+	webhooks, err := cachedWebhooks()
+	if err != nil {
+		log.Println("Error loading webhooks:", err)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		if webhook.WantsEvent(event.Type) {
+			go deliver(webhook, event)
+		}
+	}
+}
+
+// deliver posts the event to the webhook, retrying with backoff until it succeeds, gets a response
+// that won't change on retry, or runs out of attempts.  Every attempt is logged
+func deliver(webhook models.Webhook, event events.Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Println("Error encoding webhook event:", err)
+		return
+	}
+
+	backoff := RetryBackoff
+
+	for attempt := 1; attempt <= MaxAttempts; attempt++ {
+		delivery, retry := send(webhook, event, body)
+		delivery.Attempt = attempt
+
+		if err := _dataStore.CreateWebhookDelivery(&delivery); err != nil {
+			log.Println("Error recording webhook delivery:", err)
+		}
+
+		if delivery.Success || !retry {
+			return
+		}
+
+		if attempt < MaxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	log.Printf("Giving up delivering %s event %d to webhook %d", event.Type, event.ID, webhook.ID)
+}
+
+// send makes a single delivery attempt and reports whether it is worth retrying
+func send(webhook models.Webhook, event events.Event, body []byte) (models.WebhookDelivery, bool) {
+	delivery := models.WebhookDelivery{
+		WebhookID: webhook.ID,
+		EventID:   event.ID,
+		EventType: event.Type,
+		Time:      time.Now(),
+	}
+
+	request, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery, false
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventHeader, event.Type)
+	request.Header.Set(DeliveryHeader, fmt.Sprint(event.ID))
+
+	// Each attempt is signed with its own timestamp so retries aren't mistaken for replays
+	timestamp := strconv.FormatInt(delivery.Time.Unix(), 10)
+	request.Header.Set(TimestampHeader, timestamp)
+	request.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, body))
+
+	response, err := _client.Do(request)
+	delivery.DurationMs = time.Since(delivery.Time).Milliseconds()
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery, true
+	}
+	response.Body.Close()
+
+	delivery.StatusCode = response.StatusCode
+	delivery.Success = response.StatusCode >= 200 && response.StatusCode < 300
+
+	// Other client errors mean the request itself was refused, sending it again won't help
+	retry := response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusRequestTimeout
+
+	return delivery, retry
+}
+
+// Sign returns the signature header value for body sent at timestamp
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func validate(webhookURL string, webhookEvents []string) error {
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+
+	if len(webhookEvents) == 0 {
+		return ErrInvalidEvents
+	}
+
+	for _, eventType := range webhookEvents {
+		if eventType != "*" && !events.ValidType(eventType) {
+			return ErrInvalidEvents
+		}
+	}
+
+	return nil
+}
+
+func GetWebhooks() ([]models.Webhook, error) {
+	webhooks, err := _dataStore.GetWebhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	return webhooks, nil
+}
+
+func GetWebhook(id int) (models.Webhook, error) {
+	webhook, err := _dataStore.GetWebhookByID(id)
+	if err != nil {
+		return webhook, err
+	}
+
+	webhook.Secret = ""
+
+	return webhook, nil
+}
+
+// CreateWebhook stores a new webhook.  The returned webhook includes the secret
+func CreateWebhook(webhookPayload models.WebhookCreatePayload) (*models.Webhook, error) {
+	if err := validate(webhookPayload.URL, webhookPayload.Events); err != nil {
+		return nil, err
+	}
+
+	if webhookPayload.Secret == "" {
+		secret, err := helpers.NewToken()
+		if err != nil {
+			return nil, err
+		}
+
+		webhookPayload.Secret = secret
+	}
+
+	webhook := models.Webhook{
+		URL:    webhookPayload.URL,
+		Events: webhookPayload.Events,
+		Secret: webhookPayload.Secret,
+	}
+
+	if err := _dataStore.CreateWebhook(&webhook); err != nil {
+		return nil, err
+	}
+
+	invalidateCache()
+
+	return &webhook, nil
+}
+
+func UpdateWebhook(id int, webhookPayload models.WebhookUpdatePayload) (*models.Webhook, error) {
+	webhook, err := _dataStore.GetWebhookByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if webhookPayload.URL != "" {
+		webhook.URL = webhookPayload.URL
+	}
+
+	if webhookPayload.Events != nil {
+		webhook.Events = webhookPayload.Events
+	}
+
+	if webhookPayload.Disabled != nil {
+		webhook.Disabled = *webhookPayload.Disabled
+	}
+
+	if err := validate(webhook.URL, webhook.Events); err != nil {
+		return nil, err
+	}
+
+	if err := _dataStore.UpdateWebhook(&webhook); err != nil {
+		return nil, err
+	}
+
+	invalidateCache()
+
+	webhook.Secret = ""
+
+	return &webhook, nil
+}
+
+func DeleteWebhook(id int) error {
+	if _, err := _dataStore.GetWebhookByID(id); err != nil {
+		return err
+	}
+
+	if err := _dataStore.DeleteWebhook(id); err != nil {
+		return err
+	}
+
+	invalidateCache()
+
+	return nil
+}
+
+// Ping sends a ping event to the webhook so its receiver can be checked
+func Ping(id int) error {
+	webhook, err := _dataStore.GetWebhookByID(id)
+	if err != nil {
+		return err
+	}
+
+	go deliver(webhook, events.Event{
+		Type: PingEvent,
+		Time: time.Now(),
+	})
+
+	return nil
+}
+
+// GetDeliveries returns the most recent delivery attempts for the webhook, newest first
+func GetDeliveries(id int, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := _dataStore.GetWebhookByID(id); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = defaultDeliveriesLimit
+	}
+
+	if limit > maxDeliveriesLimit {
+		limit = maxDeliveriesLimit
+	}
+
+	return _dataStore.GetWebhookDeliveries(id, limit)
+}